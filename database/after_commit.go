@@ -0,0 +1,87 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// afterCommitPool is the connection pool GORM sees: transactions it begins can queue work
+// to run only once they commit, so nothing reacts to rows that may still be rolled back.
+// GORM wraps every create, update and delete in a transaction of its own, so this covers
+// plain writes as well as DB.Transaction blocks.
+type afterCommitPool struct {
+	*sql.DB
+}
+
+func (p *afterCommitPool) BeginTx(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	tx, err := p.DB.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &afterCommitTx{Tx: tx, db: p.DB}, nil
+}
+
+func (p *afterCommitPool) GetDBConn() (*sql.DB, error) {
+	return p.DB, nil
+}
+
+type afterCommitTx struct {
+	*sql.Tx
+	db *sql.DB
+
+	mu      sync.Mutex
+	pending []func()
+}
+
+func (t *afterCommitTx) Commit() error {
+	if err := t.Tx.Commit(); err != nil {
+		return err
+	}
+	t.mu.Lock()
+	pending := t.pending
+	t.pending = nil
+	t.mu.Unlock()
+
+	for _, fn := range pending {
+		fn()
+	}
+	return nil
+}
+
+func (t *afterCommitTx) Rollback() error {
+	t.mu.Lock()
+	t.pending = nil
+	t.mu.Unlock()
+	return t.Tx.Rollback()
+}
+
+func (t *afterCommitTx) GetDBConn() (*sql.DB, error) {
+	return t.db, nil
+}
+
+// AfterCommit runs fn once the transaction db is part of commits, and drops it on rollback.
+// Outside a transaction the write is already visible, so fn runs straight away.
+func AfterCommit(db *gorm.DB, fn func()) {
+	tx, ok := db.Statement.ConnPool.(*afterCommitTx)
+	if !ok {
+		fn()
+		return
+	}
+	tx.mu.Lock()
+	tx.pending = append(tx.pending, fn)
+	tx.mu.Unlock()
+}
+
+// Helper: route DB's transactions through afterCommitPool
+func installAfterCommit() {
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return
+	}
+	pool := &afterCommitPool{DB: sqlDB}
+	DB.ConnPool = pool
+	DB.Statement.ConnPool = pool
+}
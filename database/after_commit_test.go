@@ -0,0 +1,85 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// stubDriver only knows how to begin, commit and roll back
+type stubDriver struct{}
+
+type stubConn struct{}
+
+type stubTx struct{}
+
+func (stubDriver) Open(string) (driver.Conn, error) { return stubConn{}, nil }
+
+func (stubConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (stubConn) Close() error                        { return nil }
+func (stubConn) Begin() (driver.Tx, error)           { return stubTx{}, nil }
+
+func (stubTx) Commit() error   { return nil }
+func (stubTx) Rollback() error { return nil }
+
+func init() {
+	sql.Register("after-commit-stub", stubDriver{})
+}
+
+func TestAfterCommit(t *testing.T) {
+	sqlDB, err := sql.Open("after-commit-stub", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqlDB.Close()
+	pool := &afterCommitPool{DB: sqlDB}
+
+	// inTx returns what a GORM callback sees while running inside a transaction from pool
+	inTx := func() (*gorm.DB, gorm.TxCommitter) {
+		conn, err := pool.BeginTx(context.Background(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &gorm.DB{Statement: &gorm.Statement{ConnPool: conn}}, conn.(gorm.TxCommitter)
+	}
+
+	t.Run("runs after commit", func(t *testing.T) {
+		db, tx := inTx()
+		ran := 0
+		AfterCommit(db, func() { ran++ })
+		AfterCommit(db, func() { ran++ })
+		if ran != 0 {
+			t.Fatal("ran before commit")
+		}
+		if err := tx.Commit(); err != nil {
+			t.Fatal(err)
+		}
+		if ran != 2 {
+			t.Errorf("ran %d times after commit, want 2", ran)
+		}
+	})
+
+	t.Run("dropped on rollback", func(t *testing.T) {
+		db, tx := inTx()
+		ran := false
+		AfterCommit(db, func() { ran = true })
+		if err := tx.Rollback(); err != nil {
+			t.Fatal(err)
+		}
+		if ran {
+			t.Error("ran after rollback")
+		}
+	})
+
+	t.Run("runs at once outside a transaction", func(t *testing.T) {
+		ran := false
+		AfterCommit(&gorm.DB{Statement: &gorm.Statement{ConnPool: pool}}, func() { ran = true })
+		if !ran {
+			t.Error("did not run")
+		}
+	})
+}
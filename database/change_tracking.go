@@ -0,0 +1,108 @@
+package database
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// Tables whose changes are reported by delta sync, with the entity type used in tombstones
+var trackedTables = []struct{ Table, Entity string }{
+	{"groups", "group"},
+	{"group_members", "member"},
+	{"expenses", "expense"},
+	{"expense_splits", "split"},
+	{"settlements", "settlement"},
+	{"activities", "activity"},
+}
+
+// Writers take this advisory lock in shared mode for the rest of their transaction;
+// CurrentChangeSeq takes it exclusively, so it only reads the sequence once every
+// change numbered at or below it has committed.
+const changeLockKey = 0x53594e43 // "SYNC"
+
+// createChangeTracking installs the triggers behind delta sync: every insert or update
+// stamps the row with the next value of change_seq, and every delete leaves a tombstone.
+// Doing this in the database covers bulk updates and cascading deletes as well.
+func createChangeTracking() {
+	statements := []string{
+		`CREATE SEQUENCE IF NOT EXISTS change_seq`,
+		fmt.Sprintf(`CREATE OR REPLACE FUNCTION track_change() RETURNS trigger AS $$
+		BEGIN
+			PERFORM pg_advisory_xact_lock_shared(%d);
+			NEW.change_seq := nextval('change_seq');
+			RETURN NEW;
+		END $$ LANGUAGE plpgsql`, changeLockKey),
+		fmt.Sprintf(`CREATE OR REPLACE FUNCTION track_delete() RETURNS trigger AS $$
+		DECLARE
+			v_entity_id uuid;
+			v_group_id uuid;
+			v_user_id uuid;
+			v_parent_id uuid;
+		BEGIN
+			PERFORM pg_advisory_xact_lock_shared(%d);
+			IF TG_TABLE_NAME = 'groups' THEN
+				v_entity_id := OLD.id;
+				v_group_id := OLD.id;
+			ELSIF TG_TABLE_NAME = 'group_members' THEN
+				v_group_id := OLD.group_id;
+				v_user_id := OLD.user_id;
+			ELSIF TG_TABLE_NAME = 'expense_splits' THEN
+				v_entity_id := OLD.id;
+				v_parent_id := OLD.expense_id;
+				SELECT e.group_id INTO v_group_id FROM expenses e WHERE e.id = OLD.expense_id;
+			ELSE
+				v_entity_id := OLD.id;
+				v_group_id := OLD.group_id;
+			END IF;
+			INSERT INTO tombstones (seq, entity_type, entity_id, group_id, user_id, parent_id, deleted_at)
+			VALUES (nextval('change_seq'), TG_ARGV[0], v_entity_id, v_group_id, v_user_id, v_parent_id, now());
+			RETURN OLD;
+		END $$ LANGUAGE plpgsql`, changeLockKey),
+	}
+
+	for _, t := range trackedTables {
+		statements = append(statements,
+			fmt.Sprintf(`DROP TRIGGER IF EXISTS track_change ON %s`, t.Table),
+			fmt.Sprintf(`CREATE TRIGGER track_change BEFORE INSERT OR UPDATE ON %s
+				FOR EACH ROW EXECUTE FUNCTION track_change()`, t.Table),
+			fmt.Sprintf(`DROP TRIGGER IF EXISTS track_delete ON %s`, t.Table),
+			fmt.Sprintf(`CREATE TRIGGER track_delete AFTER DELETE ON %s
+				FOR EACH ROW EXECUTE FUNCTION track_delete('%s')`, t.Table, t.Entity),
+			// Rows from before change tracking get a number too (the trigger assigns it), so sync can page through them
+			fmt.Sprintf(`UPDATE %s SET change_seq = 0 WHERE change_seq = 0`, t.Table),
+		)
+	}
+
+	for _, stmt := range statements {
+		if err := DB.Exec(stmt).Error; err != nil {
+			log.Fatal("Failed to set up change tracking:", err)
+		}
+	}
+}
+
+// PruneTombstones deletes tombstones older than before; no unexpired sync token needs them
+func PruneTombstones(before time.Time) (int64, error) {
+	result := DB.Exec("DELETE FROM tombstones WHERE deleted_at < ?", before)
+	return result.RowsAffected, result.Error
+}
+
+// CurrentChangeSeq returns a sequence number that every committed change is at or below.
+// It briefly waits for in-flight writes to commit so none can later appear below it.
+func CurrentChangeSeq() (int64, error) {
+	tx := DB.Begin()
+	if tx.Error != nil {
+		return 0, tx.Error
+	}
+	defer tx.Rollback()
+
+	if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", changeLockKey).Error; err != nil {
+		return 0, err
+	}
+
+	var seq int64
+	if err := tx.Raw("SELECT CASE WHEN is_called THEN last_value ELSE 0 END FROM change_seq").Scan(&seq).Error; err != nil {
+		return 0, err
+	}
+	return seq, nil
+}
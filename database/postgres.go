@@ -20,6 +20,7 @@ func Connect() {
 	if err != nil {
 		log.Fatal("Failed to connect to database:", err)
 	}
+	installAfterCommit()
 
 	log.Println("✅ Database connected successfully")
 
@@ -33,10 +34,121 @@ func Connect() {
 		&models.Settlement{},
 		&models.Activity{},
 		&models.Invitation{},
+		&models.InviteLink{},
+		&models.OCRJob{},
+		&models.Comment{},
+		&models.Reaction{},
+		&models.Tombstone{},
+		&models.Webhook{},
+		&models.WebhookDelivery{},
+		&models.OutboxMessage{},
+		&models.NotificationPreference{},
+		&models.Notification{},
+		&models.DeviceToken{},
 	)
 	if err != nil {
 		log.Fatal("Failed to migrate database:", err)
 	}
 
 	log.Println("✅ Database migrated successfully")
+
+	backfillContactHashes()
+	backfillGroupOwners()
+	backfillDeviceTokens()
+	createSearchIndexes()
+	createNotificationPreferenceIndex()
+	createChangeTracking()
+}
+
+// Indexes GORM tags can't express. The expression must match the one used by the search query.
+func createSearchIndexes() {
+	err := DB.Exec(`CREATE INDEX IF NOT EXISTS idx_expenses_search ON expenses
+		USING GIN (to_tsvector('simple', coalesce(description, '') || ' ' || coalesce(notes, '')))`).Error
+	if err != nil {
+		log.Printf("⚠️  Failed to create expense search index: %v", err)
+	}
+}
+
+// One preference row per user, group and event type. Older databases had a non-unique index
+// and could hold duplicates: keep the most recently updated row of each before enforcing it.
+func createNotificationPreferenceIndex() {
+	if DB.Migrator().HasIndex(&models.NotificationPreference{}, "idx_notification_pref_unique") {
+		return
+	}
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec(`DELETE FROM notification_preferences p USING notification_preferences q
+			WHERE p.user_id = q.user_id AND p.event_type = q.event_type
+			AND p.group_id IS NOT DISTINCT FROM q.group_id
+			AND (p.updated_at, p.id) < (q.updated_at, q.id)`).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Exec(`DROP INDEX IF EXISTS idx_notification_pref`).Error; err != nil {
+			return err
+		}
+		return tx.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_notification_pref_unique ON notification_preferences (` +
+			models.NotificationPreferenceKey + `)`).Error
+	})
+	if err != nil {
+		log.Printf("⚠️  Failed to create notification preference index: %v", err)
+	}
+}
+
+// Users created before hashed contact matching existed have no hashes yet
+func backfillContactHashes() {
+	var users []models.User
+	DB.Where("email_hash IS NULL OR email_hash = ''").Find(&users)
+
+	for _, u := range users {
+		u.SetContactHashes()
+		DB.Model(&u).UpdateColumns(map[string]interface{}{
+			"email_hash": u.EmailHash,
+			"phone_hash": u.PhoneHash,
+		})
+	}
+
+	if len(users) > 0 {
+		log.Printf("✅ Backfilled contact hashes for %d users", len(users))
+	}
+}
+
+// Push tokens used to be a single column on users: move them to device_tokens once.
+// The column is kept (but emptied) so older deployments can still start against this database.
+func backfillDeviceTokens() {
+	if !DB.Migrator().HasColumn(&models.User{}, "fcm_token") {
+		return
+	}
+
+	var moved int64
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Exec(`
+			INSERT INTO device_tokens (id, user_id, token, platform, last_seen_at, created_at, updated_at)
+			SELECT gen_random_uuid(), id, fcm_token, '', updated_at, NOW(), NOW()
+			FROM users WHERE fcm_token IS NOT NULL AND fcm_token <> ''
+			ON CONFLICT (token) DO NOTHING`)
+		if result.Error != nil {
+			return result.Error
+		}
+		moved = result.RowsAffected
+		return tx.Exec(`UPDATE users SET fcm_token = '' WHERE fcm_token <> ''`).Error
+	})
+	if err != nil {
+		log.Printf("⚠️  Failed to move push tokens to device_tokens: %v", err)
+		return
+	}
+	if moved > 0 {
+		log.Printf("✅ Moved %d push tokens to device_tokens", moved)
+	}
+}
+
+// Groups created before the owner role existed: promote the creator (if still an admin) to owner
+func backfillGroupOwners() {
+	result := DB.Exec(`
+		UPDATE group_members gm SET role = 'owner'
+		FROM groups g
+		WHERE gm.group_id = g.id AND gm.user_id = g.created_by AND gm.role = 'admin'
+		AND NOT EXISTS (SELECT 1 FROM group_members o WHERE o.group_id = gm.group_id AND o.role = 'owner')`)
+	if result.Error == nil && result.RowsAffected > 0 {
+		log.Printf("✅ Assigned owners to %d existing groups", result.RowsAffected)
+	}
 }
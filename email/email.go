@@ -0,0 +1,72 @@
+package email
+
+import (
+	"context"
+	"log"
+	"splitwise-backend/config"
+)
+
+// Message is one email to one recipient. Text and HTML are sent together as
+// multipart/alternative; mail clients show whichever they prefer.
+type Message struct {
+	To      string
+	ToName  string
+	Subject string
+	Text    string
+	HTML    string
+	Headers map[string]string // extra headers, e.g. List-Unsubscribe
+}
+
+// Transport hands messages to a mail service
+type Transport interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Default is the configured transport, nil when email is turned off
+var Default Transport
+
+// Setup picks the transport from EMAIL_TRANSPORT
+func Setup() {
+	cfg := config.AppConfig
+
+	switch cfg.EmailTransport {
+	case "sendgrid":
+		if cfg.SendGridAPIKey == "" {
+			log.Println("⚠️  SENDGRID_API_KEY not set, emails disabled")
+			return
+		}
+		Default = NewSendGrid(cfg.SendGridAPIKey, cfg.EmailFrom, cfg.AppName)
+		log.Println("✅ Sending email through SendGrid")
+	case "smtp":
+		if cfg.SMTPHost == "" {
+			log.Fatal("SMTP_HOST is required when EMAIL_TRANSPORT=smtp")
+		}
+		switch cfg.SMTPSecurity {
+		case "starttls", "tls", "none":
+		default:
+			log.Fatalf("Unknown SMTP_SECURITY %q (use starttls, tls or none)", cfg.SMTPSecurity)
+		}
+		Default = &SMTP{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			Security: cfg.SMTPSecurity,
+			From:     cfg.EmailFrom,
+			FromName: cfg.AppName,
+		}
+		log.Printf("✅ Sending email through SMTP at %s:%s (%s)", cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPSecurity)
+	case "file":
+		sink, err := NewFileSink(cfg.EmailSinkDir, cfg.EmailFrom, cfg.AppName)
+		if err != nil {
+			log.Fatal("Failed to set up email directory:", err)
+		}
+		Default = sink
+		log.Printf("⚠️  Emails are written to %s instead of being sent", sink.Dir)
+	case "memory":
+		Default = NewMemorySink(cfg.EmailFrom, cfg.AppName)
+		log.Println("⚠️  Emails are kept in memory instead of being sent")
+	default:
+		log.Println("⚠️  Emails disabled")
+	}
+}
@@ -0,0 +1,131 @@
+package email
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// buildMIME renders msg as an RFC 5322 message: multipart/alternative with the plain-text
+// part first (clients pick the last part they can display, so HTML wins where supported)
+func buildMIME(from mail.Address, msg Message, now time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+	body := multipart.NewWriter(&buf)
+
+	header := func(key, value string) {
+		fmt.Fprintf(&buf, "%s: %s\r\n", key, value)
+	}
+	header("From", from.String())
+	header("To", (&mail.Address{Name: msg.ToName, Address: msg.To}).String())
+	header("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	header("Date", now.Format(time.RFC1123Z))
+	header("Message-ID", messageID(from.Address))
+	header("MIME-Version", "1.0")
+
+	extra := make([]string, 0, len(msg.Headers))
+	for key := range msg.Headers {
+		extra = append(extra, key)
+	}
+	sort.Strings(extra)
+	for _, key := range extra {
+		header(textproto.CanonicalMIMEHeaderKey(key), msg.Headers[key])
+	}
+	header("Content-Type", `multipart/alternative; boundary="`+body.Boundary()+`"`)
+	buf.WriteString("\r\n")
+
+	text := msg.Text
+	if text == "" {
+		text = HTMLToText(msg.HTML)
+	}
+	parts := []struct{ contentType, content string }{
+		{"text/plain; charset=utf-8", text},
+		{"text/html; charset=utf-8", msg.HTML},
+	}
+	for _, p := range parts {
+		if p.content == "" {
+			continue
+		}
+		w, err := body.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {p.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write([]byte(strings.ReplaceAll(p.content, "\n", "\r\n"))); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := body.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Helper: a unique Message-ID in the sender's domain
+func messageID(from string) string {
+	domain := "localhost"
+	if _, d, ok := strings.Cut(from, "@"); ok && d != "" {
+		domain = d
+	}
+	b := make([]byte, 12)
+	rand.Read(b)
+	return fmt.Sprintf("<%d.%s@%s>", time.Now().UnixNano(), hex.EncodeToString(b), domain)
+}
+
+var (
+	invisibleRe  = regexp.MustCompile(`(?is)<(head|style|script)\b.*?</(head|style|script)>`)
+	linkRe       = regexp.MustCompile(`(?is)<a\s[^>]*href\s*=\s*["']([^"']*)["'][^>]*>(.*?)</a>`)
+	lineBreakRe  = regexp.MustCompile(`(?i)<br\s*/?>|</(li|tr)>`)
+	blockEndRe   = regexp.MustCompile(`(?i)</(p|div|h[1-6]|table|ul|ol)>`)
+	listItemRe   = regexp.MustCompile(`(?i)<li\b[^>]*>`)
+	tagRe        = regexp.MustCompile(`<[^>]*>`)
+	whitespaceRe = regexp.MustCompile(`\s+`)
+	blankLinesRe = regexp.MustCompile(`\n{3,}`)
+)
+
+// HTMLToText derives a readable plain-text version of an email body. Links keep their
+// target in brackets so they still work in text-only clients.
+func HTMLToText(body string) string {
+	s := invisibleRe.ReplaceAllString(body, "")
+	s = whitespaceRe.ReplaceAllString(s, " ") // newlines in the source mean nothing in HTML
+	s = linkRe.ReplaceAllStringFunc(s, func(a string) string {
+		m := linkRe.FindStringSubmatch(a)
+		label := strings.TrimSpace(tagRe.ReplaceAllString(m[2], ""))
+		if label == "" || label == m[1] {
+			return m[1]
+		}
+		return label + " (" + m[1] + ")"
+	})
+	s = listItemRe.ReplaceAllString(s, "- ")
+	s = lineBreakRe.ReplaceAllString(s, "\n")
+	s = blockEndRe.ReplaceAllString(s, "\n\n")
+	s = tagRe.ReplaceAllString(s, "")
+	s = html.UnescapeString(s)
+
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	s = strings.Join(lines, "\n")
+	s = strings.TrimSpace(blankLinesRe.ReplaceAllString(s, "\n\n"))
+	if s == "" {
+		return ""
+	}
+	return s + "\n"
+}
@@ -0,0 +1,132 @@
+package email
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildMIME(t *testing.T) {
+	from := mail.Address{Name: "SplitApp", Address: "noreply@split.example"}
+	msg := Message{
+		To:      "asha@example.com",
+		ToName:  "Asha Rao",
+		Subject: "Café bill — ₹1,200 split",
+		HTML:    `<p>Hi Asha,</p><p>Ravi added <a href="https://split.example/e/1">Dinner</a>.</p>`,
+		Headers: map[string]string{
+			"list-unsubscribe":      "<https://split.example/u?t=abc>",
+			"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+		},
+	}
+	now := time.Date(2024, 3, 14, 18, 30, 0, 0, time.UTC)
+
+	raw, err := buildMIME(from, msg, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("not a parseable message: %v\n%s", err, raw)
+	}
+	h := parsed.Header
+
+	// Non-ASCII subjects go out as encoded words and decode back to the original
+	if !strings.HasPrefix(h.Get("Subject"), "=?utf-8?q?") {
+		t.Errorf("Subject = %q, want a Q-encoded word", h.Get("Subject"))
+	}
+	if subject, err := new(mime.WordDecoder).DecodeHeader(h.Get("Subject")); err != nil || subject != msg.Subject {
+		t.Errorf("decoded Subject = %q, %v", subject, err)
+	}
+	if to, err := mail.ParseAddress(h.Get("To")); err != nil || to.Name != "Asha Rao" || to.Address != "asha@example.com" {
+		t.Errorf("To = %q", h.Get("To"))
+	}
+	if date, err := h.Date(); err != nil || !date.Equal(now) {
+		t.Errorf("Date = %q", h.Get("Date"))
+	}
+	if !strings.HasSuffix(h.Get("Message-ID"), "@split.example>") {
+		t.Errorf("Message-ID = %q, want one in the sender's domain", h.Get("Message-ID"))
+	}
+
+	// Extra headers are canonicalized and end up in the header block
+	if h.Get("List-Unsubscribe") != msg.Headers["list-unsubscribe"] || h.Get("List-Unsubscribe-Post") != "List-Unsubscribe=One-Click" {
+		t.Errorf("extra headers missing: %v", h)
+	}
+	if bytes.Contains(raw, []byte("\n\n")) || !bytes.Contains(raw, []byte("\r\n\r\n")) {
+		t.Error("lines are not CRLF-terminated")
+	}
+
+	mediaType, params, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Content-Type = %q", h.Get("Content-Type"))
+	}
+	parts := multipart.NewReader(parsed.Body, params["boundary"])
+
+	want := []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", "Hi Asha,\r\n\r\nRavi added Dinner (https://split.example/e/1).\r\n"},
+		{"text/html; charset=utf-8", msg.HTML},
+	}
+	for _, w := range want {
+		part, err := parts.NextRawPart()
+		if err != nil {
+			t.Fatalf("missing %s part: %v", w.contentType, err)
+		}
+		if part.Header.Get("Content-Type") != w.contentType || part.Header.Get("Content-Transfer-Encoding") != "quoted-printable" {
+			t.Errorf("part headers = %v", part.Header)
+		}
+		body, err := io.ReadAll(quotedprintable.NewReader(part))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(body) != w.body {
+			t.Errorf("%s part = %q, want %q", w.contentType, body, w.body)
+		}
+	}
+	if _, err := parts.NextRawPart(); err != io.EOF {
+		t.Errorf("more than two parts: %v", err)
+	}
+}
+
+func TestBuildMIMEPlainSubjectAndTextOnly(t *testing.T) {
+	raw, err := buildMIME(mail.Address{Address: "noreply@split.example"}, Message{To: "a@example.com", Subject: "Weekly summary", Text: "Nothing new"}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Header.Get("Subject") != "Weekly summary" {
+		t.Errorf("ASCII Subject = %q, want it unencoded", parsed.Header.Get("Subject"))
+	}
+	if strings.Count(string(raw), "Content-Transfer-Encoding") != 1 || strings.Contains(string(raw), "text/html") {
+		t.Errorf("want a single text/plain part:\n%s", raw)
+	}
+}
+
+func TestHTMLToText(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"empty", "", ""},
+		{"paragraphs", "<p>One</p><p>Two</p>", "One\n\nTwo\n"},
+		{"line breaks", "a<br>b<BR/>c", "a\nb\nc\n"},
+		{"links keep their target", `<a href="https://x.example/a">Open</a>`, "Open (https://x.example/a)\n"},
+		{"bare links are not repeated", `<a href='https://x.example'>https://x.example</a>`, "https://x.example\n"},
+		{"lists", "<ul><li>Milk</li><li>Eggs</li></ul>", "- Milk\n- Eggs\n"},
+		{"head, style and script are dropped", "<head><title>T</title></head><style>p{}</style><script>x()</script><p>Body</p>", "Body\n"},
+		{"entities", "<p>Fish &amp; chips &lt;3 &#8377;</p>", "Fish & chips <3 ₹\n"},
+		{"source newlines are spaces", "<p>Split\n   evenly</p>", "Split evenly\n"},
+		{"blank lines collapse", "<p>A</p><br><br><br><p>B</p>", "A\n\nB\n"},
+	}
+	for _, tt := range tests {
+		if got := HTMLToText(tt.in); got != tt.want {
+			t.Errorf("%s: HTMLToText(%q) = %q, want %q", tt.name, tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+const sendGridURL = "https://api.sendgrid.com/v3/mail/send"
+
+// SendGrid sends through the SendGrid v3 HTTP API
+type SendGrid struct {
+	apiKey string
+	from   sgAddress
+	client *http.Client
+}
+
+type sendGridEmail struct {
+	Personalizations []sgPersonalization `json:"personalizations"`
+	From             sgAddress           `json:"from"`
+	Subject          string              `json:"subject"`
+	Content          []sgContent         `json:"content"`
+	Headers          map[string]string   `json:"headers,omitempty"`
+}
+
+type sgPersonalization struct {
+	To []sgAddress `json:"to"`
+}
+
+type sgAddress struct {
+	Email string `json:"email"`
+	Name  string `json:"name,omitempty"`
+}
+
+type sgContent struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+func NewSendGrid(apiKey, from, fromName string) *SendGrid {
+	return &SendGrid{
+		apiKey: apiKey,
+		from:   sgAddress{Email: from, Name: fromName},
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (s *SendGrid) Send(ctx context.Context, msg Message) error {
+	text := msg.Text
+	if text == "" {
+		text = HTMLToText(msg.HTML)
+	}
+
+	// SendGrid wants text/plain before text/html
+	var content []sgContent
+	if text != "" {
+		content = append(content, sgContent{Type: "text/plain", Value: text})
+	}
+	if msg.HTML != "" {
+		content = append(content, sgContent{Type: "text/html", Value: msg.HTML})
+	}
+
+	jsonData, err := json.Marshal(sendGridEmail{
+		Personalizations: []sgPersonalization{
+			{To: []sgAddress{{Email: msg.To, Name: msg.ToName}}},
+		},
+		From:    s.from,
+		Subject: msg.Subject,
+		Content: content,
+		Headers: msg.Headers,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sendGridURL, bytes.NewReader(jsonData))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+s.apiKey)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 200))
+		return fmt.Errorf("SendGrid returned %d: %s", resp.StatusCode, bytes.TrimSpace(snippet))
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package email
+
+import (
+	"context"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// How many captured emails MemorySink keeps; older ones are dropped
+const sinkCapacity = 200
+
+// Captured is an email a sink caught instead of sending
+type Captured struct {
+	ID      string            `json:"id"`
+	From    string            `json:"from"`
+	To      string            `json:"to"`
+	ToName  string            `json:"to_name,omitempty"`
+	Subject string            `json:"subject"`
+	Text    string            `json:"text"`
+	HTML    string            `json:"html"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Raw     []byte            `json:"-"` // the full MIME message, as an SMTP server would receive it
+	SentAt  time.Time         `json:"sent_at"`
+}
+
+// Inbox is implemented by the transports that keep what they "send", so it can be looked at
+type Inbox interface {
+	Emails() []Captured // newest first
+	Email(id string) (Captured, bool)
+}
+
+// MemorySink keeps emails in memory (lost on restart), for local development and tests
+type MemorySink struct {
+	from mail.Address
+
+	mu     sync.RWMutex
+	emails []Captured
+}
+
+func NewMemorySink(from, fromName string) *MemorySink {
+	return &MemorySink{from: mail.Address{Name: fromName, Address: from}}
+}
+
+func (m *MemorySink) Send(ctx context.Context, msg Message) error {
+	_, err := m.capture(msg)
+	return err
+}
+
+// Helper: render msg exactly as it would go out and remember it
+func (m *MemorySink) capture(msg Message) (Captured, error) {
+	now := time.Now()
+	raw, err := buildMIME(m.from, msg, now)
+	if err != nil {
+		return Captured{}, err
+	}
+
+	text := msg.Text
+	if text == "" {
+		text = HTMLToText(msg.HTML)
+	}
+	captured := Captured{
+		ID:      uuid.NewString(),
+		From:    m.from.String(),
+		To:      msg.To,
+		ToName:  msg.ToName,
+		Subject: msg.Subject,
+		Text:    text,
+		HTML:    msg.HTML,
+		Headers: msg.Headers,
+		Raw:     raw,
+		SentAt:  now,
+	}
+
+	m.mu.Lock()
+	m.emails = append(m.emails, captured)
+	if len(m.emails) > sinkCapacity {
+		m.emails = append([]Captured(nil), m.emails[len(m.emails)-sinkCapacity:]...)
+	}
+	m.mu.Unlock()
+	return captured, nil
+}
+
+func (m *MemorySink) Emails() []Captured {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	out := make([]Captured, 0, len(m.emails))
+	for i := len(m.emails) - 1; i >= 0; i-- {
+		out = append(out, m.emails[i])
+	}
+	return out
+}
+
+func (m *MemorySink) Email(id string) (Captured, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, e := range m.emails {
+		if e.ID == id {
+			return e, true
+		}
+	}
+	return Captured{}, false
+}
+
+// FileSink writes every email to Dir as an .eml file, which any mail client opens.
+// It also keeps the recent ones in memory for the dev endpoints.
+type FileSink struct {
+	*MemorySink
+	Dir string
+}
+
+func NewFileSink(dir, from, fromName string) (*FileSink, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileSink{MemorySink: NewMemorySink(from, fromName), Dir: dir}, nil
+}
+
+func (f *FileSink) Send(ctx context.Context, msg Message) error {
+	captured, err := f.capture(msg)
+	if err != nil {
+		return err
+	}
+	name := captured.SentAt.UTC().Format("20060102T150405.000Z") + "-" + captured.ID + ".eml"
+	return os.WriteFile(filepath.Join(f.Dir, name), captured.Raw, 0o644)
+}
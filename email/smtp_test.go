@@ -0,0 +1,293 @@
+package email
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"errors"
+	"math/big"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeSMTP is just enough of an SMTP server to check what the client does: it offers
+// STARTTLS on plain connections, only accepts AUTH PLAIN over TLS, and records each mail
+type fakeSMTP struct {
+	tlsConfig   *tls.Config
+	offerTLS    bool
+	implicitTLS bool
+	user, pass  string
+	rcptCode    int // answer to RCPT TO; 0 accepts
+
+	mu    sync.Mutex
+	mails []fakeMail
+}
+
+type fakeMail struct {
+	tls  bool
+	user string
+	from string
+	to   []string
+	data string
+}
+
+// Helper: a certificate for 127.0.0.1 and the pool that trusts it
+func selfSignedCert(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "fake smtp"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, pool
+}
+
+// startFakeSMTP listens on 127.0.0.1 and returns a client configured for it
+func startFakeSMTP(t *testing.T, f *fakeSMTP, security string) *SMTP {
+	cert, pool := selfSignedCert(t)
+	f.tlsConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.implicitTLS {
+		listener = tls.NewListener(listener, f.tlsConfig)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	return &SMTP{
+		Host:     "127.0.0.1",
+		Port:     port,
+		Username: f.user,
+		Password: f.pass,
+		Security: security,
+		From:     "noreply@split.example",
+		FromName: "SplitApp",
+		rootCAs:  pool,
+	}
+}
+
+func (f *fakeSMTP) serve(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+
+	tp := textproto.NewConn(conn)
+	msg := fakeMail{tls: f.implicitTLS}
+	authed := false
+	reply := func(lines ...string) {
+		for i, line := range lines {
+			sep := "-"
+			if i == len(lines)-1 {
+				sep = " "
+			}
+			tp.PrintfLine("%s%s%s", line[:3], sep, line[4:])
+		}
+	}
+
+	reply("220 fake ESMTP")
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			lines := []string{"250 fake"}
+			if f.offerTLS && !msg.tls {
+				lines = append(lines, "250 STARTTLS")
+			}
+			if msg.tls {
+				lines = append(lines, "250 AUTH PLAIN")
+			}
+			reply(append(lines, "250 8BITMIME")...)
+		case "STARTTLS":
+			if !f.offerTLS || msg.tls {
+				reply("502 not available")
+				continue
+			}
+			reply("220 go ahead")
+			tlsConn := tls.Server(conn, f.tlsConfig)
+			if tlsConn.Handshake() != nil {
+				return
+			}
+			conn, tp = tlsConn, textproto.NewConn(tlsConn)
+			msg.tls = true
+		case "AUTH":
+			mech, initial, _ := strings.Cut(arg, " ")
+			if !msg.tls {
+				reply("530 must issue STARTTLS first")
+				continue
+			}
+			decoded, err := base64.StdEncoding.DecodeString(initial)
+			creds := strings.Split(string(decoded), "\x00")
+			if mech != "PLAIN" || err != nil || len(creds) != 3 || creds[1] != f.user || creds[2] != f.pass {
+				reply("535 authentication failed")
+				continue
+			}
+			authed, msg.user = true, creds[1]
+			reply("235 authenticated")
+		case "MAIL":
+			if f.user != "" && !authed {
+				reply("530 authentication required")
+				continue
+			}
+			msg.from, _, _ = strings.Cut(strings.TrimPrefix(arg, "FROM:"), " ") // drop parameters like BODY=8BITMIME
+			reply("250 ok")
+		case "RCPT":
+			if f.rcptCode != 0 {
+				tp.PrintfLine("%d no such mailbox", f.rcptCode)
+				continue
+			}
+			msg.to = append(msg.to, strings.TrimPrefix(arg, "TO:"))
+			reply("250 ok")
+		case "DATA":
+			reply("354 end with .")
+			data, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			msg.data = string(data)
+			f.mu.Lock()
+			f.mails = append(f.mails, msg)
+			f.mu.Unlock()
+			reply("250 queued")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 unknown command")
+		}
+	}
+}
+
+func (f *fakeSMTP) received() []fakeMail {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]fakeMail(nil), f.mails...)
+}
+
+var testEmail = Message{To: "asha@example.com", ToName: "Asha", Subject: "Welcome", HTML: "<p>Hello</p>"}
+
+func TestSMTPStartTLSAndAuth(t *testing.T) {
+	f := &fakeSMTP{offerTLS: true, user: "mailer", pass: "hunter2"}
+	s := startFakeSMTP(t, f, "starttls")
+
+	if err := s.Send(context.Background(), testEmail); err != nil {
+		t.Fatal(err)
+	}
+	mails := f.received()
+	if len(mails) != 1 {
+		t.Fatalf("server got %d mails", len(mails))
+	}
+	got := mails[0]
+	if !got.tls || got.user != "mailer" {
+		t.Errorf("mail sent with tls=%v as %q, want over TLS as mailer", got.tls, got.user)
+	}
+	if got.from != "<noreply@split.example>" || len(got.to) != 1 || got.to[0] != "<asha@example.com>" {
+		t.Errorf("envelope from %s to %v", got.from, got.to)
+	}
+	msg, err := mail.ReadMessage(strings.NewReader(got.data))
+	if err != nil || msg.Header.Get("Subject") != "Welcome" {
+		t.Errorf("data is not the message: %v\n%s", err, got.data)
+	}
+}
+
+func TestSMTPImplicitTLS(t *testing.T) {
+	f := &fakeSMTP{implicitTLS: true, user: "mailer", pass: "hunter2"}
+	s := startFakeSMTP(t, f, "tls")
+
+	if err := s.Send(context.Background(), testEmail); err != nil {
+		t.Fatal(err)
+	}
+	if mails := f.received(); len(mails) != 1 || !mails[0].tls || mails[0].user != "mailer" {
+		t.Errorf("server got %+v", mails)
+	}
+}
+
+func TestSMTPErrors(t *testing.T) {
+	t.Run("STARTTLS not offered", func(t *testing.T) {
+		s := startFakeSMTP(t, &fakeSMTP{}, "starttls")
+		err := s.Send(context.Background(), testEmail)
+		if err == nil || !strings.Contains(err.Error(), "does not offer STARTTLS") {
+			t.Errorf("send = %v", err)
+		}
+	})
+
+	t.Run("untrusted certificate", func(t *testing.T) {
+		s := startFakeSMTP(t, &fakeSMTP{offerTLS: true}, "starttls")
+		s.rootCAs = x509.NewCertPool()
+		var unknown x509.UnknownAuthorityError
+		if err := s.Send(context.Background(), testEmail); !errors.As(err, &unknown) {
+			t.Errorf("send = %v, want an unknown authority error", err)
+		}
+	})
+
+	t.Run("wrong password", func(t *testing.T) {
+		f := &fakeSMTP{offerTLS: true, user: "mailer", pass: "hunter2"}
+		s := startFakeSMTP(t, f, "starttls")
+		s.Password = "wrong"
+		err := s.Send(context.Background(), testEmail)
+		if err == nil || !strings.Contains(err.Error(), "535") {
+			t.Errorf("send = %v, want a 535", err)
+		}
+		if len(f.received()) != 0 {
+			t.Error("mail accepted without authentication")
+		}
+	})
+
+	t.Run("recipient rejected", func(t *testing.T) {
+		s := startFakeSMTP(t, &fakeSMTP{offerTLS: true, rcptCode: 550}, "starttls")
+		if err := s.Send(context.Background(), testEmail); !errors.Is(err, ErrRejected) {
+			t.Errorf("send = %v, want ErrRejected", err)
+		}
+	})
+
+	t.Run("temporary failure is retryable", func(t *testing.T) {
+		s := startFakeSMTP(t, &fakeSMTP{offerTLS: true, rcptCode: 451}, "starttls")
+		err := s.Send(context.Background(), testEmail)
+		if err == nil || errors.Is(err, ErrRejected) {
+			t.Errorf("send = %v, want a plain error", err)
+		}
+	})
+}
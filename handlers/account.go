@@ -0,0 +1,317 @@
+package handlers
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"splitwise-backend/database"
+	"splitwise-backend/models"
+	"splitwise-backend/utils"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// GET /api/users/me/export — zip archive with data.json and one CSV per table
+func ExportUserData(c *gin.Context) {
+	userID := utils.GetCurrentUserID(c)
+
+	var user models.User
+	if err := database.DB.First(&user, userID).Error; err != nil {
+		utils.NotFound(c, "User not found")
+		return
+	}
+
+	export := collectUserData(user)
+
+	filename := fmt.Sprintf("splitfree-export-%s.zip", export.ExportedAt.Format("2006-01-02"))
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Status(http.StatusOK)
+
+	zw := zip.NewWriter(c.Writer)
+	defer zw.Close()
+
+	if w, err := zw.Create("data.json"); err == nil {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(export)
+	}
+
+	writeCSV(zw, "profile.csv", []string{"id", "name", "email", "phone", "currency", "created_at"}, [][]string{
+		{user.ID.String(), user.Name, user.Email, user.Phone, user.Currency, user.CreatedAt.Format(time.RFC3339)},
+	})
+
+	var rows [][]string
+	for _, g := range export.Groups {
+		rows = append(rows, []string{g.ID.String(), g.Name, g.Type, g.CreatedBy.String(), g.CreatedAt.Format(time.RFC3339)})
+	}
+	writeCSV(zw, "groups.csv", []string{"id", "name", "type", "created_by", "created_at"}, rows)
+
+	rows = nil
+	for _, e := range export.Expenses {
+		rows = append(rows, []string{
+			e.ID.String(), e.GroupID.String(), e.PaidBy.String(), e.Description, formatAmount(e.Amount),
+			e.Currency, e.Category, e.SplitType, e.Notes, e.ExpenseDate.Format("2006-01-02"), e.CreatedAt.Format(time.RFC3339),
+		})
+	}
+	writeCSV(zw, "expenses.csv", []string{"id", "group_id", "paid_by", "description", "amount", "currency", "category", "split_type", "notes", "expense_date", "created_at"}, rows)
+
+	rows = nil
+	for _, s := range export.Splits {
+		rows = append(rows, []string{s.ID.String(), s.ExpenseID.String(), s.UserID.String(), formatAmount(s.OwedAmount), formatAmount(s.PaidAmount)})
+	}
+	writeCSV(zw, "splits.csv", []string{"id", "expense_id", "user_id", "owed_amount", "paid_amount"}, rows)
+
+	rows = nil
+	for _, s := range export.Settlements {
+		rows = append(rows, []string{s.ID.String(), s.GroupID.String(), s.PaidBy.String(), s.PaidTo.String(), formatAmount(s.Amount), s.Notes, s.CreatedAt.Format(time.RFC3339)})
+	}
+	writeCSV(zw, "settlements.csv", []string{"id", "group_id", "paid_by", "paid_to", "amount", "notes", "created_at"}, rows)
+
+	rows = nil
+	for _, a := range export.Activity {
+		rows = append(rows, []string{a.ID.String(), a.GroupID.String(), a.Type, a.Description, a.CreatedAt.Format(time.RFC3339)})
+	}
+	writeCSV(zw, "activity.csv", []string{"id", "group_id", "type", "description", "created_at"}, rows)
+}
+
+// DELETE /api/users/me
+// The user row is anonymized rather than deleted so that expenses, splits and
+// settlements keep pointing at a valid user and other members' balances stay correct.
+// Deletion is refused while the user has non-zero balances unless ?force=true is passed.
+func DeleteAccount(c *gin.Context) {
+	userID := utils.GetCurrentUserID(c)
+
+	var req models.DeleteAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, userID).Error; err != nil {
+		utils.NotFound(c, "User not found")
+		return
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
+		utils.Unauthorized(c, "Invalid password")
+		return
+	}
+
+	outstanding := outstandingBalances(userID)
+	if len(outstanding) > 0 && c.Query("force") != "true" {
+		utils.Conflict(c, "You still have unsettled balances. Settle up first or pass force=true to delete anyway", outstanding)
+		return
+	}
+
+	var memberships []models.GroupMember
+	database.DB.Where("user_id = ? AND status = ?", userID, models.MemberActive).Find(&memberships)
+
+	now := time.Now()
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		// Personal webhooks go first so they don't get told about the account's own deletion
+		hookIDs := tx.Model(&models.Webhook{}).Select("id").Where("user_id = ? AND group_id IS NULL", userID)
+		if err := tx.Where("webhook_id IN (?)", hookIDs).Delete(&models.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ? AND group_id IS NULL", userID).Delete(&models.Webhook{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&models.OutboxMessage{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&models.NotificationPreference{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&models.Notification{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&models.DeviceToken{}).Error; err != nil {
+			return err
+		}
+
+		for _, m := range memberships {
+			if err := tx.Create(&models.Activity{
+				GroupID:     m.GroupID,
+				UserID:      userID,
+				MemberID:    &userID,
+				Type:        "member_left",
+				Description: fmt.Sprintf("%s deleted their account", user.Name),
+			}).Error; err != nil {
+				return err
+			}
+			if m.Role == models.RoleOwner {
+				if err := handOverOwnership(tx, m.GroupID, user); err != nil {
+					return err
+				}
+			}
+		}
+
+		if err := tx.Model(&models.GroupMember{}).
+			Where("user_id = ? AND status = ?", userID, models.MemberActive).
+			Updates(map[string]interface{}{"status": models.MemberFormer, "left_at": now}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.Invitation{}).
+			Where("email = ? AND status = ?", user.Email, "pending").
+			Update("status", "declined").Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&user).UpdateColumns(map[string]interface{}{
+			"name":                          "Deleted user",
+			"email":                         fmt.Sprintf("deleted-%s@deleted.invalid", user.ID),
+			"phone":                         "",
+			"password_hash":                 "",
+			"avatar_url":                    "",
+			"email_hash":                    "",
+			"phone_hash":                    "",
+			"privacy_discoverable_by_email": false,
+			"privacy_discoverable_by_phone": false,
+			"privacy_contact_matching":      false,
+			"anonymized_at":                 now,
+			"updated_at":                    now,
+		}).Error
+	})
+	if err != nil {
+		utils.InternalError(c, "Failed to delete account")
+		return
+	}
+
+	message := "Account deleted"
+	if len(outstanding) > 0 {
+		message = "Account deleted with unsettled balances still recorded in your groups"
+	}
+	utils.SuccessResponse(c, http.StatusOK, message, outstanding)
+}
+
+// Helper: gather everything the export contains for a user
+func collectUserData(user models.User) models.UserDataExport {
+	export := models.UserDataExport{
+		ExportedAt:  time.Now().UTC(),
+		Profile:     user.ToResponse(),
+		Groups:      []models.Group{},
+		Expenses:    []models.Expense{},
+		Splits:      []models.ExpenseSplit{},
+		Settlements: []models.Settlement{},
+		Activity:    []models.Activity{},
+	}
+
+	database.DB.Where("id IN (?)", database.DB.Model(&models.GroupMember{}).Select("group_id").Where("user_id = ?", user.ID)).
+		Order("created_at").
+		Find(&export.Groups)
+
+	database.DB.Where("user_id = ?", user.ID).Order("created_at").Find(&export.Splits)
+
+	database.DB.Where("paid_by = ? OR id IN (?)", user.ID,
+		database.DB.Model(&models.ExpenseSplit{}).Select("expense_id").Where("user_id = ?", user.ID)).
+		Order("expense_date, created_at").
+		Find(&export.Expenses)
+
+	database.DB.Where("paid_by = ? OR paid_to = ?", user.ID, user.ID).Order("created_at").Find(&export.Settlements)
+
+	// Activity by the user, about them (added, removed, role changes), on their expenses and
+	// settlements, and on anything they were mentioned in
+	refs := []uuid.UUID{user.ID}
+	for _, e := range export.Expenses {
+		refs = append(refs, e.ID)
+	}
+	for _, s := range export.Settlements {
+		refs = append(refs, s.ID)
+	}
+	mentionedOn := database.DB.Model(&models.Comment{}).Select("target_id").
+		Where("mentions @> ?", fmt.Sprintf(`[%q]`, user.ID.String()))
+	database.DB.Where("user_id = ? OR member_id = ? OR reference_id IN ? OR reference_id IN (?)", user.ID, user.ID, refs, mentionedOn).
+		Order("created_at").
+		Find(&export.Activity)
+
+	return export
+}
+
+// Helper: a group must not be left without an owner. The longest-standing admin takes over,
+// or failing that the longest-standing member; a group with nobody else in it keeps no owner.
+func handOverOwnership(tx *gorm.DB, groupID uuid.UUID, leaving models.User) error {
+	var heir models.GroupMember
+	err := tx.Where("group_id = ? AND user_id <> ? AND status = ?", groupID, leaving.ID, models.MemberActive).
+		Order("CASE role WHEN 'admin' THEN 0 WHEN 'member' THEN 1 ELSE 2 END, joined_at").
+		First(&heir).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Model(&models.GroupMember{}).
+		Where("group_id = ? AND user_id = ?", groupID, heir.UserID).
+		Update("role", models.RoleOwner).Error; err != nil {
+		return err
+	}
+
+	var newOwner models.User
+	tx.First(&newOwner, heir.UserID)
+	return tx.Create(&models.Activity{
+		GroupID:     groupID,
+		UserID:      leaving.ID,
+		Type:        "ownership_transferred",
+		ReferenceID: heir.UserID,
+		Description: fmt.Sprintf("%s is now the owner after %s deleted their account", newOwner.Name, leaving.Name),
+	}).Error
+}
+
+// Helper: non-zero net balances of a user across their groups
+func outstandingBalances(userID uuid.UUID) []models.OutstandingBalance {
+	var memberships []models.GroupMember
+	database.DB.Where("user_id = ?", userID).Find(&memberships)
+
+	groupIDs := make([]uuid.UUID, 0, len(memberships))
+	for _, m := range memberships {
+		groupIDs = append(groupIDs, m.GroupID)
+	}
+	if len(groupIDs) == 0 {
+		return nil
+	}
+
+	balances := calculateGroupNetBalances(groupIDs)
+
+	var groups []models.Group
+	database.DB.Where("id IN ?", groupIDs).Find(&groups)
+
+	var outstanding []models.OutstandingBalance
+	for _, group := range groups {
+		amount := utils.RoundToTwo(balances[group.ID][userID])
+		if isSettledAmount(amount) {
+			continue
+		}
+
+		outstanding = append(outstanding, models.OutstandingBalance{
+			GroupID:   group.ID,
+			GroupName: group.Name,
+			Amount:    amount,
+		})
+	}
+	return outstanding
+}
+
+func writeCSV(zw *zip.Writer, name string, header []string, rows [][]string) {
+	w, err := zw.Create(name)
+	if err != nil {
+		return
+	}
+	cw := csv.NewWriter(w)
+	cw.Write(header)
+	cw.WriteAll(rows)
+}
+
+func formatAmount(v float64) string {
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
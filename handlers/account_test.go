@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"splitwise-backend/database"
+	"splitwise-backend/middleware"
+	"splitwise-backend/models"
+	"splitwise-backend/utils"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Deleting the owner's account hands the group to the longest-standing admin, not to a
+// member who joined earlier and not to a newer admin
+func TestDeleteAccountHandsOverOwnership(t *testing.T) {
+	requireDB(t)
+
+	owner := createTestUser(t, "Olive")
+	member := createTestUser(t, "Mia")
+	admin := createTestUser(t, "Ada")
+	newAdmin := createTestUser(t, "Nia")
+	group := createTestGroup(t, owner, member, admin, newAdmin)
+
+	hash, _ := bcrypt.GenerateFromPassword([]byte("secret123"), bcrypt.MinCost)
+	database.DB.Model(&owner).Update("password_hash", string(hash))
+
+	now := time.Now()
+	setMember := func(userID interface{}, role string, joined time.Time) {
+		database.DB.Model(&models.GroupMember{}).
+			Where("group_id = ? AND user_id = ?", group.ID, userID).
+			Updates(map[string]interface{}{"role": role, "joined_at": joined})
+	}
+	setMember(owner.ID, models.RoleOwner, now.Add(-4*time.Hour))
+	setMember(member.ID, models.RoleMember, now.Add(-3*time.Hour))
+	setMember(admin.ID, models.RoleAdmin, now.Add(-2*time.Hour))
+	setMember(newAdmin.ID, models.RoleAdmin, now.Add(-time.Hour))
+
+	w := serveAs(owner, DeleteAccount, http.MethodDelete, "/api/users/me", "/api/users/me",
+		models.DeleteAccountRequest{Password: "secret123"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("delete account: %d %s", w.Code, w.Body.String())
+	}
+
+	var owners []models.GroupMember
+	database.DB.Where("group_id = ? AND role = ? AND status = ?", group.ID, models.RoleOwner, models.MemberActive).Find(&owners)
+	if len(owners) != 1 || owners[0].UserID != admin.ID {
+		t.Fatalf("owners after deletion = %+v, want only %s", owners, admin.ID)
+	}
+}
+
+// A token issued before deletion stops working at once, not when it expires
+func TestDeletedAccountTokenRejected(t *testing.T) {
+	requireDB(t)
+
+	user := createTestUser(t, "Dana")
+	hash, _ := bcrypt.GenerateFromPassword([]byte("secret123"), bcrypt.MinCost)
+	database.DB.Model(&user).Update("password_hash", string(hash))
+	token, err := utils.GenerateToken(user.ID, user.Email)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	api := r.Group("/api", middleware.AuthRequired())
+	api.GET("/users/me", GetProfile)
+	api.PUT("/users/me", UpdateProfile)
+	api.DELETE("/users/me", DeleteAccount)
+
+	call := func(method, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/users/me", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := call(http.MethodGet, ""); w.Code != http.StatusOK {
+		t.Fatalf("profile before deletion: %d %s", w.Code, w.Body.String())
+	}
+	if w := call(http.MethodDelete, `{"password":"secret123"}`); w.Code != http.StatusOK {
+		t.Fatalf("delete account: %d %s", w.Code, w.Body.String())
+	}
+
+	if w := call(http.MethodPut, `{"name":"Back again","phone":"+15550100"}`); w.Code != http.StatusUnauthorized {
+		t.Errorf("profile update after deletion: %d, want 401", w.Code)
+	}
+	var after models.User
+	database.DB.First(&after, user.ID)
+	if after.Name == "Back again" || after.Phone != "" {
+		t.Errorf("deleted account was edited: %q %q", after.Name, after.Phone)
+	}
+}
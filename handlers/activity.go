@@ -8,14 +8,18 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 // GET /api/activity — global activity feed for current user
 func GetActivity(c *gin.Context) {
 	userID := utils.GetCurrentUserID(c)
 
-	var pagination utils.PaginationQuery
-	c.ShouldBindQuery(&pagination)
+	pagination, err := utils.BindPagination(c)
+	if err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
 
 	// Get all groups user is in
 	var memberships []models.GroupMember
@@ -26,15 +30,22 @@ func GetActivity(c *gin.Context) {
 		groupIDs = append(groupIDs, m.GroupID)
 	}
 
-	var activities []models.Activity
+	activities := []models.Activity{}
+	var total int64
+	var fetched int
 	if len(groupIDs) > 0 {
+		database.DB.Model(&models.Activity{}).Where("group_id IN ?", groupIDs).Count(&total)
 		database.DB.Where("group_id IN ?", groupIDs).
 			Preload("User").
-			Order("created_at DESC").
-			Offset(pagination.Offset()).
-			Limit(pagination.Limit).
+			Scopes(pagination.Scope(createdAtKeyset)).
+			Order("created_at DESC, id DESC").
 			Find(&activities)
 
+		fetched = len(activities)
+		if fetched > pagination.Limit {
+			activities = activities[:pagination.Limit]
+		}
+
 		// Attach group names
 		groupNames := make(map[uuid.UUID]string)
 		var groups []models.Group
@@ -47,33 +58,54 @@ func GetActivity(c *gin.Context) {
 		}
 	}
 
-	utils.SuccessResponse(c, http.StatusOK, "", activities)
+	var last utils.Cursor
+	if n := len(activities); n > 0 {
+		last = utils.Cursor{CreatedAt: activities[n-1].CreatedAt, ID: activities[n-1].ID}
+	}
+	utils.SuccessResponseWithMeta(c, http.StatusOK, "", activities, pagination.Meta(total, fetched, last))
 }
 
 // GET /api/groups/:id/activity — activity feed for a specific group
 func GetGroupActivity(c *gin.Context) {
-	userID := utils.GetCurrentUserID(c)
 	groupID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
 		utils.BadRequest(c, "Invalid group ID")
 		return
 	}
 
-	if !isMember(groupID, userID) {
-		utils.Unauthorized(c, "You are not a member of this group")
+	if _, ok := authorize(c, groupID, models.PermViewGroup); !ok {
+		return
+	}
+
+	pagination, err := utils.BindPagination(c)
+	if err != nil {
+		utils.BadRequest(c, err.Error())
 		return
 	}
 
-	var pagination utils.PaginationQuery
-	c.ShouldBindQuery(&pagination)
+	var total int64
+	database.DB.Model(&models.Activity{}).Where("group_id = ?", groupID).Count(&total)
 
-	var activities []models.Activity
+	activities := []models.Activity{}
 	database.DB.Where("group_id = ?", groupID).
 		Preload("User").
-		Order("created_at DESC").
-		Offset(pagination.Offset()).
-		Limit(pagination.Limit).
+		Scopes(pagination.Scope(createdAtKeyset)).
+		Order("created_at DESC, id DESC").
 		Find(&activities)
 
-	utils.SuccessResponse(c, http.StatusOK, "", activities)
+	fetched := len(activities)
+	if fetched > pagination.Limit {
+		activities = activities[:pagination.Limit]
+	}
+
+	var last utils.Cursor
+	if n := len(activities); n > 0 {
+		last = utils.Cursor{CreatedAt: activities[n-1].CreatedAt, ID: activities[n-1].ID}
+	}
+	utils.SuccessResponseWithMeta(c, http.StatusOK, "", activities, pagination.Meta(total, fetched, last))
+}
+
+// Helper: rows after the cursor, for lists ordered by created_at, id (both DESC)
+func createdAtKeyset(db *gorm.DB, after utils.Cursor) *gorm.DB {
+	return db.Where("(created_at, id) < (?, ?)", after.CreatedAt, after.ID)
 }
@@ -1,9 +1,11 @@
 package handlers
 
 import (
+	"log"
 	"net/http"
 	"splitwise-backend/database"
 	"splitwise-backend/models"
+	"splitwise-backend/services"
 	"splitwise-backend/utils"
 	"strings"
 
@@ -120,31 +122,17 @@ func Login(c *gin.Context) {
 	})
 }
 
-// Auto-accept pending invitations when a user registers
+// Auto-accept pending invitations when a user registers.
+// Groups that require consent keep the invitation pending in the user's inbox.
 func acceptPendingInvitations(user models.User) {
-	var invitations []models.Invitation
-	database.DB.Where("(email = ? OR phone = ?) AND status = ?", user.Email, user.Phone, "pending").Find(&invitations)
-
-	for _, inv := range invitations {
-		// Add user to the group
-		member := models.GroupMember{
-			GroupID: inv.GroupID,
-			UserID:  user.ID,
-			Role:    "member",
+	for _, inv := range services.PendingInvitationsFor(user) {
+		if inv.Group.JoinPolicy == models.JoinPolicyConsent {
+			database.DB.Model(&models.Invitation{}).Where("id = ?", inv.ID).Update("invitee_id", user.ID)
+			continue
+		}
+
+		if err := services.AcceptInvitation(inv, user); err != nil {
+			log.Printf("⚠️  Failed to accept invitation %s for %s: %v", inv.ID, user.Email, err)
 		}
-		database.DB.Create(&member)
-
-		// Update invitation status
-		database.DB.Model(&inv).Update("status", "accepted")
-
-		// Log activity
-		var group models.Group
-		database.DB.First(&group, inv.GroupID)
-		database.DB.Create(&models.Activity{
-			GroupID:     inv.GroupID,
-			UserID:      user.ID,
-			Type:        "member_joined",
-			Description: user.Name + " joined " + group.Name,
-		})
 	}
 }
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"splitwise-backend/database"
+	"splitwise-backend/models"
+	"splitwise-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// groupAccess is what a handler gets back after a successful authorization check
+type groupAccess struct {
+	Group  models.Group
+	Member models.GroupMember
+}
+
+// Helper: check that the current user may perform perm in the group.
+// Writes the error response and returns false when access is denied.
+func authorize(c *gin.Context, groupID uuid.UUID, perm models.Permission) (*groupAccess, bool) {
+	userID := utils.GetCurrentUserID(c)
+
+	var access groupAccess
+	if err := database.DB.Where("group_id = ? AND user_id = ?", groupID, userID).First(&access.Member).Error; err != nil {
+		utils.Unauthorized(c, "You are not a member of this group")
+		return nil, false
+	}
+
+	if err := database.DB.First(&access.Group, groupID).Error; err != nil {
+		utils.NotFound(c, "Group not found")
+		return nil, false
+	}
+
+	if !access.Group.AllowsMember(access.Member, perm) {
+		if access.Group.IsArchived() {
+			utils.Forbidden(c, "This group is archived. Restore it to make changes")
+		} else if !access.Member.IsActive() {
+			utils.Forbidden(c, "Former members can only view the group and settle up")
+		} else {
+			utils.Forbidden(c, "Your role in this group does not allow this action")
+		}
+		return nil, false
+	}
+
+	return &access, true
+}
@@ -12,21 +12,17 @@ import (
 
 // GET /api/groups/:id/balances
 func GetGroupBalances(c *gin.Context) {
-	userID := utils.GetCurrentUserID(c)
 	groupID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
 		utils.BadRequest(c, "Invalid group ID")
 		return
 	}
 
-	if !isMember(groupID, userID) {
-		utils.Unauthorized(c, "You are not a member of this group")
+	access, ok := authorize(c, groupID, models.PermViewGroup)
+	if !ok {
 		return
 	}
 
-	var group models.Group
-	database.DB.First(&group, groupID)
-
 	// Calculate net balances from expenses
 	netBalance := calculateNetBalances(groupID)
 
@@ -39,7 +35,7 @@ func GetGroupBalances(c *gin.Context) {
 
 	summary := models.GroupBalanceSummary{
 		GroupID:    groupID,
-		GroupName:  group.Name,
+		GroupName:  access.Group.Name,
 		Balances:   balances,
 		TotalSpent: totalSpent,
 	}
@@ -58,11 +54,13 @@ func GetOverallBalances(c *gin.Context) {
 	// Aggregate balances across all groups
 	friendBalances := make(map[uuid.UUID]float64)
 
+	groupIDs := make([]uuid.UUID, 0, len(memberships))
 	for _, m := range memberships {
-		netBalance := calculateNetBalances(m.GroupID)
-		balances := simplifyDebts(netBalance)
+		groupIDs = append(groupIDs, m.GroupID)
+	}
 
-		for _, b := range balances {
+	for _, netBalance := range calculateGroupNetBalances(groupIDs) {
+		for _, b := range settleDebts(netBalance) {
 			if b.From == userID {
 				// I owe this person
 				friendBalances[b.To] -= b.Amount
@@ -76,14 +74,18 @@ func GetOverallBalances(c *gin.Context) {
 	var totalOwed, totalOwing float64
 	var friends []models.FriendBalance
 
+	friendIDs := make([]uuid.UUID, 0, len(friendBalances))
+	for friendID := range friendBalances {
+		friendIDs = append(friendIDs, friendID)
+	}
+	users := loadUsers(friendIDs)
+
 	for friendID, amount := range friendBalances {
 		if utils.RoundToTwo(amount) == 0 {
 			continue
 		}
 
-		var user models.User
-		database.DB.First(&user, friendID)
-
+		user := users[friendID]
 		friends = append(friends, models.FriendBalance{
 			UserID:    friendID,
 			Name:      user.Name,
@@ -111,43 +113,109 @@ func GetOverallBalances(c *gin.Context) {
 
 // Calculate net balance for each user in a group
 func calculateNetBalances(groupID uuid.UUID) map[uuid.UUID]float64 {
-	netBalance := make(map[uuid.UUID]float64)
+	return calculateGroupNetBalances([]uuid.UUID{groupID})[groupID]
+}
 
-	// Process expenses
-	var expenses []models.Expense
-	database.DB.Where("group_id = ?", groupID).Find(&expenses)
+// Calculate net balances for several groups at once, keyed by group then user.
+// Aggregation happens in SQL, so the query count doesn't grow with the number of groups or expenses.
+func calculateGroupNetBalances(groupIDs []uuid.UUID) map[uuid.UUID]map[uuid.UUID]float64 {
+	balances := make(map[uuid.UUID]map[uuid.UUID]float64, len(groupIDs))
+	for _, id := range groupIDs {
+		balances[id] = make(map[uuid.UUID]float64)
+	}
+	if len(groupIDs) == 0 {
+		return balances
+	}
 
-	for _, exp := range expenses {
-		var splits []models.ExpenseSplit
-		database.DB.Where("expense_id = ?", exp.ID).Find(&splits)
+	type row struct {
+		GroupID uuid.UUID
+		UserID  uuid.UUID
+		Amount  float64
+	}
 
-		for _, s := range splits {
-			if s.UserID == exp.PaidBy {
-				// Payer is owed: (what others owe for this expense) - (payer's own share)
-				// We handle this by: payer paid full amount, owes their share
-				netBalance[exp.PaidBy] += s.OwedAmount // gets cancelled below
-			}
-			// Everyone owes their share to the payer
-			netBalance[s.UserID] -= s.OwedAmount
-		}
-		// Payer paid the full amount
-		netBalance[exp.PaidBy] += exp.Amount
+	// Payers are owed the full amount of what they paid...
+	var paid []row
+	database.DB.Model(&models.Expense{}).
+		Select("group_id, paid_by AS user_id, SUM(amount) AS amount").
+		Where("group_id IN ?", groupIDs).
+		Group("group_id, paid_by").
+		Scan(&paid)
+
+	// ...and everyone, payer included, owes their own share
+	var owed []row
+	database.DB.Table("expense_splits").
+		Select("expenses.group_id, expense_splits.user_id, SUM(expense_splits.owed_amount) AS amount").
+		Joins("JOIN expenses ON expenses.id = expense_splits.expense_id").
+		Where("expenses.group_id IN ?", groupIDs).
+		Group("expenses.group_id, expense_splits.user_id").
+		Scan(&owed)
+
+	// Settlements move money from payer to payee
+	var settledOut, settledIn []row
+	database.DB.Model(&models.Settlement{}).
+		Select("group_id, paid_by AS user_id, SUM(amount) AS amount").
+		Where("group_id IN ?", groupIDs).
+		Group("group_id, paid_by").
+		Scan(&settledOut)
+	database.DB.Model(&models.Settlement{}).
+		Select("group_id, paid_to AS user_id, SUM(amount) AS amount").
+		Where("group_id IN ?", groupIDs).
+		Group("group_id, paid_to").
+		Scan(&settledIn)
+
+	for _, r := range paid {
+		balances[r.GroupID][r.UserID] += r.Amount
+	}
+	for _, r := range owed {
+		balances[r.GroupID][r.UserID] -= r.Amount
+	}
+	for _, r := range settledOut {
+		balances[r.GroupID][r.UserID] += r.Amount // paying off a debt raises your balance
+	}
+	for _, r := range settledIn {
+		balances[r.GroupID][r.UserID] -= r.Amount // receiving money lowers what you're owed
 	}
 
-	// Process settlements
-	var settlements []models.Settlement
-	database.DB.Where("group_id = ?", groupID).Find(&settlements)
+	return balances
+}
 
-	for _, s := range settlements {
-		netBalance[s.PaidBy] -= s.Amount // payer's balance decreases
-		netBalance[s.PaidTo] += s.Amount // payee's balance increases
+// Helper: true when no one in the group owes anything
+func isGroupSettled(groupID uuid.UUID) bool {
+	for _, amount := range calculateNetBalances(groupID) {
+		if !isSettledAmount(amount) {
+			return false
+		}
 	}
+	return true
+}
 
-	return netBalance
+// Helper: a balance counts as settled once it rounds to zero. Every check of "does anyone
+// still owe" goes through here so leaving, closing and the suggested transfers agree.
+func isSettledAmount(amount float64) bool {
+	rounded := utils.RoundToTwo(amount)
+	return rounded > -0.01 && rounded < 0.01
 }
 
-// Simplify debts using greedy algorithm
+// Simplify debts using greedy algorithm, with user names filled in
 func simplifyDebts(netBalance map[uuid.UUID]float64) []models.Balance {
+	results := settleDebts(netBalance)
+
+	// Get user names in one query
+	var userIDs []uuid.UUID
+	for _, b := range results {
+		userIDs = append(userIDs, b.From, b.To)
+	}
+	users := loadUsers(userIDs)
+	for i := range results {
+		results[i].FromName = users[results[i].From].Name
+		results[i].ToName = users[results[i].To].Name
+	}
+
+	return results
+}
+
+// Helper: greedy debt simplification without touching the database
+func settleDebts(netBalance map[uuid.UUID]float64) []models.Balance {
 	type userBalance struct {
 		UserID uuid.UUID
 		Amount float64
@@ -157,10 +225,12 @@ func simplifyDebts(netBalance map[uuid.UUID]float64) []models.Balance {
 	var debtors []userBalance   // people who owe money (negative balance)
 
 	for userID, amount := range netBalance {
-		rounded := utils.RoundToTwo(amount)
-		if rounded > 0.01 {
+		if isSettledAmount(amount) {
+			continue
+		}
+		if rounded := utils.RoundToTwo(amount); rounded > 0 {
 			creditors = append(creditors, userBalance{userID, rounded})
-		} else if rounded < -0.01 {
+		} else {
 			debtors = append(debtors, userBalance{userID, -rounded})
 		}
 	}
@@ -175,16 +245,9 @@ func simplifyDebts(netBalance map[uuid.UUID]float64) []models.Balance {
 		}
 		amount = utils.RoundToTwo(amount)
 
-		// Get user names
-		var fromUser, toUser models.User
-		database.DB.First(&fromUser, debtors[i].UserID)
-		database.DB.First(&toUser, creditors[j].UserID)
-
 		results = append(results, models.Balance{
 			From:     debtors[i].UserID,
-			FromName: fromUser.Name,
 			To:       creditors[j].UserID,
-			ToName:   toUser.Name,
 			Amount:   amount,
 			Currency: "INR",
 		})
@@ -192,10 +255,10 @@ func simplifyDebts(netBalance map[uuid.UUID]float64) []models.Balance {
 		debtors[i].Amount = utils.RoundToTwo(debtors[i].Amount - amount)
 		creditors[j].Amount = utils.RoundToTwo(creditors[j].Amount - amount)
 
-		if debtors[i].Amount < 0.01 {
+		if isSettledAmount(debtors[i].Amount) {
 			i++
 		}
-		if creditors[j].Amount < 0.01 {
+		if isSettledAmount(creditors[j].Amount) {
 			j++
 		}
 	}
@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"net/http"
+	"splitwise-backend/database"
+	"splitwise-backend/models"
+	"splitwise-backend/utils"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestNetBalancesExpenseAndSettlement(t *testing.T) {
+	requireDB(t)
+
+	alice := createTestUser(t, "Alice")
+	bob := createTestUser(t, "Bob")
+	group := createTestGroup(t, alice, bob)
+
+	// Alice pays 100 for dinner, split evenly: Bob owes her 50
+	expense := models.Expense{GroupID: group.ID, PaidBy: alice.ID, Description: "Dinner", Amount: 100, SplitType: "equal"}
+	if err := database.DB.Create(&expense).Error; err != nil {
+		t.Fatal(err)
+	}
+	splits := []models.ExpenseSplit{
+		{ExpenseID: expense.ID, UserID: alice.ID, OwedAmount: 50, PaidAmount: 100},
+		{ExpenseID: expense.ID, UserID: bob.ID, OwedAmount: 50},
+	}
+	if err := database.DB.Create(&splits).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	assertNetBalances(t, group.ID, map[uuid.UUID]float64{alice.ID: 50, bob.ID: -50})
+
+	// Bob pays back 20
+	settlement := models.Settlement{GroupID: group.ID, PaidBy: bob.ID, PaidTo: alice.ID, Amount: 20}
+	if err := database.DB.Create(&settlement).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	assertNetBalances(t, group.ID, map[uuid.UUID]float64{alice.ID: 30, bob.ID: -30})
+
+	// ...and the remaining 30: nobody owes anything
+	settlement = models.Settlement{GroupID: group.ID, PaidBy: bob.ID, PaidTo: alice.ID, Amount: 30}
+	if err := database.DB.Create(&settlement).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	assertNetBalances(t, group.ID, map[uuid.UUID]float64{alice.ID: 0, bob.ID: 0})
+	if debts := simplifyDebts(calculateNetBalances(group.ID)); len(debts) != 0 {
+		t.Errorf("settled group still has debts: %+v", debts)
+	}
+}
+
+func assertNetBalances(t *testing.T, groupID uuid.UUID, want map[uuid.UUID]float64) {
+	t.Helper()
+
+	got := calculateNetBalances(groupID)
+	for userID, amount := range want {
+		if utils.RoundToTwo(got[userID]) != amount {
+			t.Errorf("balance of %s = %.2f, want %.2f", userID, got[userID], amount)
+		}
+	}
+}
+
+// A single cent is still owed: the settled check and the suggested transfers must agree on that
+func TestSettleDebtsOneCent(t *testing.T) {
+	a, b := uuid.New(), uuid.New()
+	balances := map[uuid.UUID]float64{a: 0.01, b: -0.01}
+
+	if isSettledAmount(balances[a]) {
+		t.Error("0.01 counted as settled")
+	}
+	if !isSettledAmount(0.004) {
+		t.Error("0.004 counted as owed")
+	}
+	if debts := settleDebts(balances); len(debts) != 1 || debts[0].From != b || debts[0].Amount != 0.01 {
+		t.Errorf("debts = %+v, want %s paying 0.01", debts, b)
+	}
+}
+
+// A closing group takes no new expenses, and archives itself once deleting one settles it
+func TestClosingGroupArchivesAfterExpenseDelete(t *testing.T) {
+	requireDB(t)
+
+	alice := createTestUser(t, "Alice")
+	bob := createTestUser(t, "Bob")
+	group := createTestGroup(t, alice, bob)
+
+	expense := models.Expense{GroupID: group.ID, PaidBy: alice.ID, Description: "Taxi", Amount: 40, SplitType: "equal"}
+	if err := database.DB.Create(&expense).Error; err != nil {
+		t.Fatal(err)
+	}
+	splits := []models.ExpenseSplit{
+		{ExpenseID: expense.ID, UserID: alice.ID, OwedAmount: 20, PaidAmount: 40},
+		{ExpenseID: expense.ID, UserID: bob.ID, OwedAmount: 20},
+	}
+	if err := database.DB.Create(&splits).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	w := serveAs(alice, CloseGroup, http.MethodPost, "/api/groups/:id/close", "/api/groups/"+group.ID.String()+"/close", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("close: %d %s", w.Code, w.Body.String())
+	}
+
+	w = serveAs(alice, CreateExpense, http.MethodPost, "/api/groups/:id/expenses", "/api/groups/"+group.ID.String()+"/expenses",
+		models.CreateExpenseRequest{Description: "Snacks", Amount: 10})
+	if w.Code != http.StatusConflict {
+		t.Errorf("expense while closing: %d, want 409", w.Code)
+	}
+
+	w = serveAs(alice, DeleteExpense, http.MethodDelete, "/api/expenses/:id", "/api/expenses/"+expense.ID.String(), nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("delete: %d %s", w.Code, w.Body.String())
+	}
+
+	var reloaded models.Group
+	database.DB.First(&reloaded, group.ID)
+	if !reloaded.IsArchived() || reloaded.ClosingAt != nil {
+		t.Errorf("group after last debt removed: archived=%v closing_at=%v", reloaded.IsArchived(), reloaded.ClosingAt)
+	}
+}
+
+// A three-way split of 100 leaves a cent over; it must land on someone so the final
+// transfers clear every balance and the closing group archives
+func TestClosingGroupArchivesAfterThreeWaySharesSplit(t *testing.T) {
+	requireDB(t)
+
+	alice := createTestUser(t, "Alice")
+	bob := createTestUser(t, "Bob")
+	carol := createTestUser(t, "Carol")
+	group := createTestGroup(t, alice, bob, carol)
+	groupPath := "/api/groups/" + group.ID.String()
+
+	w := serveAs(alice, CreateExpense, http.MethodPost, "/api/groups/:id/expenses", groupPath+"/expenses", models.CreateExpenseRequest{
+		GroupID:     group.ID.String(),
+		Description: "Cabin",
+		Amount:      100,
+		SplitType:   "shares",
+		Splits: []models.SplitInput{
+			{UserID: alice.ID.String(), Value: 1},
+			{UserID: bob.ID.String(), Value: 1},
+			{UserID: carol.ID.String(), Value: 1},
+		},
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create expense: %d %s", w.Code, w.Body.String())
+	}
+
+	var owed []float64
+	database.DB.Model(&models.ExpenseSplit{}).
+		Where("expense_id IN (?)", database.DB.Model(&models.Expense{}).Select("id").Where("group_id = ?", group.ID)).
+		Pluck("owed_amount", &owed)
+	var total float64
+	for _, amount := range owed {
+		total += amount
+	}
+	if len(owed) != 3 || utils.RoundToTwo(total) != 100 {
+		t.Fatalf("owed amounts %v add up to %.2f, want 100 in three parts", owed, total)
+	}
+
+	w = serveAs(alice, CloseGroup, http.MethodPost, "/api/groups/:id/close", groupPath+"/close", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("close: %d %s", w.Code, w.Body.String())
+	}
+	var transfers []models.Balance
+	decodeData(t, w, &transfers)
+	if len(transfers) != 2 {
+		t.Fatalf("transfers = %+v, want Bob and Carol paying Alice", transfers)
+	}
+
+	users := map[uuid.UUID]models.User{bob.ID: bob, carol.ID: carol}
+	for _, transfer := range transfers {
+		w = serveAs(users[transfer.From], CreateSettlement, http.MethodPost, "/api/groups/:id/settle", groupPath+"/settle", models.CreateSettlementRequest{
+			GroupID: group.ID.String(),
+			PaidTo:  transfer.To.String(),
+			Amount:  transfer.Amount,
+		})
+		if w.Code != http.StatusCreated {
+			t.Fatalf("settle %+v: %d %s", transfer, w.Code, w.Body.String())
+		}
+	}
+
+	var reloaded models.Group
+	database.DB.First(&reloaded, group.ID)
+	if !reloaded.IsArchived() || reloaded.ClosingAt != nil {
+		t.Errorf("group after the final transfers: archived=%v closing_at=%v, balances %v",
+			reloaded.IsArchived(), reloaded.ClosingAt, calculateNetBalances(group.ID))
+	}
+}
@@ -0,0 +1,437 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"splitwise-backend/database"
+	"splitwise-backend/models"
+	"splitwise-backend/services"
+	"splitwise-backend/utils"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// commentTarget is the expense, settlement or comment a request is about
+type commentTarget struct {
+	Type    string
+	ID      uuid.UUID
+	GroupID uuid.UUID
+	Label   string // how activity and notifications refer to it
+}
+
+// GET /api/expenses/:id/comments, /api/settlements/:id/comments — threaded, oldest first
+func GetComments(targetType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := utils.GetCurrentUserID(c)
+		target, _, ok := loadCommentTarget(c, targetType, models.PermViewGroup)
+		if !ok {
+			return
+		}
+
+		utils.SuccessResponse(c, http.StatusOK, "", buildCommentThread(target, userID))
+	}
+}
+
+// POST /api/expenses/:id/comments, /api/settlements/:id/comments
+func CreateComment(targetType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := utils.GetCurrentUserID(c)
+		target, access, ok := loadCommentTarget(c, targetType, models.PermComment)
+		if !ok {
+			return
+		}
+
+		var req models.CreateCommentRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.BadRequest(c, err.Error())
+			return
+		}
+		body := strings.TrimSpace(req.Body)
+		if body == "" {
+			utils.BadRequest(c, "Comment cannot be empty")
+			return
+		}
+
+		comment := models.Comment{
+			GroupID:    target.GroupID,
+			TargetType: target.Type,
+			TargetID:   target.ID,
+			UserID:     userID,
+			Body:       body,
+			Mentions:   models.StringList{},
+		}
+
+		if req.ParentID != "" {
+			parentID, err := uuid.Parse(req.ParentID)
+			if err != nil {
+				utils.BadRequest(c, "Invalid parent comment ID")
+				return
+			}
+			var parent models.Comment
+			if err := database.DB.Where("id = ? AND target_type = ? AND target_id = ?", parentID, target.Type, target.ID).
+				First(&parent).Error; err != nil {
+				utils.BadRequest(c, "Parent comment not found on this "+target.Type)
+				return
+			}
+			comment.ParentID = &parent.ID
+		}
+
+		// Mentions must be active members of the group
+		var mentioned []models.User
+		if len(req.Mentions) > 0 {
+			seen := map[uuid.UUID]bool{}
+			var ids []uuid.UUID
+			for _, m := range req.Mentions {
+				uid, err := uuid.Parse(m)
+				if err != nil {
+					utils.BadRequest(c, "Invalid mentioned user ID: "+m)
+					return
+				}
+				if !seen[uid] {
+					seen[uid] = true
+					ids = append(ids, uid)
+				}
+			}
+
+			var count int64
+			database.DB.Model(&models.GroupMember{}).
+				Where("group_id = ? AND user_id IN ? AND status = ?", target.GroupID, ids, models.MemberActive).
+				Count(&count)
+			if int(count) != len(ids) {
+				utils.BadRequest(c, "You can only mention members of this group")
+				return
+			}
+
+			database.DB.Where("id IN ?", ids).Find(&mentioned)
+			for _, uid := range ids {
+				comment.Mentions = append(comment.Mentions, uid.String())
+			}
+		}
+
+		var author models.User
+		database.DB.First(&author, userID)
+
+		err := database.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&comment).Error; err != nil {
+				return err
+			}
+			err := tx.Create(&models.Activity{
+				GroupID:     target.GroupID,
+				UserID:      userID,
+				Type:        "comment_added",
+				ReferenceID: target.ID,
+				Description: fmt.Sprintf("%s commented on %s: \"%s\"", author.Name, target.Label, truncate(body, 80)),
+			}).Error
+			if err != nil {
+				return err
+			}
+
+			for _, user := range mentioned {
+				if user.ID == userID {
+					continue
+				}
+				if err := services.GetNotificationService().NotifyMentioned(tx, comment, author, user, access.Group, target.Label); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			utils.InternalError(c, "Failed to add comment")
+			return
+		}
+
+		comment.User = author
+		utils.SuccessResponse(c, http.StatusCreated, "Comment added", buildCommentResponse(comment, nil, userID))
+	}
+}
+
+// DELETE /api/expenses/:id/comments/:commentId — the author or an admin can delete
+func DeleteComment(targetType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := utils.GetCurrentUserID(c)
+		target, access, ok := loadCommentTarget(c, targetType, models.PermViewGroup)
+		if !ok {
+			return
+		}
+
+		commentID, err := uuid.Parse(c.Param("commentId"))
+		if err != nil {
+			utils.BadRequest(c, "Invalid comment ID")
+			return
+		}
+
+		var comment models.Comment
+		if err := database.DB.Where("id = ? AND target_type = ? AND target_id = ? AND deleted_at IS NULL", commentID, target.Type, target.ID).
+			First(&comment).Error; err != nil {
+			utils.NotFound(c, "Comment not found")
+			return
+		}
+
+		if comment.UserID != userID && !models.RoleAtLeast(access.Member.Role, models.RoleAdmin) {
+			utils.Forbidden(c, "Only the author or an admin can delete this comment")
+			return
+		}
+
+		var replies int64
+		database.DB.Model(&models.Comment{}).Where("parent_id = ?", comment.ID).Count(&replies)
+
+		err = database.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("target_type = ? AND target_id = ?", models.TargetComment, comment.ID).Delete(&models.Reaction{}).Error; err != nil {
+				return err
+			}
+			if replies > 0 {
+				// Keep a placeholder so the replies stay in their thread
+				return tx.Model(&comment).Updates(map[string]interface{}{
+					"body":       "",
+					"mentions":   models.StringList{},
+					"deleted_at": time.Now(),
+				}).Error
+			}
+			return tx.Delete(&comment).Error
+		})
+		if err != nil {
+			utils.InternalError(c, "Failed to delete comment")
+			return
+		}
+
+		utils.SuccessResponse(c, http.StatusOK, "Comment deleted", nil)
+	}
+}
+
+// GET /api/expenses/:id/reactions (also settlements and comments)
+func GetReactions(targetType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := utils.GetCurrentUserID(c)
+		target, _, ok := loadCommentTarget(c, targetType, models.PermViewGroup)
+		if !ok {
+			return
+		}
+
+		utils.SuccessResponse(c, http.StatusOK, "", reactionSummaryFor(target, userID))
+	}
+}
+
+// POST /api/expenses/:id/reactions (also settlements and comments) — adding the same emoji twice is a no-op
+func AddReaction(targetType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := utils.GetCurrentUserID(c)
+		target, _, ok := loadCommentTarget(c, targetType, models.PermComment)
+		if !ok {
+			return
+		}
+
+		var req models.ReactionRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.BadRequest(c, err.Error())
+			return
+		}
+		emoji := strings.TrimSpace(req.Emoji)
+		if !isEmoji(emoji) {
+			utils.BadRequest(c, "Reaction must be a single emoji")
+			return
+		}
+
+		reaction := models.Reaction{
+			TargetType: target.Type,
+			TargetID:   target.ID,
+			UserID:     userID,
+			Emoji:      emoji,
+			GroupID:    target.GroupID,
+		}
+		if err := database.DB.Where(reaction).FirstOrCreate(&reaction).Error; err != nil {
+			utils.InternalError(c, "Failed to add reaction")
+			return
+		}
+
+		utils.SuccessResponse(c, http.StatusOK, "", reactionSummaryFor(target, userID))
+	}
+}
+
+// DELETE /api/expenses/:id/reactions?emoji=👍 (also settlements and comments)
+func RemoveReaction(targetType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := utils.GetCurrentUserID(c)
+		target, _, ok := loadCommentTarget(c, targetType, models.PermViewGroup)
+		if !ok {
+			return
+		}
+
+		emoji := strings.TrimSpace(c.Query("emoji"))
+		if emoji == "" {
+			utils.BadRequest(c, "emoji is required")
+			return
+		}
+
+		database.DB.Where("target_type = ? AND target_id = ? AND user_id = ? AND emoji = ?", target.Type, target.ID, userID, emoji).
+			Delete(&models.Reaction{})
+
+		utils.SuccessResponse(c, http.StatusOK, "", reactionSummaryFor(target, userID))
+	}
+}
+
+// Helper: resolve :id for the target type and check perm in its group
+func loadCommentTarget(c *gin.Context, targetType string, perm models.Permission) (*commentTarget, *groupAccess, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid "+targetType+" ID")
+		return nil, nil, false
+	}
+
+	target := &commentTarget{Type: targetType, ID: id}
+	switch targetType {
+	case models.TargetExpense:
+		var expense models.Expense
+		if err := database.DB.First(&expense, id).Error; err != nil {
+			utils.NotFound(c, "Expense not found")
+			return nil, nil, false
+		}
+		target.GroupID = expense.GroupID
+		target.Label = fmt.Sprintf("\"%s\"", expense.Description)
+	case models.TargetSettlement:
+		var settlement models.Settlement
+		if err := database.DB.First(&settlement, id).Error; err != nil {
+			utils.NotFound(c, "Settlement not found")
+			return nil, nil, false
+		}
+		target.GroupID = settlement.GroupID
+		target.Label = fmt.Sprintf("a payment of %.2f", settlement.Amount)
+	case models.TargetComment:
+		var comment models.Comment
+		if err := database.DB.Where("id = ? AND deleted_at IS NULL", id).First(&comment).Error; err != nil {
+			utils.NotFound(c, "Comment not found")
+			return nil, nil, false
+		}
+		target.GroupID = comment.GroupID
+		target.Label = "a comment"
+	}
+
+	access, ok := authorize(c, target.GroupID, perm)
+	if !ok {
+		return nil, nil, false
+	}
+	return target, access, true
+}
+
+// Helper: all comments on a target arranged as a tree
+func buildCommentThread(target *commentTarget, currentUserID uuid.UUID) []*models.CommentResponse {
+	var comments []models.Comment
+	database.DB.Preload("User").
+		Where("target_type = ? AND target_id = ?", target.Type, target.ID).
+		Order("created_at ASC").
+		Find(&comments)
+
+	ids := make([]uuid.UUID, len(comments))
+	for i, cm := range comments {
+		ids[i] = cm.ID
+	}
+
+	reactionsByComment := map[uuid.UUID][]models.Reaction{}
+	if len(ids) > 0 {
+		var reactions []models.Reaction
+		database.DB.Where("target_type = ? AND target_id IN ?", models.TargetComment, ids).Order("created_at ASC").Find(&reactions)
+		for _, r := range reactions {
+			reactionsByComment[r.TargetID] = append(reactionsByComment[r.TargetID], r)
+		}
+	}
+
+	byID := map[uuid.UUID]*models.CommentResponse{}
+	roots := []*models.CommentResponse{}
+	for _, cm := range comments {
+		resp := buildCommentResponse(cm, reactionsByComment[cm.ID], currentUserID)
+		byID[cm.ID] = resp
+
+		if cm.ParentID != nil {
+			if parent, ok := byID[*cm.ParentID]; ok {
+				parent.Replies = append(parent.Replies, resp)
+				continue
+			}
+		}
+		roots = append(roots, resp)
+	}
+	return roots
+}
+
+func buildCommentResponse(cm models.Comment, reactions []models.Reaction, currentUserID uuid.UUID) *models.CommentResponse {
+	resp := &models.CommentResponse{
+		ID:        cm.ID,
+		ParentID:  cm.ParentID,
+		UserID:    cm.UserID,
+		UserName:  cm.User.Name,
+		AvatarURL: cm.User.AvatarURL,
+		Body:      cm.Body,
+		Mentions:  cm.Mentions,
+		Deleted:   cm.DeletedAt != nil,
+		Reactions: summarizeReactions(reactions, currentUserID),
+		Replies:   []*models.CommentResponse{},
+		CreatedAt: cm.CreatedAt,
+	}
+	if resp.Mentions == nil {
+		resp.Mentions = []string{}
+	}
+	return resp
+}
+
+func reactionSummaryFor(target *commentTarget, currentUserID uuid.UUID) []models.ReactionSummary {
+	var reactions []models.Reaction
+	database.DB.Where("target_type = ? AND target_id = ?", target.Type, target.ID).Order("created_at ASC").Find(&reactions)
+	return summarizeReactions(reactions, currentUserID)
+}
+
+// Helper: group reactions by emoji, in the order each emoji was first used
+func summarizeReactions(reactions []models.Reaction, currentUserID uuid.UUID) []models.ReactionSummary {
+	summaries := []models.ReactionSummary{}
+	index := map[string]int{}
+	for _, r := range reactions {
+		i, ok := index[r.Emoji]
+		if !ok {
+			i = len(summaries)
+			index[r.Emoji] = i
+			summaries = append(summaries, models.ReactionSummary{Emoji: r.Emoji, UserIDs: []uuid.UUID{}})
+		}
+		summaries[i].Count++
+		summaries[i].UserIDs = append(summaries[i].UserIDs, r.UserID)
+		if r.UserID == currentUserID {
+			summaries[i].Reacted = true
+		}
+	}
+	return summaries
+}
+
+// Helper: remove comments and reactions attached to an expense or settlement
+func deleteDiscussion(tx *gorm.DB, targetType string, targetID uuid.UUID) error {
+	commentIDs := tx.Model(&models.Comment{}).Select("id").Where("target_type = ? AND target_id = ?", targetType, targetID)
+	if err := tx.Where("target_type = ? AND target_id IN (?)", models.TargetComment, commentIDs).Delete(&models.Reaction{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("target_type = ? AND target_id = ?", targetType, targetID).Delete(&models.Comment{}).Error; err != nil {
+		return err
+	}
+	return tx.Where("target_type = ? AND target_id = ?", targetType, targetID).Delete(&models.Reaction{}).Error
+}
+
+// Helper: a reaction is a short run of emoji, no plain text
+func isEmoji(s string) bool {
+	if s == "" || len(s) > 32 || utf8.RuneCountInString(s) > 8 {
+		return false
+	}
+	for _, r := range s {
+		if r < 0x80 || unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsSpace(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n]) + "…"
+}
@@ -0,0 +1,276 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"splitwise-backend/database"
+	"splitwise-backend/models"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func createTestExpense(t *testing.T, group models.Group, payer models.User, description string) models.Expense {
+	t.Helper()
+
+	expense := models.Expense{GroupID: group.ID, PaidBy: payer.ID, Description: description, Amount: 30, SplitType: "equal"}
+	if err := database.DB.Create(&expense).Error; err != nil {
+		t.Fatalf("create expense: %v", err)
+	}
+	return expense
+}
+
+func postComment(t *testing.T, user models.User, expense models.Expense, req models.CreateCommentRequest) (*models.CommentResponse, int) {
+	t.Helper()
+
+	w := serveAs(user, CreateComment(models.TargetExpense), http.MethodPost, "/api/expenses/:id/comments",
+		"/api/expenses/"+expense.ID.String()+"/comments", req)
+	if w.Code != http.StatusCreated {
+		return nil, w.Code
+	}
+	var comment models.CommentResponse
+	decodeData(t, w, &comment)
+	return &comment, w.Code
+}
+
+func mustComment(t *testing.T, user models.User, expense models.Expense, req models.CreateCommentRequest) *models.CommentResponse {
+	t.Helper()
+
+	comment, code := postComment(t, user, expense, req)
+	if comment == nil {
+		t.Fatalf("comment %q: %d, want 201", req.Body, code)
+	}
+	return comment
+}
+
+func deleteComment(user models.User, expense models.Expense, commentID uuid.UUID) int {
+	path := "/api/expenses/" + expense.ID.String() + "/comments/" + commentID.String()
+	return serveAs(user, DeleteComment(models.TargetExpense), http.MethodDelete, "/api/expenses/:id/comments/:commentId", path, nil).Code
+}
+
+func commentThread(t *testing.T, user models.User, expense models.Expense) []*models.CommentResponse {
+	t.Helper()
+
+	w := serveAs(user, GetComments(models.TargetExpense), http.MethodGet, "/api/expenses/:id/comments", "/api/expenses/"+expense.ID.String()+"/comments", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("get comments: %d %s", w.Code, w.Body.String())
+	}
+	var thread []*models.CommentResponse
+	decodeData(t, w, &thread)
+	return thread
+}
+
+func TestCommentMentions(t *testing.T) {
+	requireDB(t)
+
+	alice, bob, carol, outsider := createTestUser(t, "Alice"), createTestUser(t, "Bob"), createTestUser(t, "Carol"), createTestUser(t, "Dave")
+	group := createTestGroup(t, alice, bob, carol)
+	expense := createTestExpense(t, group, alice, "Groceries")
+	database.DB.Model(&models.GroupMember{}).Where("group_id = ? AND user_id = ?", group.ID, carol.ID).Update("status", models.MemberFormer)
+
+	comment := mustComment(t, alice, expense, models.CreateCommentRequest{
+		Body:     "@Bob can you check the receipt?",
+		Mentions: []string{bob.ID.String(), bob.ID.String()},
+	})
+	if len(comment.Mentions) != 1 || comment.Mentions[0] != bob.ID.String() {
+		t.Errorf("mentions = %v, want Bob once", comment.Mentions)
+	}
+
+	tests := []struct {
+		name    string
+		mention string
+	}{
+		{"former member", carol.ID.String()},
+		{"not in the group", outsider.ID.String()},
+		{"no such user", uuid.NewString()},
+		{"not an ID", "bob"},
+	}
+	for _, tt := range tests {
+		if _, code := postComment(t, alice, expense, models.CreateCommentRequest{Body: "hey", Mentions: []string{bob.ID.String(), tt.mention}}); code != http.StatusBadRequest {
+			t.Errorf("mentioning %s: %d, want 400", tt.name, code)
+		}
+	}
+	if thread := commentThread(t, alice, expense); len(thread) != 1 {
+		t.Errorf("%d comments, want the rejected ones not stored", len(thread))
+	}
+}
+
+func TestCommentReplies(t *testing.T) {
+	requireDB(t)
+
+	alice, bob := createTestUser(t, "Alice"), createTestUser(t, "Bob")
+	group := createTestGroup(t, alice, bob)
+	dinner := createTestExpense(t, group, alice, "Dinner")
+	taxi := createTestExpense(t, group, alice, "Taxi")
+
+	parent := mustComment(t, alice, dinner, models.CreateCommentRequest{Body: "Who had dessert?"})
+
+	if _, code := postComment(t, bob, taxi, models.CreateCommentRequest{Body: "Me", ParentID: parent.ID.String()}); code != http.StatusBadRequest {
+		t.Errorf("reply on another expense: %d, want 400", code)
+	}
+	if _, code := postComment(t, bob, dinner, models.CreateCommentRequest{Body: "Me", ParentID: uuid.NewString()}); code != http.StatusBadRequest {
+		t.Errorf("reply to a missing comment: %d, want 400", code)
+	}
+
+	reply := mustComment(t, bob, dinner, models.CreateCommentRequest{Body: "Me", ParentID: parent.ID.String()})
+	thread := commentThread(t, bob, dinner)
+	if len(thread) != 1 || len(thread[0].Replies) != 1 || thread[0].Replies[0].ID != reply.ID {
+		t.Errorf("thread = %+v, want the reply under its parent", thread)
+	}
+	if len(commentThread(t, bob, taxi)) != 0 {
+		t.Error("the rejected reply shows up on the other expense")
+	}
+}
+
+func TestDeleteComment(t *testing.T) {
+	requireDB(t)
+
+	t.Run("placeholder when there are replies, gone otherwise", func(t *testing.T) {
+		alice, bob := createTestUser(t, "Alice"), createTestUser(t, "Bob")
+		group := createTestGroup(t, alice, bob)
+		expense := createTestExpense(t, group, alice, "Dinner")
+
+		parent := mustComment(t, bob, expense, models.CreateCommentRequest{Body: "Split the wine separately?"})
+		reply := mustComment(t, alice, expense, models.CreateCommentRequest{Body: "Sure", ParentID: parent.ID.String()})
+		lonely := mustComment(t, bob, expense, models.CreateCommentRequest{Body: "Typo"})
+
+		if code := deleteComment(bob, expense, parent.ID); code != http.StatusOK {
+			t.Fatalf("delete parent: %d, want 200", code)
+		}
+		if code := deleteComment(bob, expense, lonely.ID); code != http.StatusOK {
+			t.Fatalf("delete comment without replies: %d, want 200", code)
+		}
+
+		thread := commentThread(t, alice, expense)
+		if len(thread) != 1 {
+			t.Fatalf("%d top-level comments, want only the placeholder", len(thread))
+		}
+		placeholder := thread[0]
+		if placeholder.ID != parent.ID || !placeholder.Deleted || placeholder.Body != "" {
+			t.Errorf("placeholder = %+v, want the parent emptied and marked deleted", placeholder)
+		}
+		if len(placeholder.Replies) != 1 || placeholder.Replies[0].ID != reply.ID || placeholder.Replies[0].Body != "Sure" {
+			t.Errorf("replies = %+v, want the reply kept", placeholder.Replies)
+		}
+
+		var left int64
+		database.DB.Model(&models.Comment{}).Where("id = ?", lonely.ID).Count(&left)
+		if left != 0 {
+			t.Error("comment without replies was kept")
+		}
+		if code := deleteComment(bob, expense, parent.ID); code != http.StatusNotFound {
+			t.Errorf("deleting the placeholder again: %d, want 404", code)
+		}
+	})
+
+	t.Run("author or admin only", func(t *testing.T) {
+		admin, bob, carol := createTestUser(t, "Alice"), createTestUser(t, "Bob"), createTestUser(t, "Carol")
+		group := createTestGroup(t, admin, bob, carol)
+		expense := createTestExpense(t, group, admin, "Dinner")
+
+		byBob := mustComment(t, bob, expense, models.CreateCommentRequest{Body: "I paid the tip"})
+		byCarol := mustComment(t, carol, expense, models.CreateCommentRequest{Body: "Thanks"})
+
+		if code := deleteComment(carol, expense, byBob.ID); code != http.StatusForbidden {
+			t.Errorf("member deleting someone else's comment: %d, want 403", code)
+		}
+		if code := deleteComment(bob, expense, byBob.ID); code != http.StatusOK {
+			t.Errorf("author deleting: %d, want 200", code)
+		}
+		if code := deleteComment(admin, expense, byCarol.ID); code != http.StatusOK {
+			t.Errorf("admin deleting: %d, want 200", code)
+		}
+		if thread := commentThread(t, admin, expense); len(thread) != 0 {
+			t.Errorf("%d comments left, want none", len(thread))
+		}
+	})
+}
+
+func TestReactions(t *testing.T) {
+	requireDB(t)
+
+	alice, bob, outsider := createTestUser(t, "Alice"), createTestUser(t, "Bob"), createTestUser(t, "Carol")
+	group := createTestGroup(t, alice, bob)
+	expense := createTestExpense(t, group, alice, "Dinner")
+	route, path := "/api/expenses/:id/reactions", "/api/expenses/"+expense.ID.String()+"/reactions"
+
+	react := func(user models.User, emoji string) ([]models.ReactionSummary, int) {
+		w := serveAs(user, AddReaction(models.TargetExpense), http.MethodPost, route, path, models.ReactionRequest{Emoji: emoji})
+		if w.Code != http.StatusOK {
+			return nil, w.Code
+		}
+		var summary []models.ReactionSummary
+		decodeData(t, w, &summary)
+		return summary, w.Code
+	}
+
+	react(alice, "👍")
+	summary, _ := react(alice, "👍")
+	if len(summary) != 1 || summary[0].Count != 1 || !summary[0].Reacted {
+		t.Errorf("after reacting twice: %+v, want one 👍 by Alice", summary)
+	}
+
+	summary, _ = react(bob, "👍")
+	react(bob, "🎉")
+	if len(summary) != 1 || summary[0].Count != 2 {
+		t.Errorf("after Bob's 👍: %+v, want a count of 2", summary)
+	}
+
+	for _, emoji := range []string{"ok", "👍 nice", ""} {
+		if _, code := react(alice, emoji); code != http.StatusBadRequest {
+			t.Errorf("reaction %q: %d, want 400", emoji, code)
+		}
+	}
+	if _, code := react(outsider, "👍"); code != http.StatusUnauthorized {
+		t.Errorf("reaction from outside the group: %d, want 401", code)
+	}
+
+	w := serveAs(bob, RemoveReaction(models.TargetExpense), http.MethodDelete, route, path+"?emoji="+url.QueryEscape("👍"), nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("remove reaction: %d %s", w.Code, w.Body.String())
+	}
+	decodeData(t, w, &summary)
+	if len(summary) != 2 || summary[0].Emoji != "👍" || summary[0].Count != 1 || summary[0].Reacted || summary[1].Emoji != "🎉" {
+		t.Errorf("after Bob removed 👍: %+v, want Alice's 👍 then Bob's 🎉", summary)
+	}
+
+	// Reactions on a comment go with it when it is deleted
+	comment := mustComment(t, alice, expense, models.CreateCommentRequest{Body: "Paid in cash"})
+	commentPath := "/api/comments/" + comment.ID.String() + "/reactions"
+	if w := serveAs(bob, AddReaction(models.TargetComment), http.MethodPost, "/api/comments/:id/reactions", commentPath, models.ReactionRequest{Emoji: "🙏"}); w.Code != http.StatusOK {
+		t.Fatalf("react to comment: %d %s", w.Code, w.Body.String())
+	}
+	if thread := commentThread(t, alice, expense); len(thread) != 1 || len(thread[0].Reactions) != 1 || thread[0].Reactions[0].Emoji != "🙏" {
+		t.Errorf("thread = %+v, want the comment's 🙏", thread)
+	}
+	if code := deleteComment(alice, expense, comment.ID); code != http.StatusOK {
+		t.Fatalf("delete comment: %d", code)
+	}
+	var left int64
+	database.DB.Model(&models.Reaction{}).Where("target_type = ? AND target_id = ?", models.TargetComment, comment.ID).Count(&left)
+	if left != 0 {
+		t.Errorf("%d reactions left on the deleted comment", left)
+	}
+}
+
+func TestIsEmoji(t *testing.T) {
+	tests := []struct {
+		in   string
+		want bool
+	}{
+		{"👍", true},
+		{"👍🏽", true},
+		{"❤️", true},
+		{"👨‍👩‍👧", true},
+		{"", false},
+		{"ok", false},
+		{"👍 ", false},
+		{"1️⃣", false},
+		{"🎉🎉🎉🎉🎉🎉🎉🎉🎉", false},
+	}
+	for _, tt := range tests {
+		if got := isEmoji(tt.in); got != tt.want {
+			t.Errorf("isEmoji(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"net/http"
+	"splitwise-backend/email"
+	"splitwise-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Only registered when EMAIL_TRANSPORT is file or memory, i.e. never in production
+
+// GET /dev/emails — emails caught by the sink, newest first
+func GetCapturedEmails(inbox email.Inbox) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		emails := inbox.Emails()
+		if to := c.Query("to"); to != "" {
+			filtered := []email.Captured{}
+			for _, e := range emails {
+				if e.To == to {
+					filtered = append(filtered, e)
+				}
+			}
+			emails = filtered
+		}
+		utils.SuccessResponse(c, http.StatusOK, "", emails)
+	}
+}
+
+// GET /dev/emails/:id — one email as the recipient would see it; ?format=text|raw|json
+func GetCapturedEmail(inbox email.Inbox) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		captured, ok := inbox.Email(c.Param("id"))
+		if !ok {
+			utils.NotFound(c, "Email not found")
+			return
+		}
+
+		switch c.DefaultQuery("format", "html") {
+		case "html":
+			c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(captured.HTML))
+		case "text":
+			c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(captured.Text))
+		case "raw":
+			c.Data(http.StatusOK, "message/rfc822", captured.Raw)
+		case "json":
+			utils.SuccessResponse(c, http.StatusOK, "", captured)
+		default:
+			utils.BadRequest(c, "format must be html, text, raw or json")
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"net/http"
+	"splitwise-backend/database"
+	"splitwise-backend/models"
+	"splitwise-backend/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm/clause"
+)
+
+// POST /api/users/me/devices — register this device for push notifications; call on every app start
+func RegisterDevice(c *gin.Context) {
+	userID := utils.GetCurrentUserID(c)
+
+	var req models.RegisterDeviceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	device, err := registerDevice(userID, req)
+	if err != nil {
+		utils.InternalError(c, "Failed to register device")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Device registered", device)
+}
+
+// GET /api/users/me/devices
+func GetDevices(c *gin.Context) {
+	userID := utils.GetCurrentUserID(c)
+
+	devices := []models.DeviceToken{}
+	database.DB.Where("user_id = ?", userID).Order("last_seen_at DESC").Find(&devices)
+	utils.SuccessResponse(c, http.StatusOK, "", devices)
+}
+
+// DELETE /api/users/me/devices/:id — e.g. on logout, so the device stops getting pushes
+func RemoveDevice(c *gin.Context) {
+	userID := utils.GetCurrentUserID(c)
+
+	deviceID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid device ID")
+		return
+	}
+
+	result := database.DB.Where("id = ? AND user_id = ?", deviceID, userID).Delete(&models.DeviceToken{})
+	if result.RowsAffected == 0 {
+		utils.NotFound(c, "Device not found")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Device removed", nil)
+}
+
+// Helper: a token belongs to whoever registered it last, so a shared or
+// handed-down phone stops getting the previous user's notifications
+func registerDevice(userID uuid.UUID, req models.RegisterDeviceRequest) (models.DeviceToken, error) {
+	now := time.Now()
+	device := models.DeviceToken{
+		UserID:     userID,
+		Token:      req.Token,
+		Platform:   req.Platform,
+		LastSeenAt: now,
+	}
+
+	columns := []string{"user_id", "last_seen_at", "updated_at"}
+	if req.Platform != "" {
+		columns = append(columns, "platform") // the legacy fcm-token endpoint doesn't send it
+	}
+	err := database.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "token"}},
+		DoUpdates: clause.AssignmentColumns(columns),
+	}).Create(&device).Error
+	if err != nil {
+		return device, err
+	}
+
+	err = database.DB.Where("token = ?", req.Token).First(&device).Error
+	return device, err
+}
@@ -7,10 +7,12 @@ import (
 	"splitwise-backend/models"
 	"splitwise-backend/services"
 	"splitwise-backend/utils"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 // POST /api/groups/:id/expenses
@@ -22,8 +24,14 @@ func CreateExpense(c *gin.Context) {
 		return
 	}
 
-	if !isMember(groupID, userID) {
-		utils.Unauthorized(c, "You are not a member of this group")
+	access, ok := authorize(c, groupID, models.PermAddExpense)
+	if !ok {
+		return
+	}
+
+	// New expenses would change the final transfers everyone was just told about
+	if access.Group.ClosingAt != nil {
+		utils.Conflict(c, "This group is closing. Settle up, or unarchive it to add more expenses", nil)
 		return
 	}
 
@@ -33,6 +41,25 @@ func CreateExpense(c *gin.Context) {
 		return
 	}
 
+	clientID, ok := parseClientID(c, req.ID)
+	if !ok {
+		return
+	}
+	if clientID != uuid.Nil {
+		var existing models.Expense
+		if database.DB.First(&existing, clientID).Error == nil {
+			if existing.GroupID != groupID || existing.PaidBy != userID {
+				utils.Conflict(c, "This expense ID is already in use", nil)
+				return
+			}
+			// A retried offline create: answer with what the first attempt made
+			response := buildExpenseResponse(existing.ID)
+			utils.SetETag(c, response.Version)
+			utils.SuccessResponse(c, http.StatusOK, "Expense already exists", response)
+			return
+		}
+	}
+
 	// Parse expense date
 	expenseDate := time.Now()
 	if req.ExpenseDate != "" {
@@ -42,94 +69,285 @@ func CreateExpense(c *gin.Context) {
 		}
 	}
 
-	currency := req.Currency
+	// Fall back to the group's settings for anything the request leaves out
+	settings := access.Group.Settings
+
+	currency := strings.ToUpper(req.Currency)
+	if currency == "" {
+		currency = settings.DefaultCurrency
+	}
 	if currency == "" {
 		currency = "INR"
 	}
 
+	if !settings.AllowsCategory(req.Category) {
+		utils.BadRequest(c, fmt.Sprintf("Category \"%s\" is not allowed in this group", req.Category))
+		return
+	}
+
+	splitType := req.SplitType
+	splitInputs := req.Splits
+	if splitType == "" {
+		splitType = settings.DefaultSplitType
+	}
+	if splitType == "" {
+		splitType = "equal"
+	}
+	if len(splitInputs) == 0 && splitType == settings.DefaultSplitType {
+		splitInputs = settings.DefaultSplits
+	}
+
 	expense := models.Expense{
+		ID:          clientID,
 		GroupID:     groupID,
 		PaidBy:      userID,
 		Description: req.Description,
 		Amount:      req.Amount,
 		Currency:    currency,
 		Category:    req.Category,
-		SplitType:   req.SplitType,
+		SplitType:   splitType,
 		Notes:       req.Notes,
 		ExpenseDate: expenseDate,
 	}
 
-	if err := database.DB.Create(&expense).Error; err != nil {
-		utils.InternalError(c, "Failed to create expense")
-		return
-	}
-
-	// Calculate and create splits
-	splits, err := calculateSplits(expense, req.Splits, groupID)
+	// Calculate splits
+	splits, err := calculateSplits(expense, splitInputs, req.Participants, groupID)
 	if err != nil {
-		// Rollback expense
-		database.DB.Delete(&expense)
 		utils.BadRequest(c, err.Error())
 		return
 	}
 
-	for _, split := range splits {
-		split.ExpenseID = expense.ID
-		database.DB.Create(&split)
-	}
-
-	// Log activity
 	var payer models.User
 	database.DB.First(&payer, userID)
-	var group models.Group
-	database.DB.First(&group, groupID)
+	group := access.Group
 
-	database.DB.Create(&models.Activity{
-		GroupID:     groupID,
-		UserID:      userID,
-		Type:        "expense_added",
-		ReferenceID: expense.ID,
-		Description: fmt.Sprintf("%s added \"%s\" (%s %.2f)", payer.Name, expense.Description, expense.Currency, expense.Amount),
-	})
+	// The expense, its splits, the activity and the notifications are saved together
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&expense).Error; err != nil {
+			return err
+		}
+
+		for i := range splits {
+			splits[i].ExpenseID = expense.ID
+		}
+		if len(splits) > 0 {
+			if err := tx.Create(&splits).Error; err != nil {
+				return err
+			}
+		}
 
-	// Send notifications asynchronously
-	go services.GetNotificationService().NotifyExpenseAdded(expense, splits, payer, group)
+		// Log activity
+		err := tx.Create(&models.Activity{
+			GroupID:     groupID,
+			UserID:      userID,
+			Type:        "expense_added",
+			ReferenceID: expense.ID,
+			Description: fmt.Sprintf("%s added \"%s\" (%s %.2f)", payer.Name, expense.Description, expense.Currency, expense.Amount),
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		return services.GetNotificationService().NotifyExpenseAdded(tx, expense, splits, payer, group)
+	})
+	if err != nil {
+		utils.InternalError(c, "Failed to create expense")
+		return
+	}
 
 	// Build response
 	response := buildExpenseResponse(expense.ID)
+	utils.SetETag(c, response.Version)
 	utils.SuccessResponse(c, http.StatusCreated, "Expense added", response)
 }
 
-// GET /api/groups/:id/expenses
+// GET /api/groups/:id/expenses — accepts the ExpenseFilter query parameters
 func GetGroupExpenses(c *gin.Context) {
-	userID := utils.GetCurrentUserID(c)
 	groupID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
 		utils.BadRequest(c, "Invalid group ID")
 		return
 	}
 
-	if !isMember(groupID, userID) {
-		utils.Unauthorized(c, "You are not a member of this group")
+	if _, ok := authorize(c, groupID, models.PermViewGroup); !ok {
+		return
+	}
+
+	listExpenses(c, func(db *gorm.DB) *gorm.DB {
+		return db.Where("expenses.group_id = ?", groupID)
+	})
+}
+
+// GET /api/expenses — search expenses across all of the current user's groups
+func SearchExpenses(c *gin.Context) {
+	userID := utils.GetCurrentUserID(c)
+
+	listExpenses(c, func(db *gorm.DB) *gorm.DB {
+		return db.Where("expenses.group_id IN (?)",
+			database.DB.Model(&models.GroupMember{}).Select("group_id").Where("user_id = ?", userID))
+	})
+}
+
+// Helper: filter, paginate and total the expenses visible through scope
+func listExpenses(c *gin.Context, scope func(*gorm.DB) *gorm.DB) {
+	userID := utils.GetCurrentUserID(c)
+
+	var filter models.ExpenseFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	filterScope, err := expenseFilterScope(filter, userID)
+	if err != nil {
+		utils.BadRequest(c, err.Error())
 		return
 	}
 
-	var pagination utils.PaginationQuery
-	c.ShouldBindQuery(&pagination)
+	pagination, err := utils.BindPagination(c)
+	if err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
 
 	var expenses []models.Expense
-	database.DB.Where("group_id = ?", groupID).
-		Order("expense_date DESC, created_at DESC").
-		Offset(pagination.Offset()).
-		Limit(pagination.Limit).
+	database.DB.Model(&models.Expense{}).
+		Scopes(scope, filterScope, pagination.Scope(expenseKeyset)).
+		Order("expenses.expense_date DESC, expenses.created_at DESC, expenses.id DESC").
 		Find(&expenses)
 
-	var responses []models.ExpenseResponse
-	for _, e := range expenses {
-		responses = append(responses, buildExpenseResponse(e.ID))
+	fetched := len(expenses)
+	if fetched > pagination.Limit {
+		expenses = expenses[:pagination.Limit]
+	}
+
+	responses := buildExpenseResponses(expenses)
+	var last utils.Cursor
+	if len(expenses) > 0 {
+		e := expenses[len(expenses)-1]
+		last = utils.Cursor{Date: e.ExpenseDate.Format("2006-01-02"), CreatedAt: e.CreatedAt, ID: e.ID}
 	}
 
-	utils.SuccessResponse(c, http.StatusOK, "", responses)
+	totals := expenseTotals(userID, scope, filterScope)
+	utils.SuccessResponseWithMeta(c, http.StatusOK, "", responses, models.ExpenseListMeta{
+		PageMeta: pagination.Meta(totals.Count, fetched, last),
+		Totals:   totals,
+	})
+}
+
+// Helper: rows after the cursor, for lists ordered by expense_date, created_at, id (all DESC)
+func expenseKeyset(db *gorm.DB, after utils.Cursor) *gorm.DB {
+	return db.Where("(expenses.expense_date, expenses.created_at, expenses.id) < (?, ?, ?)", after.Date, after.CreatedAt, after.ID)
+}
+
+// Helper: turn an ExpenseFilter into query conditions, validating it first
+func expenseFilterScope(f models.ExpenseFilter, userID uuid.UUID) (func(*gorm.DB) *gorm.DB, error) {
+	var paidBy, participant uuid.UUID
+	var groupID uuid.UUID
+	var from, to time.Time
+	var err error
+
+	if f.PaidBy != "" {
+		if paidBy, err = uuid.Parse(f.PaidBy); err != nil {
+			return nil, fmt.Errorf("invalid paid_by user ID")
+		}
+	}
+	if f.Participant != "" {
+		if participant, err = uuid.Parse(f.Participant); err != nil {
+			return nil, fmt.Errorf("invalid participant user ID")
+		}
+	}
+	if f.GroupID != "" {
+		if groupID, err = uuid.Parse(f.GroupID); err != nil {
+			return nil, fmt.Errorf("invalid group_id")
+		}
+	}
+	if f.From != "" {
+		if from, err = time.Parse("2006-01-02", f.From); err != nil {
+			return nil, fmt.Errorf("from must be a date in YYYY-MM-DD format")
+		}
+	}
+	if f.To != "" {
+		if to, err = time.Parse("2006-01-02", f.To); err != nil {
+			return nil, fmt.Errorf("to must be a date in YYYY-MM-DD format")
+		}
+	}
+	if f.MinAmount < 0 || f.MaxAmount < 0 {
+		return nil, fmt.Errorf("amounts cannot be negative")
+	}
+	if f.MaxAmount > 0 && f.MinAmount > f.MaxAmount {
+		return nil, fmt.Errorf("min_amount cannot be greater than max_amount")
+	}
+
+	hasShare := "EXISTS (SELECT 1 FROM expense_splits es WHERE es.expense_id = expenses.id AND es.user_id = ?)"
+
+	return func(db *gorm.DB) *gorm.DB {
+		if q := strings.TrimSpace(f.Query); q != "" {
+			db = db.Where(expenseSearchVector+" @@ websearch_to_tsquery('simple', ?)", q)
+		}
+		if groupID != uuid.Nil {
+			db = db.Where("expenses.group_id = ?", groupID)
+		}
+		if f.Category != "" {
+			db = db.Where("expenses.category = ?", f.Category)
+		}
+		if paidBy != uuid.Nil {
+			db = db.Where("expenses.paid_by = ?", paidBy)
+		}
+		if participant != uuid.Nil {
+			db = db.Where(hasShare, participant)
+		}
+		if f.MinAmount > 0 {
+			db = db.Where("expenses.amount >= ?", f.MinAmount)
+		}
+		if f.MaxAmount > 0 {
+			db = db.Where("expenses.amount <= ?", f.MaxAmount)
+		}
+		if !from.IsZero() {
+			db = db.Where("expenses.expense_date >= ?", from)
+		}
+		if !to.IsZero() {
+			db = db.Where("expenses.expense_date <= ?", to)
+		}
+		if f.Currency != "" {
+			db = db.Where("expenses.currency = ?", strings.ToUpper(f.Currency))
+		}
+		if f.IPaid {
+			db = db.Where("expenses.paid_by = ?", userID)
+		}
+		if f.InvolvesMe {
+			db = db.Where("expenses.paid_by = ? OR "+hasShare, userID, userID)
+		}
+		return db
+	}, nil
+}
+
+// Must match the expression of the idx_expenses_search index so Postgres can use it
+const expenseSearchVector = "to_tsvector('simple', coalesce(expenses.description, '') || ' ' || coalesce(expenses.notes, ''))"
+
+// Helper: totals per currency over every expense matching the scopes (not just the current page)
+func expenseTotals(userID uuid.UUID, scopes ...func(*gorm.DB) *gorm.DB) models.ExpenseTotals {
+	totals := models.ExpenseTotals{ByCurrency: []models.CurrencyTotal{}}
+
+	database.DB.Model(&models.Expense{}).
+		Scopes(scopes...).
+		Joins("LEFT JOIN expense_splits mine ON mine.expense_id = expenses.id AND mine.user_id = ?", userID).
+		Select(`expenses.currency AS currency,
+			COUNT(*) AS count,
+			COALESCE(SUM(expenses.amount), 0) AS amount,
+			COALESCE(SUM(mine.owed_amount), 0) AS my_share,
+			COALESCE(SUM(CASE WHEN expenses.paid_by = ? THEN expenses.amount ELSE 0 END), 0) AS i_paid`, userID).
+		Group("expenses.currency").
+		Order("expenses.currency").
+		Scan(&totals.ByCurrency)
+
+	for i, t := range totals.ByCurrency {
+		totals.Count += t.Count
+		totals.ByCurrency[i].Amount = utils.RoundToTwo(t.Amount)
+		totals.ByCurrency[i].MyShare = utils.RoundToTwo(t.MyShare)
+		totals.ByCurrency[i].IPaid = utils.RoundToTwo(t.IPaid)
+	}
+	return totals
 }
 
 // GET /api/expenses/:id
@@ -140,12 +358,18 @@ func GetExpense(c *gin.Context) {
 		return
 	}
 
-	response := buildExpenseResponse(expenseID)
-	if response.ID == uuid.Nil {
+	var expense models.Expense
+	if err := database.DB.First(&expense, expenseID).Error; err != nil {
 		utils.NotFound(c, "Expense not found")
 		return
 	}
 
+	if _, ok := authorize(c, expense.GroupID, models.PermViewGroup); !ok {
+		return
+	}
+
+	response := buildExpenseResponse(expenseID)
+	utils.SetETag(c, response.Version)
 	utils.SuccessResponse(c, http.StatusOK, "", response)
 }
 
@@ -164,8 +388,23 @@ func UpdateExpense(c *gin.Context) {
 		return
 	}
 
-	if !isMember(expense.GroupID, userID) {
-		utils.Unauthorized(c, "You are not a member of this group")
+	access, ok := authorize(c, expense.GroupID, models.PermViewGroup)
+	if !ok {
+		return
+	}
+	if !access.Group.CanModifyExpense(access.Member, expense) {
+		utils.Forbidden(c, "Only the payer or a group admin can change this expense")
+		return
+	}
+
+	// Offline clients send the version they edited; a stale one is a conflict
+	expected, conditional, err := utils.IfMatchVersion(c)
+	if err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+	if conditional && expected != expense.Version {
+		respondExpenseConflict(c, expense.ID)
 		return
 	}
 
@@ -178,43 +417,90 @@ func UpdateExpense(c *gin.Context) {
 	updates := map[string]interface{}{}
 	if req.Description != "" {
 		updates["description"] = req.Description
+		expense.Description = req.Description
 	}
 	if req.Amount > 0 {
 		updates["amount"] = req.Amount
+		expense.Amount = req.Amount
 	}
 	if req.Category != "" {
+		if !access.Group.Settings.AllowsCategory(req.Category) {
+			utils.BadRequest(c, fmt.Sprintf("Category \"%s\" is not allowed in this group", req.Category))
+			return
+		}
 		updates["category"] = req.Category
 	}
 	if req.Notes != "" {
 		updates["notes"] = req.Notes
 	}
 
-	database.DB.Model(&expense).Updates(updates)
-
-	// Recalculate splits if amount or split type changed
-	if req.Amount > 0 || req.SplitType != "" || len(req.Splits) > 0 {
-		// Delete old splits
-		database.DB.Where("expense_id = ?", expenseID).Delete(&models.ExpenseSplit{})
-
-		// Reload expense
-		database.DB.First(&expense, expenseID)
+	// Recalculate splits if amount, split type or participants changed. This happens
+	// before anything is written so a bad split leaves the expense untouched.
+	var splits []models.ExpenseSplit
+	resplit := req.Amount > 0 || req.SplitType != "" || len(req.Splits) > 0 || len(req.Participants) > 0
+	if resplit {
+		var oldSplits []models.ExpenseSplit
+		database.DB.Where("expense_id = ?", expenseID).Find(&oldSplits)
 
 		splitType := req.SplitType
 		if splitType == "" {
 			splitType = expense.SplitType
 		}
+
+		// An equal split keeps its participants when only the amount changes
+		participants := req.Participants
+		if len(participants) == 0 && splitType == "equal" && expense.SplitType == "equal" {
+			for _, s := range oldSplits {
+				participants = append(participants, s.UserID.String())
+			}
+		}
 		expense.SplitType = splitType
 
-		splits, err := calculateSplits(expense, req.Splits, expense.GroupID)
+		splits, err = calculateSplits(expense, req.Splits, participants, expense.GroupID)
 		if err != nil {
 			utils.BadRequest(c, err.Error())
 			return
 		}
+		updates["split_type"] = splitType
+	}
+
+	updates["version"] = gorm.Expr("version + 1")
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		query := tx.Model(&models.Expense{}).Where("id = ?", expenseID)
+		if conditional {
+			query = query.Where("version = ?", expected)
+		}
+		result := query.Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errVersionConflict // someone else saved in between
+		}
+
+		if !resplit {
+			return nil
+		}
 
+		// Replace old splits
+		if err := tx.Where("expense_id = ?", expenseID).Delete(&models.ExpenseSplit{}).Error; err != nil {
+			return err
+		}
 		for _, split := range splits {
-			split.ExpenseID = expense.ID
-			database.DB.Create(&split)
+			split.ExpenseID = expenseID
+			if err := tx.Create(&split).Error; err != nil {
+				return err
+			}
 		}
+		return nil
+	})
+	if err == errVersionConflict {
+		respondExpenseConflict(c, expenseID)
+		return
+	}
+	if err != nil {
+		utils.InternalError(c, "Failed to update expense")
+		return
 	}
 
 	// Log activity
@@ -228,11 +514,20 @@ func UpdateExpense(c *gin.Context) {
 		ReferenceID: expense.ID,
 		Description: fmt.Sprintf("%s updated \"%s\"", editor.Name, expense.Description),
 	})
+	archiveIfSettled(access.Group, userID)
 
 	response := buildExpenseResponse(expense.ID)
+	utils.SetETag(c, response.Version)
 	utils.SuccessResponse(c, http.StatusOK, "Expense updated", response)
 }
 
+// Helper: 409 with the expense as it is now, so the client can merge and retry
+func respondExpenseConflict(c *gin.Context, expenseID uuid.UUID) {
+	response := buildExpenseResponse(expenseID)
+	utils.SetETag(c, response.Version)
+	utils.Conflict(c, "This expense was changed by someone else. Review the current version and try again", response)
+}
+
 // DELETE /api/expenses/:id
 func DeleteExpense(c *gin.Context) {
 	userID := utils.GetCurrentUserID(c)
@@ -248,8 +543,12 @@ func DeleteExpense(c *gin.Context) {
 		return
 	}
 
-	if !isMember(expense.GroupID, userID) {
-		utils.Unauthorized(c, "You are not a member of this group")
+	access, ok := authorize(c, expense.GroupID, models.PermViewGroup)
+	if !ok {
+		return
+	}
+	if !access.Group.CanModifyExpense(access.Member, expense) {
+		utils.Forbidden(c, "Only the payer or a group admin can change this expense")
 		return
 	}
 
@@ -264,44 +563,40 @@ func DeleteExpense(c *gin.Context) {
 		Description: fmt.Sprintf("%s deleted \"%s\" (%s %.2f)", deleter.Name, expense.Description, expense.Currency, expense.Amount),
 	})
 
-	// Delete splits and expense
+	// Delete splits, comments and expense
 	database.DB.Where("expense_id = ?", expenseID).Delete(&models.ExpenseSplit{})
+	deleteDiscussion(database.DB, models.TargetExpense, expenseID)
 	database.DB.Delete(&expense)
+	go services.DeleteReceipt(expense)
+	archiveIfSettled(access.Group, userID)
 
 	utils.SuccessResponse(c, http.StatusOK, "Expense deleted", nil)
 }
 
 // Calculate splits based on split type
-func calculateSplits(expense models.Expense, splitInputs []models.SplitInput, groupID uuid.UUID) ([]models.ExpenseSplit, error) {
+func calculateSplits(expense models.Expense, splitInputs []models.SplitInput, participants []string, groupID uuid.UUID) ([]models.ExpenseSplit, error) {
 	var splits []models.ExpenseSplit
 
 	switch expense.SplitType {
 	case "equal":
-		// Split equally among all group members
-		var members []models.GroupMember
-		database.DB.Where("group_id = ?", groupID).Find(&members)
-
-		if len(members) == 0 {
-			return nil, fmt.Errorf("no members in group")
+		// Split equally among the chosen participants, or all active group members (former members only if already on the expense)
+		members, err := equalSplitParticipants(groupID, expense.ID, participants)
+		if err != nil {
+			return nil, err
 		}
 
-		perPerson := utils.RoundToTwo(expense.Amount / float64(len(members)))
+		// Leftover cents go to the first people
+		shares := utils.SplitEvenly(expense.Amount, len(members))
 
-		// Handle rounding remainder
-		remainder := utils.RoundToTwo(expense.Amount - perPerson*float64(len(members)))
-
-		for i, m := range members {
-			amount := perPerson
-			if i == 0 {
-				amount = utils.RoundToTwo(amount + remainder) // first person gets the remainder
-			}
+		for i, uid := range members {
+			amount := shares[i]
 			paidAmount := 0.0
-			if m.UserID == expense.PaidBy {
+			if uid == expense.PaidBy {
 				paidAmount = expense.Amount
 			}
 
 			splits = append(splits, models.ExpenseSplit{
-				UserID:     m.UserID,
+				UserID:     uid,
 				OwedAmount: amount,
 				PaidAmount: paidAmount,
 			})
@@ -355,13 +650,20 @@ func calculateSplits(expense models.Expense, splitInputs []models.SplitInput, gr
 			return nil, fmt.Errorf("percentages must add up to 100, got %.2f", totalPercent)
 		}
 
-		for _, s := range splitInputs {
+		// Rounded as a whole so the stray cents don't leave the expense unbalanced
+		weights := make([]float64, len(splitInputs))
+		for i, s := range splitInputs {
+			weights[i] = s.Value
+		}
+		owed := utils.SplitByWeights(expense.Amount, weights)
+
+		for i, s := range splitInputs {
 			uid, err := uuid.Parse(s.UserID)
 			if err != nil {
 				return nil, fmt.Errorf("invalid user ID: %s", s.UserID)
 			}
 
-			owedAmount := utils.RoundToTwo(expense.Amount * s.Value / 100.0)
+			owedAmount := owed[i]
 			paidAmount := 0.0
 			if uid == expense.PaidBy {
 				paidAmount = expense.Amount
@@ -389,13 +691,72 @@ func calculateSplits(expense models.Expense, splitInputs []models.SplitInput, gr
 			return nil, fmt.Errorf("total shares must be greater than 0")
 		}
 
+		// Rounded as a whole so the stray cents don't leave the expense unbalanced
+		weights := make([]float64, len(splitInputs))
+		for i, s := range splitInputs {
+			weights[i] = s.Value
+		}
+		owed := utils.SplitByWeights(expense.Amount, weights)
+
+		for i, s := range splitInputs {
+			uid, err := uuid.Parse(s.UserID)
+			if err != nil {
+				return nil, fmt.Errorf("invalid user ID: %s", s.UserID)
+			}
+
+			owedAmount := owed[i]
+			paidAmount := 0.0
+			if uid == expense.PaidBy {
+				paidAmount = expense.Amount
+			}
+
+			splits = append(splits, models.ExpenseSplit{
+				UserID:     uid,
+				OwedAmount: owedAmount,
+				PaidAmount: paidAmount,
+			})
+		}
+
+	case "adjustment":
+		// Everyone listed pays an equal part of what's left after their own +/- adjustment
+		if len(splitInputs) == 0 {
+			return nil, fmt.Errorf("splits required for adjustment split type")
+		}
+
+		// Keyed by the parsed ID so two spellings of one UUID count as the same person
+		var ids []string
+		var totalAdjustment float64
+		adjustments := map[uuid.UUID]float64{}
 		for _, s := range splitInputs {
 			uid, err := uuid.Parse(s.UserID)
 			if err != nil {
 				return nil, fmt.Errorf("invalid user ID: %s", s.UserID)
 			}
+			if _, dup := adjustments[uid]; dup {
+				return nil, fmt.Errorf("user %s appears more than once", s.UserID)
+			}
+			adjustments[uid] = s.Value
+			ids = append(ids, uid.String())
+			totalAdjustment += s.Value
+		}
+
+		members, err := equalSplitParticipants(groupID, expense.ID, ids)
+		if err != nil {
+			return nil, err
+		}
+
+		remaining := utils.RoundToTwo(expense.Amount - totalAdjustment)
+		if remaining < 0 {
+			return nil, fmt.Errorf("adjustments (%.2f) exceed the total (%.2f)", totalAdjustment, expense.Amount)
+		}
+
+		shares := utils.SplitEvenly(remaining, len(members))
+		for i, uid := range members {
+			owedAmount := utils.RoundToTwo(shares[i] + adjustments[uid])
+			if owedAmount < 0 {
+				return nil, fmt.Errorf("adjustment for %s makes their share negative", uid)
+			}
 
-			owedAmount := utils.RoundToTwo(expense.Amount * s.Value / totalShares)
 			paidAmount := 0.0
 			if uid == expense.PaidBy {
 				paidAmount = expense.Amount
@@ -415,44 +776,115 @@ func calculateSplits(expense models.Expense, splitInputs []models.SplitInput, gr
 	return splits, nil
 }
 
+// Helper: resolve who shares an equal split. Participants must be active members of the group,
+// except that people who have left can stay on an expense they are already part of.
+func equalSplitParticipants(groupID uuid.UUID, expenseID uuid.UUID, participants []string) ([]uuid.UUID, error) {
+	var members []models.GroupMember
+	database.DB.Where("group_id = ? AND status = ?", groupID, models.MemberActive).Order("joined_at").Find(&members)
+
+	if len(members) == 0 {
+		return nil, fmt.Errorf("no members in group")
+	}
+
+	var ids []uuid.UUID
+	if len(participants) == 0 {
+		for _, m := range members {
+			ids = append(ids, m.UserID)
+		}
+		return ids, nil
+	}
+
+	allowed := map[uuid.UUID]bool{}
+	for _, m := range members {
+		allowed[m.UserID] = true
+	}
+	var current []uuid.UUID
+	database.DB.Model(&models.ExpenseSplit{}).Where("expense_id = ?", expenseID).Pluck("user_id", &current)
+	for _, id := range current {
+		allowed[id] = true
+	}
+
+	seen := map[uuid.UUID]bool{}
+	for _, p := range participants {
+		uid, err := uuid.Parse(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid user ID: %s", p)
+		}
+		if !allowed[uid] {
+			return nil, fmt.Errorf("user %s is not an active member of this group", p)
+		}
+		if seen[uid] {
+			continue
+		}
+		seen[uid] = true
+		ids = append(ids, uid)
+	}
+	return ids, nil
+}
+
 // Build expense response with payer name and split details
 func buildExpenseResponse(expenseID uuid.UUID) models.ExpenseResponse {
 	var expense models.Expense
 	if err := database.DB.First(&expense, expenseID).Error; err != nil {
 		return models.ExpenseResponse{}
 	}
+	return buildExpenseResponses([]models.Expense{expense})[0]
+}
 
-	var payer models.User
-	database.DB.First(&payer, expense.PaidBy)
+// Build responses for a page of expenses: splits and users are loaded in one query each
+func buildExpenseResponses(expenses []models.Expense) []models.ExpenseResponse {
+	responses := make([]models.ExpenseResponse, 0, len(expenses))
+	if len(expenses) == 0 {
+		return responses
+	}
+
+	expenseIDs := make([]uuid.UUID, 0, len(expenses))
+	userIDs := make([]uuid.UUID, 0, len(expenses))
+	for _, e := range expenses {
+		expenseIDs = append(expenseIDs, e.ID)
+		userIDs = append(userIDs, e.PaidBy)
+	}
 
 	var dbSplits []models.ExpenseSplit
-	database.DB.Where("expense_id = ?", expenseID).Find(&dbSplits)
+	database.DB.Where("expense_id IN ?", expenseIDs).Find(&dbSplits)
 
-	var splitResponses []models.SplitResponse
+	splitsByExpense := make(map[uuid.UUID][]models.ExpenseSplit)
 	for _, s := range dbSplits {
-		var user models.User
-		database.DB.First(&user, s.UserID)
-		splitResponses = append(splitResponses, models.SplitResponse{
-			UserID:     s.UserID,
-			UserName:   user.Name,
-			OwedAmount: s.OwedAmount,
-			PaidAmount: s.PaidAmount,
-		})
+		splitsByExpense[s.ExpenseID] = append(splitsByExpense[s.ExpenseID], s)
+		userIDs = append(userIDs, s.UserID)
 	}
 
-	return models.ExpenseResponse{
-		ID:          expense.ID,
-		GroupID:     expense.GroupID,
-		PaidBy:      expense.PaidBy,
-		PayerName:   payer.Name,
-		Description: expense.Description,
-		Amount:      expense.Amount,
-		Currency:    expense.Currency,
-		Category:    expense.Category,
-		SplitType:   expense.SplitType,
-		Notes:       expense.Notes,
-		ExpenseDate: expense.ExpenseDate,
-		Splits:      splitResponses,
-		CreatedAt:   expense.CreatedAt,
+	users := loadUsers(userIDs)
+
+	for _, expense := range expenses {
+		var splitResponses []models.SplitResponse
+		for _, s := range splitsByExpense[expense.ID] {
+			splitResponses = append(splitResponses, models.SplitResponse{
+				UserID:     s.UserID,
+				UserName:   users[s.UserID].Name,
+				OwedAmount: s.OwedAmount,
+				PaidAmount: s.PaidAmount,
+			})
+		}
+
+		responses = append(responses, models.ExpenseResponse{
+			ID:              expense.ID,
+			GroupID:         expense.GroupID,
+			PaidBy:          expense.PaidBy,
+			PayerName:       users[expense.PaidBy].Name,
+			Description:     expense.Description,
+			Amount:          expense.Amount,
+			Currency:        expense.Currency,
+			Category:        expense.Category,
+			SplitType:       expense.SplitType,
+			Notes:           expense.Notes,
+			ReceiptURL:      expense.ReceiptURL,
+			ReceiptThumbURL: receiptThumbURL(expense),
+			ExpenseDate:     expense.ExpenseDate,
+			Splits:          splitResponses,
+			Version:         expense.Version,
+			CreatedAt:       expense.CreatedAt,
+		})
 	}
+	return responses
 }
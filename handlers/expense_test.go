@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"net/http"
+	"splitwise-backend/database"
+	"splitwise-backend/models"
+	"splitwise-backend/utils"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// Former members stay on the expenses they were part of, and those expenses remain editable
+func TestUpdateEqualExpenseWithFormerMember(t *testing.T) {
+	requireDB(t)
+
+	alice := createTestUser(t, "Alice")
+	bob := createTestUser(t, "Bob")
+	carol := createTestUser(t, "Carol")
+	group := createTestGroup(t, alice, bob, carol)
+
+	expense := models.Expense{GroupID: group.ID, PaidBy: alice.ID, Description: "Cabin", Amount: 90, SplitType: "equal"}
+	if err := database.DB.Create(&expense).Error; err != nil {
+		t.Fatal(err)
+	}
+	splits := []models.ExpenseSplit{
+		{ExpenseID: expense.ID, UserID: alice.ID, OwedAmount: 30, PaidAmount: 90},
+		{ExpenseID: expense.ID, UserID: bob.ID, OwedAmount: 30},
+		{ExpenseID: expense.ID, UserID: carol.ID, OwedAmount: 30},
+	}
+	if err := database.DB.Create(&splits).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	database.DB.Model(&models.GroupMember{}).
+		Where("group_id = ? AND user_id = ?", group.ID, carol.ID).
+		Update("status", models.MemberFormer)
+
+	path := "/api/expenses/" + expense.ID.String()
+
+	// Changing only the amount re-splits over the same three people
+	w := serveAs(alice, UpdateExpense, http.MethodPut, "/api/expenses/:id", path, map[string]interface{}{"amount": 120})
+	if w.Code != http.StatusOK {
+		t.Fatalf("update amount: status %d: %s", w.Code, w.Body)
+	}
+	assertSplits(t, expense.ID, map[uuid.UUID]float64{alice.ID: 40, bob.ID: 40, carol.ID: 40})
+
+	// Someone who was never on the expense can't be added once they're gone
+	dave := createTestUser(t, "Dave")
+	database.DB.Create(&models.GroupMember{GroupID: group.ID, UserID: dave.ID, Role: "member", Status: models.MemberFormer})
+
+	w = serveAs(alice, UpdateExpense, http.MethodPut, "/api/expenses/:id", path, map[string]interface{}{
+		"amount":       150,
+		"participants": []string{alice.ID.String(), dave.ID.String()},
+	})
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("adding a former member: status %d, want 400", w.Code)
+	}
+
+	// The rejected update left everything as it was
+	var stored models.Expense
+	database.DB.First(&stored, expense.ID)
+	if stored.Amount != 120 {
+		t.Errorf("amount = %.2f after a rejected update, want 120", stored.Amount)
+	}
+	assertSplits(t, expense.ID, map[uuid.UUID]float64{alice.ID: 40, bob.ID: 40, carol.ID: 40})
+}
+
+// Upper- and lower-case spellings of one UUID are the same person, not two participants
+func TestAdjustmentSplitRejectsSameUserTwice(t *testing.T) {
+	id := uuid.New()
+	expense := models.Expense{Amount: 100, SplitType: "adjustment"}
+	inputs := []models.SplitInput{
+		{UserID: id.String(), Value: 10},
+		{UserID: strings.ToUpper(id.String()), Value: -10},
+	}
+
+	_, err := calculateSplits(expense, inputs, nil, uuid.New())
+	if err == nil || !strings.Contains(err.Error(), "more than once") {
+		t.Errorf("err = %v, want duplicate user", err)
+	}
+}
+
+func assertSplits(t *testing.T, expenseID uuid.UUID, want map[uuid.UUID]float64) {
+	t.Helper()
+
+	var splits []models.ExpenseSplit
+	database.DB.Where("expense_id = ?", expenseID).Find(&splits)
+	if len(splits) != len(want) {
+		t.Fatalf("got %d splits, want %d", len(splits), len(want))
+	}
+	for _, s := range splits {
+		if amount, ok := want[s.UserID]; !ok || utils.RoundToTwo(s.OwedAmount) != amount {
+			t.Errorf("split for %s = %.2f, want %.2f", s.UserID, s.OwedAmount, amount)
+		}
+	}
+}
+
+// Percentage and shares splits are rounded as a whole: the owed amounts add up to the expense
+func TestProportionalSplitsAddUp(t *testing.T) {
+	a, b, c := uuid.New(), uuid.New(), uuid.New()
+
+	tests := []struct {
+		splitType string
+		amount    float64
+		values    []float64
+		want      []float64
+	}{
+		{"shares", 100, []float64{1, 1, 1}, []float64{33.34, 33.33, 33.33}},
+		{"shares", 10, []float64{2, 3, 1}, []float64{3.33, 5, 1.67}},
+		{"percentage", 100, []float64{33.33, 33.33, 33.34}, []float64{33.33, 33.33, 33.34}},
+		{"percentage", 0.1, []float64{50, 25, 25}, []float64{0.05, 0.03, 0.02}},
+	}
+	for _, tt := range tests {
+		expense := models.Expense{Amount: tt.amount, SplitType: tt.splitType, PaidBy: a}
+		inputs := []models.SplitInput{
+			{UserID: a.String(), Value: tt.values[0]},
+			{UserID: b.String(), Value: tt.values[1]},
+			{UserID: c.String(), Value: tt.values[2]},
+		}
+
+		splits, err := calculateSplits(expense, inputs, nil, uuid.New())
+		if err != nil {
+			t.Errorf("%s %v: %v", tt.splitType, tt.values, err)
+			continue
+		}
+		var total float64
+		for i, s := range splits {
+			total += s.OwedAmount
+			if s.OwedAmount != tt.want[i] {
+				t.Errorf("%s %v: part %d = %.2f, want %.2f", tt.splitType, tt.values, i, s.OwedAmount, tt.want[i])
+			}
+		}
+		if utils.RoundToTwo(total) != tt.amount {
+			t.Errorf("%s %v: owed amounts add up to %.2f, want %.2f", tt.splitType, tt.values, total, tt.amount)
+		}
+	}
+}
+
+// Adjustment splits share what's left after the adjustments evenly, to the cent
+func TestAdjustmentSplitMath(t *testing.T) {
+	requireDB(t)
+
+	alice := createTestUser(t, "Alice")
+	bob := createTestUser(t, "Bob")
+	carol := createTestUser(t, "Carol")
+	group := createTestGroup(t, alice, bob, carol)
+
+	tests := []struct {
+		name        string
+		amount      float64
+		adjustments []float64 // for Alice, Bob and Carol
+		want        []float64
+		wantErr     string
+	}{
+		{"adjustment on top of an even split", 100, []float64{10, 0, 0}, []float64{40, 30, 30}, ""},
+		{"remainder cents go to the first", 100, []float64{5, 0, 0}, []float64{36.67, 31.67, 31.66}, ""},
+		{"remainder with adjustments for everyone", 50, []float64{1.5, 2.5, -1}, []float64{17.17, 18.17, 14.66}, ""},
+		{"negative adjustment", 50, []float64{-10, 0, 0}, []float64{10, 20, 20}, ""},
+		{"adjustments use up the total", 100, []float64{60, 40, 0}, []float64{60, 40, 0}, ""},
+		{"adjustments larger than the total", 100, []float64{80, 30, 0}, nil, "exceed the total"},
+		{"adjustment making a share negative", 30, []float64{-40, 0, 0}, nil, "negative"},
+	}
+	for _, tt := range tests {
+		expense := models.Expense{GroupID: group.ID, PaidBy: alice.ID, Amount: tt.amount, SplitType: "adjustment"}
+		inputs := []models.SplitInput{
+			{UserID: alice.ID.String(), Value: tt.adjustments[0]},
+			{UserID: bob.ID.String(), Value: tt.adjustments[1]},
+			{UserID: carol.ID.String(), Value: tt.adjustments[2]},
+		}
+
+		splits, err := calculateSplits(expense, inputs, nil, group.ID)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: err = %v, want %q", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+
+		var total float64
+		for i, s := range splits {
+			total += s.OwedAmount
+			if s.OwedAmount != tt.want[i] {
+				t.Errorf("%s: part %d = %.2f, want %.2f", tt.name, i, s.OwedAmount, tt.want[i])
+			}
+		}
+		if utils.RoundToTwo(total) != tt.amount {
+			t.Errorf("%s: owed amounts add up to %.2f, want %.2f", tt.name, total, tt.amount)
+		}
+	}
+}
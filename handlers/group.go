@@ -7,9 +7,12 @@ import (
 	"splitwise-backend/models"
 	"splitwise-backend/services"
 	"splitwise-backend/utils"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 // POST /api/groups
@@ -27,10 +30,36 @@ func CreateGroup(c *gin.Context) {
 		groupType = "other"
 	}
 
+	clientID, ok := parseClientID(c, req.ID)
+	if !ok {
+		return
+	}
+	if clientID != uuid.Nil {
+		var existing models.Group
+		if database.DB.First(&existing, clientID).Error == nil {
+			if existing.CreatedBy != userID {
+				utils.Conflict(c, "This group ID is already in use", nil)
+				return
+			}
+			// A retried offline create: answer with what the first attempt made
+			response := buildGroupResponse(existing.ID)
+			utils.SetETag(c, response.Version)
+			utils.SuccessResponse(c, http.StatusOK, "Group already exists", response)
+			return
+		}
+	}
+
+	joinPolicy := req.JoinPolicy
+	if joinPolicy == "" {
+		joinPolicy = models.JoinPolicyAuto
+	}
+
 	group := models.Group{
-		Name:      req.Name,
-		Type:      groupType,
-		CreatedBy: userID,
+		ID:         clientID,
+		Name:       req.Name,
+		Type:       groupType,
+		CreatedBy:  userID,
+		JoinPolicy: joinPolicy,
 	}
 
 	if err := database.DB.Create(&group).Error; err != nil {
@@ -38,11 +67,11 @@ func CreateGroup(c *gin.Context) {
 		return
 	}
 
-	// Add creator as admin member
+	// Add creator as owner
 	member := models.GroupMember{
 		GroupID: group.ID,
 		UserID:  userID,
-		Role:    "admin",
+		Role:    models.RoleOwner,
 	}
 	database.DB.Create(&member)
 
@@ -56,18 +85,29 @@ func CreateGroup(c *gin.Context) {
 				memberUUID = user.ID
 			} else {
 				// Send invitation
-				go services.InviteToGroup(group.ID, userID, memberInput, "")
+				services.InviteToGroup(group.ID, userID, memberInput, "")
 				continue
 			}
 		}
 
-		if memberUUID != userID {
-			database.DB.Create(&models.GroupMember{
-				GroupID: group.ID,
-				UserID:  memberUUID,
-				Role:    "member",
-			})
+		if memberUUID == userID {
+			continue
 		}
+
+		// Consent-required groups invite registered users instead of adding them
+		if joinPolicy == models.JoinPolicyConsent {
+			var user models.User
+			if dbErr := database.DB.First(&user, memberUUID).Error; dbErr == nil {
+				services.InviteToGroup(group.ID, userID, user.Email, "")
+			}
+			continue
+		}
+
+		database.DB.Create(&models.GroupMember{
+			GroupID: group.ID,
+			UserID:  memberUUID,
+			Role:    models.RoleMember,
+		})
 	}
 
 	// Log activity
@@ -76,6 +116,7 @@ func CreateGroup(c *gin.Context) {
 	database.DB.Create(&models.Activity{
 		GroupID:     group.ID,
 		UserID:      userID,
+		MemberID:    &userID,
 		Type:        "group_created",
 		ReferenceID: group.ID,
 		Description: fmt.Sprintf("%s created group \"%s\"", creator.Name, group.Name),
@@ -83,10 +124,11 @@ func CreateGroup(c *gin.Context) {
 
 	// Return group with members
 	response := buildGroupResponse(group.ID)
+	utils.SetETag(c, response.Version)
 	utils.SuccessResponse(c, http.StatusCreated, "Group created", response)
 }
 
-// GET /api/groups
+// GET /api/groups — archived groups are hidden unless ?include_archived=true
 func GetGroups(c *gin.Context) {
 	userID := utils.GetCurrentUserID(c)
 
@@ -100,55 +142,59 @@ func GetGroups(c *gin.Context) {
 
 	var groups []models.Group
 	if len(groupIDs) > 0 {
-		database.DB.Where("id IN ?", groupIDs).Order("created_at DESC").Find(&groups)
+		query := database.DB.Where("id IN ?", groupIDs)
+		if c.Query("include_archived") != "true" {
+			query = query.Where("archived_at IS NULL")
+		}
+		query.Order("created_at DESC").Find(&groups)
 	}
 
-	var responses []models.GroupResponse
-	for _, g := range groups {
-		responses = append(responses, buildGroupResponse(g.ID))
-	}
+	responses := buildGroupResponses(groups)
 
 	utils.SuccessResponse(c, http.StatusOK, "", responses)
 }
 
 // GET /api/groups/:id
 func GetGroup(c *gin.Context) {
-	userID := utils.GetCurrentUserID(c)
 	groupID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
 		utils.BadRequest(c, "Invalid group ID")
 		return
 	}
 
-	// Check membership
-	if !isMember(groupID, userID) {
-		utils.Unauthorized(c, "You are not a member of this group")
+	if _, ok := authorize(c, groupID, models.PermViewGroup); !ok {
 		return
 	}
 
 	response := buildGroupResponse(groupID)
+	utils.SetETag(c, response.Version)
 	utils.SuccessResponse(c, http.StatusOK, "", response)
 }
 
 // PUT /api/groups/:id
 func UpdateGroup(c *gin.Context) {
-	userID := utils.GetCurrentUserID(c)
 	groupID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
 		utils.BadRequest(c, "Invalid group ID")
 		return
 	}
 
-	if !isMember(groupID, userID) {
-		utils.Unauthorized(c, "You are not a member of this group")
+	access, ok := authorize(c, groupID, models.PermEditGroup)
+	if !ok {
 		return
 	}
 
-	var req struct {
-		Name     string `json:"name"`
-		Type     string `json:"type"`
-		ImageURL string `json:"image_url"`
+	expected, conditional, err := utils.IfMatchVersion(c)
+	if err != nil {
+		utils.BadRequest(c, err.Error())
+		return
 	}
+	if conditional && expected != access.Group.Version {
+		respondGroupConflict(c, groupID)
+		return
+	}
+
+	var req models.UpdateGroupRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.BadRequest(c, err.Error())
 		return
@@ -164,13 +210,103 @@ func UpdateGroup(c *gin.Context) {
 	if req.ImageURL != "" {
 		updates["image_url"] = req.ImageURL
 	}
+	if req.ExpenseEditPolicy != "" {
+		updates["expense_edit_policy"] = req.ExpenseEditPolicy
+	}
+	if req.MemberAddPolicy != "" {
+		updates["member_add_policy"] = req.MemberAddPolicy
+	}
+	if req.JoinPolicy != "" {
+		updates["join_policy"] = req.JoinPolicy
+	}
 
-	database.DB.Model(&models.Group{}).Where("id = ?", groupID).Updates(updates)
+	updates["version"] = gorm.Expr("version + 1")
+	query := database.DB.Model(&models.Group{}).Where("id = ?", groupID)
+	if conditional {
+		query = query.Where("version = ?", expected)
+	}
+	result := query.Updates(updates)
+	if result.Error != nil {
+		utils.InternalError(c, "Failed to update group")
+		return
+	}
+	if result.RowsAffected == 0 {
+		respondGroupConflict(c, groupID) // someone else saved in between
+		return
+	}
 
 	response := buildGroupResponse(groupID)
+	utils.SetETag(c, response.Version)
 	utils.SuccessResponse(c, http.StatusOK, "Group updated", response)
 }
 
+// Helper: 409 with the group as it is now, so the client can merge and retry
+func respondGroupConflict(c *gin.Context, groupID uuid.UUID) {
+	response := buildGroupResponse(groupID)
+	utils.SetETag(c, response.Version)
+	utils.Conflict(c, "This group was changed by someone else. Review the current version and try again", response)
+}
+
+// PUT /api/groups/:id/settings
+func UpdateGroupSettings(c *gin.Context) {
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid group ID")
+		return
+	}
+
+	access, ok := authorize(c, groupID, models.PermEditGroup)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateGroupSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	settings := access.Group.Settings
+	if req.DefaultCurrency != nil {
+		settings.DefaultCurrency = strings.ToUpper(*req.DefaultCurrency)
+	}
+	if req.DefaultSplitType != nil {
+		settings.DefaultSplitType = *req.DefaultSplitType
+	}
+	if req.DefaultSplits != nil {
+		settings.DefaultSplits = *req.DefaultSplits
+	}
+	if req.AllowedCategories != nil {
+		settings.AllowedCategories = models.StringList{}
+		for _, category := range *req.AllowedCategories {
+			if category = strings.TrimSpace(category); category != "" {
+				settings.AllowedCategories = append(settings.AllowedCategories, category)
+			}
+		}
+	}
+	if req.AdminsOnlyExpenses != nil {
+		settings.AdminsOnlyExpenses = *req.AdminsOnlyExpenses
+	}
+
+	if err := validateDefaultSplits(groupID, settings); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	database.DB.Model(&models.Group{}).Where("id = ?", groupID).Updates(map[string]interface{}{
+		"settings_default_currency":     settings.DefaultCurrency,
+		"settings_default_split_type":   settings.DefaultSplitType,
+		"settings_default_splits":       settings.DefaultSplits,
+		"settings_allowed_categories":   settings.AllowedCategories,
+		"settings_admins_only_expenses": settings.AdminsOnlyExpenses,
+		"version":                       gorm.Expr("version + 1"),
+	})
+
+	response := buildGroupResponse(groupID)
+	utils.SetETag(c, response.Version)
+	utils.SuccessResponse(c, http.StatusOK, "Group settings updated", response)
+}
+
 // POST /api/groups/:id/members
 func AddMember(c *gin.Context) {
 	userID := utils.GetCurrentUserID(c)
@@ -180,8 +316,8 @@ func AddMember(c *gin.Context) {
 		return
 	}
 
-	if !isMember(groupID, userID) {
-		utils.Unauthorized(c, "You are not a member of this group")
+	access, ok := authorize(c, groupID, models.PermAddMember)
+	if !ok {
 		return
 	}
 
@@ -216,38 +352,53 @@ func AddMember(c *gin.Context) {
 	if found {
 		// Check if already a member
 		var existing models.GroupMember
-		if err := database.DB.Where("group_id = ? AND user_id = ?", groupID, targetUser.ID).First(&existing).Error; err == nil {
+		if err := database.DB.Where("group_id = ? AND user_id = ?", groupID, targetUser.ID).First(&existing).Error; err == nil && existing.IsActive() {
 			utils.BadRequest(c, "User is already a member of this group")
 			return
 		}
 
-		database.DB.Create(&models.GroupMember{
-			GroupID: groupID,
-			UserID:  targetUser.ID,
-			Role:    "member",
-		})
+		// Consent-required groups invite instead of adding directly
+		if access.Group.JoinPolicy == models.JoinPolicyConsent {
+			services.InviteToGroup(groupID, userID, targetUser.Email, "")
+			utils.SuccessResponse(c, http.StatusOK, "Invitation sent", nil)
+			return
+		}
 
-		// Log activity and notify
 		var adder models.User
 		database.DB.First(&adder, userID)
-		var group models.Group
-		database.DB.First(&group, groupID)
+		group := access.Group
 
-		database.DB.Create(&models.Activity{
-			GroupID:     groupID,
-			UserID:      userID,
-			Type:        "member_joined",
-			Description: fmt.Sprintf("%s added %s to %s", adder.Name, targetUser.Name, group.Name),
-		})
+		err := database.DB.Transaction(func(tx *gorm.DB) error {
+			if _, err := services.JoinGroup(tx, groupID, targetUser.ID); err != nil {
+				return err
+			}
 
-		go services.GetNotificationService().NotifyMemberAdded(group, adder, targetUser)
+			// Log activity and notify
+			err := tx.Create(&models.Activity{
+				GroupID:     groupID,
+				UserID:      userID,
+				MemberID:    &targetUser.ID,
+				Type:        "member_joined",
+				ReferenceID: targetUser.ID,
+				Description: fmt.Sprintf("%s added %s to %s", adder.Name, targetUser.Name, group.Name),
+			}).Error
+			if err != nil {
+				return err
+			}
+
+			return services.GetNotificationService().NotifyMemberAdded(tx, group, adder, targetUser)
+		})
+		if err != nil {
+			utils.InternalError(c, "Failed to add member")
+			return
+		}
 
 		utils.SuccessResponse(c, http.StatusOK, "Member added", targetUser.ToResponse())
 	} else {
 		// User not registered — send invitation
 		email := req.Email
 		phone := req.Phone
-		go services.InviteToGroup(groupID, userID, email, phone)
+		services.InviteToGroup(groupID, userID, email, phone)
 		utils.SuccessResponse(c, http.StatusOK, "Invitation sent", nil)
 	}
 }
@@ -267,26 +418,68 @@ func RemoveMember(c *gin.Context) {
 		return
 	}
 
-	// Only admin or self can remove
-	var membership models.GroupMember
-	database.DB.Where("group_id = ? AND user_id = ?", groupID, userID).First(&membership)
-	if membership.Role != "admin" && userID != memberUID {
-		utils.Unauthorized(c, "Only admins can remove other members")
+	// Members can always leave; removing someone else needs a higher role than theirs
+	access, ok := authorize(c, groupID, models.PermViewGroup)
+	if !ok {
+		return
+	}
+
+	var target models.GroupMember
+	if err := database.DB.Where("group_id = ? AND user_id = ?", groupID, memberUID).First(&target).Error; err != nil || !target.IsActive() {
+		utils.NotFound(c, "Member not found")
+		return
+	}
+
+	if target.Role == models.RoleOwner {
+		utils.Forbidden(c, "The owner must transfer ownership before leaving the group")
 		return
 	}
 
-	database.DB.Where("group_id = ? AND user_id = ?", groupID, memberUID).Delete(&models.GroupMember{})
+	isAdminRemoval := userID != memberUID
+	if isAdminRemoval {
+		if !access.Group.AllowsMember(access.Member, models.PermRemoveMember) ||
+			models.RoleRank(access.Member.Role) <= models.RoleRank(target.Role) {
+			utils.Forbidden(c, "You cannot remove this member")
+			return
+		}
+	}
+
+	// Members with an open balance can't leave; an admin may override with ?force=true
+	balance := utils.RoundToTwo(calculateNetBalances(groupID)[memberUID])
+	if !isSettledAmount(balance) {
+		if !isAdminRemoval || c.Query("force") != "true" {
+			utils.Conflict(c, "Member still has an unsettled balance in this group", models.OutstandingBalance{
+				GroupID:   groupID,
+				GroupName: access.Group.Name,
+				Amount:    balance,
+			})
+			return
+		}
+	}
+
+	// Keep the row as a former member so history stays visible and they can still settle up
+	database.DB.Model(&models.GroupMember{}).
+		Where("group_id = ? AND user_id = ?", groupID, memberUID).
+		Updates(map[string]interface{}{"status": models.MemberFormer, "left_at": time.Now()})
 
 	var removedUser models.User
 	database.DB.First(&removedUser, memberUID)
-	var group models.Group
-	database.DB.First(&group, groupID)
+	group := access.Group
+
+	description := fmt.Sprintf("%s left %s", removedUser.Name, group.Name)
+	if isAdminRemoval {
+		var remover models.User
+		database.DB.First(&remover, userID)
+		description = fmt.Sprintf("%s removed %s from %s", remover.Name, removedUser.Name, group.Name)
+	}
 
 	database.DB.Create(&models.Activity{
 		GroupID:     groupID,
 		UserID:      userID,
+		MemberID:    &memberUID,
 		Type:        "member_left",
-		Description: fmt.Sprintf("%s left %s", removedUser.Name, group.Name),
+		ReferenceID: memberUID,
+		Description: description,
 	})
 
 	utils.SuccessResponse(c, http.StatusOK, "Member removed", nil)
@@ -301,8 +494,7 @@ func InviteToGroupHandler(c *gin.Context) {
 		return
 	}
 
-	if !isMember(groupID, userID) {
-		utils.Unauthorized(c, "You are not a member of this group")
+	if _, ok := authorize(c, groupID, models.PermInvite); !ok {
 		return
 	}
 
@@ -317,47 +509,442 @@ func InviteToGroupHandler(c *gin.Context) {
 		return
 	}
 
-	go services.InviteToGroup(groupID, userID, req.Email, req.Phone)
+	services.InviteToGroup(groupID, userID, req.Email, req.Phone)
 
 	utils.SuccessResponse(c, http.StatusOK, "Invitation sent", nil)
 }
 
-// Helper: check group membership
-func isMember(groupID, userID uuid.UUID) bool {
-	var count int64
-	database.DB.Model(&models.GroupMember{}).Where("group_id = ? AND user_id = ?", groupID, userID).Count(&count)
-	return count > 0
+// PUT /api/groups/:id/members/:uid/role
+func UpdateMemberRole(c *gin.Context) {
+	userID := utils.GetCurrentUserID(c)
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid group ID")
+		return
+	}
+
+	memberUID, err := uuid.Parse(c.Param("uid"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid user ID")
+		return
+	}
+
+	access, ok := authorize(c, groupID, models.PermManageRoles)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateMemberRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	var target models.GroupMember
+	if err := database.DB.Where("group_id = ? AND user_id = ?", groupID, memberUID).First(&target).Error; err != nil || !target.IsActive() {
+		utils.NotFound(c, "Member not found")
+		return
+	}
+
+	// You can only manage people below you, and only grant roles up to your own
+	actorRank := models.RoleRank(access.Member.Role)
+	if memberUID == userID || models.RoleRank(target.Role) >= actorRank || models.RoleRank(req.Role) > actorRank {
+		utils.Forbidden(c, "You cannot change this member's role")
+		return
+	}
+
+	database.DB.Model(&models.GroupMember{}).
+		Where("group_id = ? AND user_id = ?", groupID, memberUID).
+		Update("role", req.Role)
+
+	var actor, targetUser models.User
+	database.DB.First(&actor, userID)
+	database.DB.First(&targetUser, memberUID)
+
+	database.DB.Create(&models.Activity{
+		GroupID:     groupID,
+		UserID:      userID,
+		Type:        "role_changed",
+		ReferenceID: memberUID,
+		Description: fmt.Sprintf("%s made %s %s", actor.Name, targetUser.Name, req.Role),
+	})
+
+	response := buildGroupResponse(groupID)
+	utils.SuccessResponse(c, http.StatusOK, "Role updated", response)
+}
+
+// POST /api/groups/:id/transfer-ownership
+func TransferOwnership(c *gin.Context) {
+	userID := utils.GetCurrentUserID(c)
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid group ID")
+		return
+	}
+
+	access, ok := authorize(c, groupID, models.PermTransferOwnership)
+	if !ok {
+		return
+	}
+
+	var req models.TransferOwnershipRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	newOwnerID, err := uuid.Parse(req.UserID)
+	if err != nil || newOwnerID == userID {
+		utils.BadRequest(c, "Invalid user ID")
+		return
+	}
+
+	var target models.GroupMember
+	if err := database.DB.Where("group_id = ? AND user_id = ?", groupID, newOwnerID).First(&target).Error; err != nil || !target.IsActive() {
+		utils.NotFound(c, "Member not found")
+		return
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.GroupMember{}).
+			Where("group_id = ? AND user_id = ?", groupID, userID).
+			Update("role", models.RoleAdmin).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.GroupMember{}).
+			Where("group_id = ? AND user_id = ?", groupID, newOwnerID).
+			Update("role", models.RoleOwner).Error
+	})
+	if err != nil {
+		utils.InternalError(c, "Failed to transfer ownership")
+		return
+	}
+
+	var oldOwner, newOwner models.User
+	database.DB.First(&oldOwner, userID)
+	database.DB.First(&newOwner, newOwnerID)
+
+	database.DB.Create(&models.Activity{
+		GroupID:     groupID,
+		UserID:      userID,
+		Type:        "ownership_transferred",
+		ReferenceID: newOwnerID,
+		Description: fmt.Sprintf("%s transferred ownership of %s to %s", oldOwner.Name, access.Group.Name, newOwner.Name),
+	})
+
+	response := buildGroupResponse(groupID)
+	utils.SuccessResponse(c, http.StatusOK, "Ownership transferred", response)
+}
+
+// POST /api/groups/:id/archive
+func ArchiveGroup(c *gin.Context) {
+	userID := utils.GetCurrentUserID(c)
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid group ID")
+		return
+	}
+
+	access, ok := authorize(c, groupID, models.PermArchiveGroup)
+	if !ok {
+		return
+	}
+
+	if access.Group.IsArchived() {
+		utils.BadRequest(c, "Group is already archived")
+		return
+	}
+
+	var archiver models.User
+	database.DB.First(&archiver, userID)
+	archiveGroup(groupID, userID, fmt.Sprintf("%s archived %s", archiver.Name, access.Group.Name))
+
+	response := buildGroupResponse(groupID)
+	utils.SuccessResponse(c, http.StatusOK, "Group archived", response)
+}
+
+// POST /api/groups/:id/unarchive — also cancels a pending close
+func UnarchiveGroup(c *gin.Context) {
+	userID := utils.GetCurrentUserID(c)
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid group ID")
+		return
+	}
+
+	access, ok := authorize(c, groupID, models.PermArchiveGroup)
+	if !ok {
+		return
+	}
+
+	if !access.Group.IsArchived() && access.Group.ClosingAt == nil {
+		utils.BadRequest(c, "Group is not archived")
+		return
+	}
+
+	database.DB.Model(&models.Group{}).Where("id = ?", groupID).Updates(map[string]interface{}{
+		"archived_at": nil,
+		"closing_at":  nil,
+	})
+
+	var restorer models.User
+	database.DB.First(&restorer, userID)
+	database.DB.Create(&models.Activity{
+		GroupID:     groupID,
+		UserID:      userID,
+		Type:        "group_restored",
+		ReferenceID: groupID,
+		Description: fmt.Sprintf("%s restored %s", restorer.Name, access.Group.Name),
+	})
+
+	response := buildGroupResponse(groupID)
+	utils.SuccessResponse(c, http.StatusOK, "Group restored", response)
+}
+
+// DELETE /api/groups/:id — permanently deletes a fully settled group
+func DeleteGroup(c *gin.Context) {
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid group ID")
+		return
+	}
+
+	if _, ok := authorize(c, groupID, models.PermDeleteGroup); !ok {
+		return
+	}
+
+	if !isGroupSettled(groupID) {
+		utils.Conflict(c, "Settle all balances before deleting the group", simplifyDebts(calculateNetBalances(groupID)))
+		return
+	}
+
+	// Receipt files live outside the database; remember them so they can be removed afterwards
+	var withReceipts []models.Expense
+	database.DB.Where("group_id = ? AND receipt_key <> ''", groupID).Find(&withReceipts)
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		expenseIDs := tx.Model(&models.Expense{}).Select("id").Where("group_id = ?", groupID)
+		if err := tx.Where("expense_id IN (?)", expenseIDs).Delete(&models.ExpenseSplit{}).Error; err != nil {
+			return err
+		}
+
+		hookIDs := tx.Model(&models.Webhook{}).Select("id").Where("group_id = ?", groupID)
+		if err := tx.Where("webhook_id IN (?)", hookIDs).Delete(&models.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+
+		for _, model := range []interface{}{
+			&models.Webhook{},
+			&models.Expense{},
+			&models.Settlement{},
+			&models.Activity{},
+			&models.Invitation{},
+			&models.InviteLink{},
+			&models.OCRJob{},
+			&models.Reaction{},
+			&models.Comment{},
+			&models.NotificationPreference{},
+			&models.Notification{},
+			&models.GroupMember{},
+		} {
+			if err := tx.Where("group_id = ?", groupID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Delete(&models.Group{}, groupID).Error
+	})
+	if err != nil {
+		utils.InternalError(c, "Failed to delete group")
+		return
+	}
+
+	for _, e := range withReceipts {
+		go services.DeleteReceipt(e)
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Group deleted", nil)
+}
+
+// POST /api/groups/:id/close — "close trip": compute the final transfers, notify everyone,
+// and archive the group automatically once all of them have been recorded
+func CloseGroup(c *gin.Context) {
+	userID := utils.GetCurrentUserID(c)
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid group ID")
+		return
+	}
+
+	access, ok := authorize(c, groupID, models.PermArchiveGroup)
+	if !ok {
+		return
+	}
+
+	var closer models.User
+	database.DB.First(&closer, userID)
+
+	transfers := simplifyDebts(calculateNetBalances(groupID))
+	if len(transfers) == 0 {
+		archiveGroup(groupID, userID, fmt.Sprintf("%s closed %s, everyone was already settled", closer.Name, access.Group.Name))
+		response := buildGroupResponse(groupID)
+		utils.SuccessResponse(c, http.StatusOK, "Group settled and archived", response)
+		return
+	}
+
+	var members []models.User
+	database.DB.Where("id IN (?)", database.DB.Model(&models.GroupMember{}).Select("user_id").Where("group_id = ?", groupID)).Find(&members)
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Group{}).Where("id = ?", groupID).Update("closing_at", time.Now()).Error; err != nil {
+			return err
+		}
+
+		err := tx.Create(&models.Activity{
+			GroupID:     groupID,
+			UserID:      userID,
+			Type:        "group_closing",
+			ReferenceID: groupID,
+			Description: fmt.Sprintf("%s closed %s, %d payments left to settle up", closer.Name, access.Group.Name, len(transfers)),
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		return services.GetNotificationService().NotifyGroupClosing(tx, access.Group, closer, members, transfers)
+	})
+	if err != nil {
+		utils.InternalError(c, "Failed to close group")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Group closing, waiting for final payments", transfers)
+}
+
+// Helper: archive a group and log it
+func archiveGroup(groupID uuid.UUID, userID uuid.UUID, description string) {
+	database.DB.Model(&models.Group{}).Where("id = ?", groupID).Updates(map[string]interface{}{
+		"archived_at": time.Now(),
+		"closing_at":  nil,
+	})
+
+	database.DB.Create(&models.Activity{
+		GroupID:     groupID,
+		UserID:      userID,
+		Type:        "group_archived",
+		ReferenceID: groupID,
+		Description: description,
+	})
+}
+
+// Helper: a closing trip archives itself once the last balance is settled, whether by a
+// final transfer or by correcting or removing an expense
+func archiveIfSettled(group models.Group, userID uuid.UUID) {
+	if group.ClosingAt != nil && isGroupSettled(group.ID) {
+		archiveGroup(group.ID, userID, fmt.Sprintf("Everyone is settled up, %s was archived", group.Name))
+	}
+}
+
+// Helper: default weights must belong to active members and fit the default split type
+func validateDefaultSplits(groupID uuid.UUID, settings models.GroupSettings) error {
+	if len(settings.DefaultSplits) == 0 {
+		return nil
+	}
+	if settings.DefaultSplitType != "percentage" && settings.DefaultSplitType != "shares" {
+		return fmt.Errorf("default splits only apply to percentage or shares split types")
+	}
+
+	var members []models.GroupMember
+	database.DB.Where("group_id = ? AND status = ?", groupID, models.MemberActive).Find(&members)
+	active := map[uuid.UUID]bool{}
+	for _, m := range members {
+		active[m.UserID] = true
+	}
+
+	seen := map[uuid.UUID]bool{}
+	var total float64
+	for _, s := range settings.DefaultSplits {
+		uid, err := uuid.Parse(s.UserID)
+		if err != nil {
+			return fmt.Errorf("invalid user ID: %s", s.UserID)
+		}
+		if !active[uid] {
+			return fmt.Errorf("user %s is not an active member of this group", s.UserID)
+		}
+		if seen[uid] {
+			return fmt.Errorf("user %s appears more than once", s.UserID)
+		}
+		if s.Value < 0 {
+			return fmt.Errorf("split values cannot be negative")
+		}
+		seen[uid] = true
+		total += s.Value
+	}
+
+	if settings.DefaultSplitType == "percentage" && utils.RoundToTwo(total) != 100.0 {
+		return fmt.Errorf("percentages must add up to 100, got %.2f", total)
+	}
+	if settings.DefaultSplitType == "shares" && total <= 0 {
+		return fmt.Errorf("total shares must be greater than 0")
+	}
+	return nil
 }
 
 // Helper: build full group response with members
 func buildGroupResponse(groupID uuid.UUID) models.GroupResponse {
 	var group models.Group
-	database.DB.First(&group, groupID)
+	if err := database.DB.First(&group, groupID).Error; err != nil {
+		return models.GroupResponse{}
+	}
+	return buildGroupResponses([]models.Group{group})[0]
+}
+
+// Helper: build responses for several groups, loading members with their users in two queries
+func buildGroupResponses(groups []models.Group) []models.GroupResponse {
+	responses := make([]models.GroupResponse, 0, len(groups))
+	if len(groups) == 0 {
+		return responses
+	}
+
+	groupIDs := make([]uuid.UUID, 0, len(groups))
+	for _, g := range groups {
+		groupIDs = append(groupIDs, g.ID)
+	}
 
 	var members []models.GroupMember
-	database.DB.Where("group_id = ?", groupID).Find(&members)
+	database.DB.Preload("User").Where("group_id IN ?", groupIDs).Find(&members)
 
-	var memberResponses []models.GroupMemberResponse
+	membersByGroup := make(map[uuid.UUID][]models.GroupMemberResponse)
 	for _, m := range members {
-		var user models.User
-		database.DB.First(&user, m.UserID)
-		memberResponses = append(memberResponses, models.GroupMemberResponse{
-			UserID:    user.ID,
-			Name:      user.Name,
-			Email:     user.Email,
-			AvatarURL: user.AvatarURL,
+		membersByGroup[m.GroupID] = append(membersByGroup[m.GroupID], models.GroupMemberResponse{
+			UserID:    m.User.ID,
+			Name:      m.User.Name,
+			Email:     m.User.Email,
+			AvatarURL: m.User.AvatarURL,
 			Role:      m.Role,
+			Status:    m.Status,
 			JoinedAt:  m.JoinedAt,
+			LeftAt:    m.LeftAt,
 		})
 	}
 
-	return models.GroupResponse{
-		ID:        group.ID,
-		Name:      group.Name,
-		Type:      group.Type,
-		ImageURL:  group.ImageURL,
-		CreatedBy: group.CreatedBy,
-		Members:   memberResponses,
-		CreatedAt: group.CreatedAt,
+	for _, group := range groups {
+		responses = append(responses, models.GroupResponse{
+			ID:                group.ID,
+			Name:              group.Name,
+			Type:              group.Type,
+			ImageURL:          group.ImageURL,
+			CreatedBy:         group.CreatedBy,
+			Members:           membersByGroup[group.ID],
+			ExpenseEditPolicy: group.ExpenseEditPolicy,
+			MemberAddPolicy:   group.MemberAddPolicy,
+			JoinPolicy:        group.JoinPolicy,
+			ArchivedAt:        group.ArchivedAt,
+			ClosingAt:         group.ClosingAt,
+			Settings:          group.Settings,
+			Version:           group.Version,
+			CreatedAt:         group.CreatedAt,
+		})
 	}
+	return responses
 }
@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"net/http"
+	"splitwise-backend/database"
+	"splitwise-backend/models"
+	"splitwise-backend/services"
+	"splitwise-backend/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// GET /api/invitations — pending invitations addressed to the current user
+func GetMyInvitations(c *gin.Context) {
+	userID := utils.GetCurrentUserID(c)
+
+	var user models.User
+	if err := database.DB.First(&user, userID).Error; err != nil {
+		utils.NotFound(c, "User not found")
+		return
+	}
+
+	responses := []models.InvitationResponse{}
+	for _, inv := range services.PendingInvitationsFor(user) {
+		responses = append(responses, inv.ToResponse())
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "", responses)
+}
+
+// POST /api/invitations/:id/accept
+func AcceptInvitation(c *gin.Context) {
+	inv, user, ok := loadOwnInvitation(c)
+	if !ok {
+		return
+	}
+
+	if err := services.AcceptInvitation(inv, user); err != nil {
+		if err == services.ErrInvitationNotPending {
+			utils.BadRequest(c, "Invitation is no longer pending")
+			return
+		}
+		utils.InternalError(c, "Failed to accept invitation")
+		return
+	}
+
+	response := buildGroupResponse(inv.GroupID)
+	utils.SuccessResponse(c, http.StatusOK, "Invitation accepted", response)
+}
+
+// POST /api/invitations/:id/decline
+func DeclineInvitation(c *gin.Context) {
+	inv, user, ok := loadOwnInvitation(c)
+	if !ok {
+		return
+	}
+
+	if err := services.DeclineInvitation(inv, user); err != nil {
+		if err == services.ErrInvitationNotPending {
+			utils.BadRequest(c, "Invitation is no longer pending")
+			return
+		}
+		utils.InternalError(c, "Failed to decline invitation")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Invitation declined", nil)
+}
+
+// GET /api/groups/:id/invitations — invitations sent for a group (?status=all to include answered ones)
+func GetGroupInvitations(c *gin.Context) {
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid group ID")
+		return
+	}
+
+	if _, ok := authorize(c, groupID, models.PermInvite); !ok {
+		return
+	}
+
+	services.ExpireStaleInvitations()
+
+	query := database.DB.Preload("Group").Preload("Inviter").Where("group_id = ?", groupID)
+	if status := c.DefaultQuery("status", "pending"); status != "all" {
+		query = query.Where("status = ?", status)
+	}
+
+	var invitations []models.Invitation
+	query.Order("created_at DESC").Find(&invitations)
+
+	responses := []models.InvitationResponse{}
+	for _, inv := range invitations {
+		responses = append(responses, inv.ToResponse())
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "", responses)
+}
+
+// DELETE /api/groups/:id/invitations/:invId — the inviter or an admin can cancel
+func CancelInvitation(c *gin.Context) {
+	userID := utils.GetCurrentUserID(c)
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid group ID")
+		return
+	}
+
+	invID, err := uuid.Parse(c.Param("invId"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid invitation ID")
+		return
+	}
+
+	access, ok := authorize(c, groupID, models.PermInvite)
+	if !ok {
+		return
+	}
+
+	var inv models.Invitation
+	if err := database.DB.Where("id = ? AND group_id = ?", invID, groupID).First(&inv).Error; err != nil {
+		utils.NotFound(c, "Invitation not found")
+		return
+	}
+
+	if inv.InvitedBy != userID && !models.RoleAtLeast(access.Member.Role, models.RoleAdmin) {
+		utils.Forbidden(c, "Only the inviter or an admin can cancel this invitation")
+		return
+	}
+
+	result := database.DB.Model(&models.Invitation{}).
+		Where("id = ? AND status = ?", inv.ID, "pending").
+		Updates(map[string]interface{}{"status": "cancelled", "responded_at": time.Now()})
+	if result.RowsAffected == 0 {
+		utils.BadRequest(c, "Invitation is no longer pending")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Invitation cancelled", nil)
+}
+
+// Helper: load :id and make sure it is addressed to the current user
+func loadOwnInvitation(c *gin.Context) (models.Invitation, models.User, bool) {
+	userID := utils.GetCurrentUserID(c)
+
+	var inv models.Invitation
+	var user models.User
+
+	invID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid invitation ID")
+		return inv, user, false
+	}
+
+	if err := database.DB.First(&user, userID).Error; err != nil {
+		utils.NotFound(c, "User not found")
+		return inv, user, false
+	}
+
+	services.ExpireStaleInvitations()
+
+	if err := database.DB.Where("id = ?", invID).Scopes(services.InvitationsAddressedTo(user)).First(&inv).Error; err != nil {
+		utils.NotFound(c, "Invitation not found")
+		return inv, user, false
+	}
+
+	return inv, user, true
+}
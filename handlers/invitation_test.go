@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"splitwise-backend/database"
+	"splitwise-backend/models"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// Helper: a pending invitation to group, as InviteToGroup leaves it for a consent group
+func createTestInvitation(t *testing.T, group models.Group, inviter models.User, inv models.Invitation) models.Invitation {
+	t.Helper()
+
+	inv.GroupID, inv.InvitedBy, inv.Status = group.ID, inviter.ID, "pending"
+	if err := database.DB.Create(&inv).Error; err != nil {
+		t.Fatalf("create invitation: %v", err)
+	}
+	return inv
+}
+
+func myInvitations(t *testing.T, user models.User) map[uuid.UUID]models.InvitationResponse {
+	t.Helper()
+
+	w := serveAs(user, GetMyInvitations, http.MethodGet, "/api/invitations", "/api/invitations", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("list invitations: %d %s", w.Code, w.Body.String())
+	}
+	var list []models.InvitationResponse
+	decodeData(t, w, &list)
+
+	byID := map[uuid.UUID]models.InvitationResponse{}
+	for _, inv := range list {
+		byID[inv.ID] = inv
+	}
+	return byID
+}
+
+func answerInvitation(user models.User, inv models.Invitation, answer string) int {
+	path := fmt.Sprintf("/api/invitations/%s/%s", inv.ID, answer)
+	handler := AcceptInvitation
+	if answer == "decline" {
+		handler = DeclineInvitation
+	}
+	return serveAs(user, handler, http.MethodPost, "/api/invitations/:id/"+answer, path, nil).Code
+}
+
+func TestInvitationInbox(t *testing.T) {
+	requireDB(t)
+
+	t.Run("lists invitations by account or email, not by phone", func(t *testing.T) {
+		admin, invitee := createTestUser(t, "Alice"), createTestUser(t, "Bob")
+		phone := fmt.Sprintf("+9198%08d", rand.Intn(100000000))
+		setPhone(t, &invitee, phone)
+		group := createTestGroup(t, admin)
+
+		byAccount := createTestInvitation(t, group, admin, models.Invitation{InviteeID: &invitee.ID})
+		byEmail := createTestInvitation(t, group, admin, models.Invitation{Email: " " + strings.ToUpper(invitee.Email)})
+		byPhone := createTestInvitation(t, group, admin, models.Invitation{Phone: phone})
+
+		inbox := myInvitations(t, invitee)
+		if _, ok := inbox[byAccount.ID]; !ok {
+			t.Error("invitation to the account is missing")
+		}
+		if _, ok := inbox[byEmail.ID]; !ok {
+			t.Error("invitation to the email, differently cased, is missing")
+		}
+		if _, ok := inbox[byPhone.ID]; ok {
+			t.Error("invitation to an unverified phone number is listed")
+		}
+		if code := answerInvitation(invitee, byPhone, "accept"); code != http.StatusNotFound {
+			t.Errorf("accept by phone = %d, want 404", code)
+		}
+	})
+
+	t.Run("only the addressee can accept", func(t *testing.T) {
+		admin, invitee, other := createTestUser(t, "Alice"), createTestUser(t, "Bob"), createTestUser(t, "Carol")
+		group := createTestGroup(t, admin)
+		inv := createTestInvitation(t, group, admin, models.Invitation{Email: invitee.Email})
+
+		if _, ok := myInvitations(t, other)[inv.ID]; ok {
+			t.Error("invitation listed for someone else")
+		}
+		if code := answerInvitation(other, inv, "accept"); code != http.StatusNotFound {
+			t.Errorf("accept by someone else = %d, want 404", code)
+		}
+		if isActiveMember(t, group, other) {
+			t.Error("someone else joined through the invitation")
+		}
+	})
+
+	t.Run("accepting twice is rejected", func(t *testing.T) {
+		admin, invitee := createTestUser(t, "Alice"), createTestUser(t, "Bob")
+		group := createTestGroup(t, admin)
+		inv := createTestInvitation(t, group, admin, models.Invitation{Email: invitee.Email})
+
+		if code := answerInvitation(invitee, inv, "accept"); code != http.StatusOK {
+			t.Fatalf("accept = %d, want 200", code)
+		}
+		if !isActiveMember(t, group, invitee) {
+			t.Error("invitee is not a member after accepting")
+		}
+		if code := answerInvitation(invitee, inv, "accept"); code != http.StatusBadRequest {
+			t.Errorf("second accept = %d, want 400", code)
+		}
+		if code := answerInvitation(invitee, inv, "decline"); code != http.StatusBadRequest {
+			t.Errorf("decline after accepting = %d, want 400", code)
+		}
+		if _, ok := myInvitations(t, invitee)[inv.ID]; ok {
+			t.Error("accepted invitation still pending")
+		}
+	})
+
+	t.Run("declining adds no member", func(t *testing.T) {
+		admin, invitee := createTestUser(t, "Alice"), createTestUser(t, "Bob")
+		group := createTestGroup(t, admin)
+		inv := createTestInvitation(t, group, admin, models.Invitation{Email: invitee.Email})
+
+		if code := answerInvitation(invitee, inv, "decline"); code != http.StatusOK {
+			t.Fatalf("decline = %d, want 200", code)
+		}
+		var members int64
+		database.DB.Model(&models.GroupMember{}).Where("group_id = ? AND user_id = ?", group.ID, invitee.ID).Count(&members)
+		if members != 0 {
+			t.Error("declining added a membership")
+		}
+		var status string
+		database.DB.Model(&models.Invitation{}).Where("id = ?", inv.ID).Pluck("status", &status)
+		if status != "declined" {
+			t.Errorf("status = %q, want declined", status)
+		}
+		if code := answerInvitation(invitee, inv, "accept"); code != http.StatusBadRequest {
+			t.Errorf("accept after declining = %d, want 400", code)
+		}
+	})
+}
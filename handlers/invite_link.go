@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"splitwise-backend/config"
+	"splitwise-backend/database"
+	"splitwise-backend/models"
+	"splitwise-backend/services"
+	"splitwise-backend/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// POST /api/groups/:id/invite-links
+func CreateInviteLink(c *gin.Context) {
+	userID := utils.GetCurrentUserID(c)
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid group ID")
+		return
+	}
+
+	if _, ok := authorize(c, groupID, models.PermManageInviteLinks); !ok {
+		return
+	}
+
+	var req models.CreateInviteLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	token, err := utils.RandomToken(24)
+	if err != nil {
+		utils.InternalError(c, "Failed to generate invite link")
+		return
+	}
+
+	link := models.InviteLink{
+		GroupID:   groupID,
+		Token:     token,
+		CreatedBy: userID,
+		MaxUses:   req.MaxUses,
+	}
+	if req.ExpiresInHours > 0 {
+		expiresAt := time.Now().Add(time.Duration(req.ExpiresInHours) * time.Hour)
+		link.ExpiresAt = &expiresAt
+	}
+
+	if err := database.DB.Create(&link).Error; err != nil {
+		utils.InternalError(c, "Failed to create invite link")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, "Invite link created", toInviteLinkResponse(link))
+}
+
+// GET /api/groups/:id/invite-links
+func GetInviteLinks(c *gin.Context) {
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid group ID")
+		return
+	}
+
+	if _, ok := authorize(c, groupID, models.PermManageInviteLinks); !ok {
+		return
+	}
+
+	var links []models.InviteLink
+	database.DB.Where("group_id = ?", groupID).Order("created_at DESC").Find(&links)
+
+	responses := []models.InviteLinkResponse{}
+	for _, l := range links {
+		responses = append(responses, toInviteLinkResponse(l))
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "", responses)
+}
+
+// DELETE /api/groups/:id/invite-links/:linkId
+func RevokeInviteLink(c *gin.Context) {
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid group ID")
+		return
+	}
+
+	linkID, err := uuid.Parse(c.Param("linkId"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid invite link ID")
+		return
+	}
+
+	if _, ok := authorize(c, groupID, models.PermManageInviteLinks); !ok {
+		return
+	}
+
+	result := database.DB.Model(&models.InviteLink{}).
+		Where("id = ? AND group_id = ? AND revoked_at IS NULL", linkID, groupID).
+		Update("revoked_at", time.Now())
+	if result.RowsAffected == 0 {
+		utils.NotFound(c, "Invite link not found")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Invite link revoked", nil)
+}
+
+// GET /invites/:token — public preview, no authentication
+func PreviewInvite(c *gin.Context) {
+	link, ok := findUsableInviteLink(c)
+	if !ok {
+		return
+	}
+
+	var group models.Group
+	database.DB.First(&group, link.GroupID)
+	var inviter models.User
+	database.DB.First(&inviter, link.CreatedBy)
+
+	var memberCount int64
+	database.DB.Model(&models.GroupMember{}).Where("group_id = ? AND status = ?", link.GroupID, models.MemberActive).Count(&memberCount)
+
+	utils.SuccessResponse(c, http.StatusOK, "", models.InvitePreview{
+		GroupName:   group.Name,
+		GroupType:   group.Type,
+		ImageURL:    group.ImageURL,
+		InviterName: inviter.Name,
+		MemberCount: memberCount,
+		ExpiresAt:   link.ExpiresAt,
+	})
+}
+
+// POST /api/invites/:token/join
+func JoinViaInviteLink(c *gin.Context) {
+	userID := utils.GetCurrentUserID(c)
+
+	link, ok := findUsableInviteLink(c)
+	if !ok {
+		return
+	}
+
+	var existing models.GroupMember
+	if err := database.DB.Where("group_id = ? AND user_id = ?", link.GroupID, userID).First(&existing).Error; err == nil && existing.IsActive() {
+		utils.BadRequest(c, "You are already a member of this group")
+		return
+	}
+
+	var user, inviter models.User
+	database.DB.First(&user, userID)
+	database.DB.First(&inviter, link.CreatedBy)
+	var group models.Group
+	database.DB.First(&group, link.GroupID)
+
+	// Links outlive the group's active life; nobody joins once it's archived or closing
+	if group.IsArchived() || group.ClosingAt != nil {
+		utils.Conflict(c, "This group is archived or closing and can't take new members", nil)
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		// Claim a use atomically so concurrent joins can't exceed max_uses
+		result := tx.Model(&models.InviteLink{}).
+			Where("id = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?) AND (max_uses = 0 OR uses < max_uses)", link.ID, time.Now()).
+			Update("uses", gorm.Expr("uses + 1"))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errInviteLinkUnusable
+		}
+
+		if _, err := services.JoinGroup(tx, link.GroupID, userID); err != nil {
+			return err
+		}
+
+		return tx.Create(&models.Activity{
+			GroupID:     link.GroupID,
+			UserID:      userID,
+			MemberID:    &userID,
+			Type:        "member_joined",
+			ReferenceID: link.ID,
+			Description: fmt.Sprintf("%s joined %s via an invite link from %s", user.Name, group.Name, inviter.Name),
+		}).Error
+	})
+	if err == errInviteLinkUnusable {
+		utils.ErrorResponse(c, http.StatusGone, "This invite link has expired or reached its limit")
+		return
+	}
+	if err != nil {
+		utils.InternalError(c, "Failed to join group")
+		return
+	}
+
+	response := buildGroupResponse(link.GroupID)
+	utils.SuccessResponse(c, http.StatusOK, "Joined group", response)
+}
+
+var errInviteLinkUnusable = errors.New("invite link is no longer usable")
+
+// Helper: look up the link in :token and reject it if revoked, expired or used up
+func findUsableInviteLink(c *gin.Context) (models.InviteLink, bool) {
+	var link models.InviteLink
+	if err := database.DB.Where("token = ?", c.Param("token")).First(&link).Error; err != nil {
+		utils.NotFound(c, "Invite link not found")
+		return link, false
+	}
+
+	if !link.IsUsable(time.Now()) {
+		utils.ErrorResponse(c, http.StatusGone, "This invite link has expired or reached its limit")
+		return link, false
+	}
+
+	return link, true
+}
+
+func toInviteLinkResponse(link models.InviteLink) models.InviteLinkResponse {
+	return models.InviteLinkResponse{
+		InviteLink: link,
+		URL:        fmt.Sprintf("%s/invites/%s", config.AppConfig.AppURL, link.Token),
+	}
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"net/http"
+	"splitwise-backend/database"
+	"splitwise-backend/models"
+	"testing"
+	"time"
+)
+
+// Helper: create a link through the API as the group's admin
+func createInviteLink(t *testing.T, admin models.User, group models.Group, req models.CreateInviteLinkRequest) models.InviteLinkResponse {
+	t.Helper()
+
+	w := serveAs(admin, CreateInviteLink, http.MethodPost, "/api/groups/:id/invite-links", "/api/groups/"+group.ID.String()+"/invite-links", req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create invite link: %d %s", w.Code, w.Body.String())
+	}
+	var link models.InviteLinkResponse
+	decodeData(t, w, &link)
+	return link
+}
+
+func joinViaLink(user models.User, token string) int {
+	return serveAs(user, JoinViaInviteLink, http.MethodPost, "/api/invites/:token/join", "/api/invites/"+token+"/join", nil).Code
+}
+
+func isActiveMember(t *testing.T, group models.Group, user models.User) bool {
+	t.Helper()
+
+	var member models.GroupMember
+	err := database.DB.Where("group_id = ? AND user_id = ?", group.ID, user.ID).First(&member).Error
+	return err == nil && member.IsActive()
+}
+
+func TestJoinViaInviteLink(t *testing.T) {
+	requireDB(t)
+
+	t.Run("joins once and counts the use", func(t *testing.T) {
+		admin, joiner := createTestUser(t, "Alice"), createTestUser(t, "Bob")
+		group := createTestGroup(t, admin)
+		link := createInviteLink(t, admin, group, models.CreateInviteLinkRequest{})
+
+		if code := joinViaLink(joiner, link.Token); code != http.StatusOK {
+			t.Fatalf("join = %d, want 200", code)
+		}
+		if !isActiveMember(t, group, joiner) {
+			t.Error("joiner is not an active member")
+		}
+		if code := joinViaLink(joiner, link.Token); code != http.StatusBadRequest {
+			t.Errorf("second join = %d, want 400", code)
+		}
+		var uses int
+		database.DB.Model(&models.InviteLink{}).Where("id = ?", link.ID).Pluck("uses", &uses)
+		if uses != 1 {
+			t.Errorf("uses = %d, want 1", uses)
+		}
+	})
+
+	t.Run("expired link is gone", func(t *testing.T) {
+		admin, joiner := createTestUser(t, "Alice"), createTestUser(t, "Bob")
+		group := createTestGroup(t, admin)
+		link := createInviteLink(t, admin, group, models.CreateInviteLinkRequest{ExpiresInHours: 1})
+		database.DB.Model(&models.InviteLink{}).Where("id = ?", link.ID).Update("expires_at", time.Now().Add(-time.Minute))
+
+		if code := joinViaLink(joiner, link.Token); code != http.StatusGone {
+			t.Errorf("join = %d, want 410", code)
+		}
+		if isActiveMember(t, group, joiner) {
+			t.Error("joined through an expired link")
+		}
+	})
+
+	t.Run("max uses are enforced", func(t *testing.T) {
+		admin, first, second := createTestUser(t, "Alice"), createTestUser(t, "Bob"), createTestUser(t, "Carol")
+		group := createTestGroup(t, admin)
+		link := createInviteLink(t, admin, group, models.CreateInviteLinkRequest{MaxUses: 1})
+
+		if code := joinViaLink(first, link.Token); code != http.StatusOK {
+			t.Fatalf("first join = %d, want 200", code)
+		}
+		if code := joinViaLink(second, link.Token); code != http.StatusGone {
+			t.Errorf("join past max uses = %d, want 410", code)
+		}
+		if isActiveMember(t, group, second) {
+			t.Error("joined through a used-up link")
+		}
+	})
+
+	t.Run("revoked link is gone", func(t *testing.T) {
+		admin, joiner := createTestUser(t, "Alice"), createTestUser(t, "Bob")
+		group := createTestGroup(t, admin)
+		link := createInviteLink(t, admin, group, models.CreateInviteLinkRequest{})
+
+		path := "/api/groups/" + group.ID.String() + "/invite-links/" + link.ID.String()
+		if w := serveAs(admin, RevokeInviteLink, http.MethodDelete, "/api/groups/:id/invite-links/:linkId", path, nil); w.Code != http.StatusOK {
+			t.Fatalf("revoke: %d %s", w.Code, w.Body.String())
+		}
+		if code := joinViaLink(joiner, link.Token); code != http.StatusGone {
+			t.Errorf("join = %d, want 410", code)
+		}
+		if isActiveMember(t, group, joiner) {
+			t.Error("joined through a revoked link")
+		}
+	})
+
+	for _, column := range []string{"archived_at", "closing_at"} {
+		t.Run("no joins once "+column+" is set", func(t *testing.T) {
+			admin, joiner := createTestUser(t, "Alice"), createTestUser(t, "Bob")
+			group := createTestGroup(t, admin)
+			link := createInviteLink(t, admin, group, models.CreateInviteLinkRequest{})
+			database.DB.Model(&models.Group{}).Where("id = ?", group.ID).Update(column, time.Now())
+
+			if code := joinViaLink(joiner, link.Token); code != http.StatusConflict {
+				t.Errorf("join = %d, want 409", code)
+			}
+			if isActiveMember(t, group, joiner) {
+				t.Error("joined a group that no longer takes members")
+			}
+			var uses int
+			database.DB.Model(&models.InviteLink{}).Where("id = ?", link.ID).Pluck("uses", &uses)
+			if uses != 0 {
+				t.Errorf("uses = %d, want the refused join not to count", uses)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"net/http"
+	"splitwise-backend/database"
+	"splitwise-backend/models"
+	"splitwise-backend/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// GET /api/notifications — the inbox, newest first; ?unread=true for unread only
+func GetNotifications(c *gin.Context) {
+	userID := utils.GetCurrentUserID(c)
+
+	pagination, err := utils.BindPagination(c)
+	if err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	scope := func(db *gorm.DB) *gorm.DB {
+		db = db.Where("user_id = ?", userID)
+		if c.Query("unread") == "true" {
+			db = db.Where("read_at IS NULL")
+		}
+		return db
+	}
+
+	var total int64
+	database.DB.Model(&models.Notification{}).Scopes(scope).Count(&total)
+
+	response := models.NotificationListResponse{
+		Notifications: []models.Notification{},
+		UnreadCount:   unreadNotificationCount(userID),
+	}
+	database.DB.Scopes(scope, pagination.Scope(createdAtKeyset)).
+		Order("created_at DESC, id DESC").
+		Find(&response.Notifications)
+
+	fetched := len(response.Notifications)
+	if fetched > pagination.Limit {
+		response.Notifications = response.Notifications[:pagination.Limit]
+	}
+
+	var last utils.Cursor
+	if n := len(response.Notifications); n > 0 {
+		last = utils.Cursor{CreatedAt: response.Notifications[n-1].CreatedAt, ID: response.Notifications[n-1].ID}
+	}
+	utils.SuccessResponseWithMeta(c, http.StatusOK, "", response, pagination.Meta(total, fetched, last))
+}
+
+// GET /api/notifications/unread-count — for badges
+func GetUnreadNotificationCount(c *gin.Context) {
+	userID := utils.GetCurrentUserID(c)
+	utils.SuccessResponse(c, http.StatusOK, "", gin.H{"unread_count": unreadNotificationCount(userID)})
+}
+
+// POST /api/notifications/read — {"ids": [...]} for one or several, {"all": true} for everything
+func MarkNotificationsRead(c *gin.Context) {
+	userID := utils.GetCurrentUserID(c)
+
+	var req models.MarkNotificationsReadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+	if !req.All && len(req.IDs) == 0 {
+		utils.BadRequest(c, "Pass the notification ids, or all: true")
+		return
+	}
+
+	query := database.DB.Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", userID)
+	if !req.All {
+		ids := make([]uuid.UUID, 0, len(req.IDs))
+		for _, raw := range req.IDs {
+			id, err := uuid.Parse(raw)
+			if err != nil {
+				utils.BadRequest(c, "Invalid notification ID: "+raw)
+				return
+			}
+			ids = append(ids, id)
+		}
+		query = query.Where("id IN ?", ids)
+	}
+
+	result := query.Update("read_at", time.Now())
+	if result.Error != nil {
+		utils.InternalError(c, "Failed to mark notifications read")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Notifications marked read", gin.H{
+		"marked":       result.RowsAffected,
+		"unread_count": unreadNotificationCount(userID),
+	})
+}
+
+func unreadNotificationCount(userID uuid.UUID) int64 {
+	var count int64
+	database.DB.Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Count(&count)
+	return count
+}
@@ -0,0 +1,248 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"splitwise-backend/config"
+	"splitwise-backend/database"
+	"splitwise-backend/models"
+	"splitwise-backend/services"
+	"splitwise-backend/utils"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// GET /api/users/me/notification-preferences
+func GetNotificationPreferences(c *gin.Context) {
+	userID := utils.GetCurrentUserID(c)
+
+	var user models.User
+	if err := database.DB.First(&user, userID).Error; err != nil {
+		utils.NotFound(c, "User not found")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "", buildNotificationPreferences(user))
+}
+
+// PUT /api/users/me/notification-preferences — timezone, quiet hours and default channels per event
+func UpdateNotificationPreferences(c *gin.Context) {
+	userID := utils.GetCurrentUserID(c)
+
+	var req models.UpdateNotificationPreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if req.Timezone != nil {
+		if _, err := time.LoadLocation(*req.Timezone); err != nil || *req.Timezone == "" {
+			utils.BadRequest(c, fmt.Sprintf("Unknown timezone \"%s\"", *req.Timezone))
+			return
+		}
+		updates["timezone"] = *req.Timezone
+	}
+	if req.QuietHours != nil {
+		if err := req.QuietHours.Validate(); err != nil {
+			utils.BadRequest(c, err.Error())
+			return
+		}
+		updates["quiet_enabled"] = req.QuietHours.Enabled
+		updates["quiet_start"] = req.QuietHours.Start
+		updates["quiet_end"] = req.QuietHours.End
+	}
+	if err := validateNotificationEvents(req.Preferences); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if len(updates) > 0 {
+			if err := tx.Model(&models.User{}).Where("id = ?", userID).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+		for _, p := range req.Preferences {
+			if err := services.SetPreference(tx, userID, nil, p); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		utils.InternalError(c, "Failed to update notification preferences")
+		return
+	}
+
+	var user models.User
+	database.DB.First(&user, userID)
+	utils.SuccessResponse(c, http.StatusOK, "Notification preferences updated", buildNotificationPreferences(user))
+}
+
+// GET /api/groups/:id/notification-preferences — what applies in this group; group_id is set on overrides
+func GetGroupNotificationPreferences(c *gin.Context) {
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid group ID")
+		return
+	}
+
+	if _, ok := authorize(c, groupID, models.PermViewGroup); !ok {
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "", groupNotificationPreferences(utils.GetCurrentUserID(c), groupID))
+}
+
+// PUT /api/groups/:id/notification-preferences — override the defaults for one group, e.g. to mute it
+func UpdateGroupNotificationPreferences(c *gin.Context) {
+	userID := utils.GetCurrentUserID(c)
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid group ID")
+		return
+	}
+
+	if _, ok := authorize(c, groupID, models.PermViewGroup); !ok {
+		return
+	}
+
+	var req models.UpdateGroupNotificationsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+	if err := validateNotificationEvents(req.Preferences); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		for _, p := range req.Preferences {
+			if err := services.SetPreference(tx, userID, &groupID, p); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		utils.InternalError(c, "Failed to update notification preferences")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Notification preferences updated", groupNotificationPreferences(userID, groupID))
+}
+
+// DELETE /api/groups/:id/notification-preferences — back to the user's defaults
+func ResetGroupNotificationPreferences(c *gin.Context) {
+	userID := utils.GetCurrentUserID(c)
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid group ID")
+		return
+	}
+
+	if _, ok := authorize(c, groupID, models.PermViewGroup); !ok {
+		return
+	}
+
+	database.DB.Where("user_id = ? AND group_id = ?", userID, groupID).Delete(&models.NotificationPreference{})
+
+	utils.SuccessResponse(c, http.StatusOK, "Notification preferences reset", groupNotificationPreferences(userID, groupID))
+}
+
+// GET /notifications/unsubscribe?token= — confirmation page linked from emails.
+// It only asks: link scanners follow GET links, so the change itself is a POST.
+func UnsubscribePage(c *gin.Context) {
+	token := c.Query("token")
+	if _, _, err := services.ParseUnsubscribeToken(token); err != nil {
+		renderUnsubscribePage(c, http.StatusBadRequest, "This unsubscribe link is invalid.", "")
+		return
+	}
+	renderUnsubscribePage(c, http.StatusOK, "Stop receiving these emails?", token)
+}
+
+// POST /notifications/unsubscribe?token= — the page's button, and one-click unsubscribe from mail clients
+func Unsubscribe(c *gin.Context) {
+	user, eventType, err := services.Unsubscribe(database.DB, c.Query("token"))
+	if errors.Is(err, services.ErrInvalidUnsubscribeToken) {
+		renderUnsubscribePage(c, http.StatusBadRequest, "This unsubscribe link is invalid.", "")
+		return
+	}
+	if err != nil {
+		renderUnsubscribePage(c, http.StatusInternalServerError, "Something went wrong, please try again.", "")
+		return
+	}
+
+	kind := strings.ReplaceAll(eventType, "_", " ")
+	renderUnsubscribePage(c, http.StatusOK, fmt.Sprintf("Done. %s will no longer get %s emails. You can turn them back on in the app.", user.Email, kind), "")
+}
+
+// Helper: every event type with the user's default channels
+func buildNotificationPreferences(user models.User) models.NotificationPreferencesResponse {
+	sets, _ := services.LoadPreferences(database.DB, []uuid.UUID{user.ID})
+	set := sets[user.ID]
+
+	response := models.NotificationPreferencesResponse{
+		Timezone:    user.Location().String(),
+		QuietHours:  user.QuietHours,
+		Preferences: []models.NotificationPreference{},
+		Groups:      []models.NotificationPreference{},
+	}
+	for _, e := range models.NotificationEvents {
+		response.Preferences = append(response.Preferences, set.Effective(e, nil))
+	}
+	database.DB.Where("user_id = ? AND group_id IS NOT NULL", user.ID).Order("group_id, event_type").Find(&response.Groups)
+	return response
+}
+
+func groupNotificationPreferences(userID, groupID uuid.UUID) []models.NotificationPreference {
+	sets, _ := services.LoadPreferences(database.DB, []uuid.UUID{userID})
+
+	prefs := []models.NotificationPreference{}
+	for _, e := range models.NotificationEvents {
+		prefs = append(prefs, sets[userID].Effective(e, &groupID))
+	}
+	return prefs
+}
+
+func validateNotificationEvents(prefs []models.NotificationChannelsInput) error {
+	for _, p := range prefs {
+		if !services.IsNotificationEvent(p.EventType) {
+			return fmt.Errorf("unknown event type \"%s\"", p.EventType)
+		}
+	}
+	return nil
+}
+
+var unsubscribeTemplate = template.Must(template.New("unsubscribe").Parse(`<!DOCTYPE html>
+<html>
+<head><meta name="viewport" content="width=device-width, initial-scale=1"><title>{{.AppName}}</title></head>
+<body style="font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif; max-width: 600px; margin: 0 auto; padding: 20px; background-color: #f5f5f5;">
+	<div style="background: white; border-radius: 12px; padding: 32px; box-shadow: 0 2px 8px rgba(0,0,0,0.1);">
+		<p>{{.Message}}</p>
+		{{if .Token}}<form method="POST" action="?token={{.Token}}">
+			<button type="submit" style="background: #1DB954; color: white; padding: 12px 32px; border: 0; border-radius: 8px; font-weight: bold;">Unsubscribe</button>
+		</form>{{end}}
+		<p style="color: #999; font-size: 12px; margin-top: 24px;">— {{.AppName}}</p>
+	</div>
+</body>
+</html>`))
+
+func renderUnsubscribePage(c *gin.Context, status int, message string, token string) {
+	var buf bytes.Buffer
+	unsubscribeTemplate.Execute(&buf, map[string]string{
+		"AppName": config.AppConfig.AppName,
+		"Message": message,
+		"Token":   token,
+	})
+	c.Data(status, "text/html; charset=utf-8", buf.Bytes())
+}
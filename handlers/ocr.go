@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"net/http"
+	"splitwise-backend/database"
+	"splitwise-backend/models"
+	"splitwise-backend/ocr"
+	"splitwise-backend/services"
+	"splitwise-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// POST /api/groups/:id/receipts/scan — multipart "receipt" (+ optional itemize=true); returns a job to poll
+func ScanReceipt(c *gin.Context) {
+	userID := utils.GetCurrentUserID(c)
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid group ID")
+		return
+	}
+
+	if _, ok := authorize(c, groupID, models.PermAddExpense); !ok {
+		return
+	}
+
+	data, ok := readReceiptUpload(c)
+	if !ok {
+		return
+	}
+
+	job, err := services.StartReceiptScan(c.Request.Context(), groupID, userID, data, c.PostForm("itemize") == "true")
+	if !handleScanError(c, err) {
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusAccepted, "Receipt scan started", job)
+}
+
+// POST /api/expenses/:id/receipt/scan — scan the receipt already attached to an expense
+func ScanExpenseReceipt(c *gin.Context) {
+	userID := utils.GetCurrentUserID(c)
+	expense, ok := loadModifiableExpense(c)
+	if !ok {
+		return
+	}
+
+	if expense.ReceiptKey == "" {
+		utils.NotFound(c, "This expense has no receipt")
+		return
+	}
+
+	job, err := services.StartExpenseReceiptScan(expense, userID, c.Query("itemize") == "true")
+	if !handleScanError(c, err) {
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusAccepted, "Receipt scan started", job)
+}
+
+// GET /api/ocr-jobs/:id — status of a scan, with the draft expense once completed
+func GetOCRJob(c *gin.Context) {
+	userID := utils.GetCurrentUserID(c)
+	jobID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid job ID")
+		return
+	}
+
+	var job models.OCRJob
+	if err := database.DB.Where("id = ? AND user_id = ?", jobID, userID).First(&job).Error; err != nil {
+		utils.NotFound(c, "Scan not found")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "", job)
+}
+
+// Helper: map scan start errors to responses; returns true when there was none
+func handleScanError(c *gin.Context, err error) bool {
+	switch err {
+	case nil:
+		return true
+	case ocr.ErrDisabled:
+		utils.ErrorResponse(c, http.StatusServiceUnavailable, "Receipt scanning is not available on this server")
+	case services.ErrUnsupportedScan:
+		utils.BadRequest(c, err.Error())
+	default:
+		utils.InternalError(c, "Failed to start receipt scan")
+	}
+	return false
+}
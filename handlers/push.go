@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"path"
+	"splitwise-backend/models"
+	"splitwise-backend/utils"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+var errVersionConflict = errors.New("version conflict")
+
+// replayingPush marks the context of requests replayed from a push, so a push can't contain another
+type replayingPush struct{}
+
+// POST /api/sync/push — applies a client's queued offline writes in order.
+// Each mutation goes through the normal route, so validation, permissions and
+// conflict checks are exactly those of the single-item endpoints.
+func PushMutations(router http.Handler) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Context().Value(replayingPush{}) != nil {
+			utils.BadRequest(c, "Pushes cannot be nested")
+			return
+		}
+
+		var req models.PushRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.BadRequest(c, err.Error())
+			return
+		}
+
+		results := make([]models.MutationResult, 0, len(req.Mutations))
+		for _, m := range req.Mutations {
+			results = append(results, replayMutation(c, router, m))
+		}
+
+		utils.SuccessResponse(c, http.StatusOK, "", results)
+	}
+}
+
+// Helper: run one mutation through the router with the caller's credentials
+func replayMutation(c *gin.Context, router http.Handler, m models.Mutation) models.MutationResult {
+	result := models.MutationResult{ID: m.ID}
+
+	target, ok := mutationTarget(m.Path)
+	if !ok {
+		result.Status = http.StatusBadRequest
+		result.Body, _ = json.Marshal(utils.APIResponse{Message: "Only /api/ writes can be pushed"})
+		return result
+	}
+
+	body := m.Body
+	if len(body) == 0 {
+		body = []byte("{}")
+	}
+
+	ctx := context.WithValue(c.Request.Context(), replayingPush{}, true)
+	r, err := http.NewRequestWithContext(ctx, m.Method, target.String(), bytes.NewReader(body))
+	if err != nil {
+		result.Status = http.StatusBadRequest
+		result.Body, _ = json.Marshal(utils.APIResponse{Message: "Invalid path"})
+		return result
+	}
+	r.Header.Set("Authorization", c.GetHeader("Authorization"))
+	r.Header.Set("Content-Type", "application/json")
+	if m.IfMatch != "" {
+		r.Header.Set("If-Match", m.IfMatch)
+	}
+
+	w := &mutationRecorder{header: http.Header{}, status: http.StatusOK}
+	router.ServeHTTP(w, r)
+
+	result.Status = w.status
+	result.ETag = w.header.Get("ETag")
+	if json.Valid(w.body.Bytes()) {
+		result.Body = w.body.Bytes()
+	}
+	return result
+}
+
+// Helper: the URL a mutation may be replayed on. The check runs on the decoded path the router
+// will match, so escapes like /api/%73ync can't reach sync itself; queries, fragments and
+// anything that isn't already a clean path are refused rather than normalised.
+func mutationTarget(raw string) (*url.URL, bool) {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme != "" || u.Host != "" || u.User != nil || u.Opaque != "" ||
+		u.RawQuery != "" || u.ForceQuery || u.Fragment != "" || strings.ContainsAny(raw, "?#") {
+		return nil, false
+	}
+	if path.Clean(u.Path) != u.Path || !strings.HasPrefix(u.Path, "/api/") {
+		return nil, false
+	}
+	if u.Path == "/api/sync" || strings.HasPrefix(u.Path, "/api/sync/") {
+		return nil, false
+	}
+	return &url.URL{Path: u.Path}, true
+}
+
+// mutationRecorder captures the response of a replayed mutation
+type mutationRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (w *mutationRecorder) Header() http.Header         { return w.header }
+func (w *mutationRecorder) Write(b []byte) (int, error) { return w.body.Write(b) }
+func (w *mutationRecorder) WriteHeader(status int)      { w.status = status }
+
+// Helper: parse the optional client-generated ID of a create request.
+// Returns uuid.Nil when the client didn't send one.
+func parseClientID(c *gin.Context, raw string) (uuid.UUID, bool) {
+	if raw == "" {
+		return uuid.Nil, true
+	}
+	id, err := uuid.Parse(raw)
+	if err != nil || id == uuid.Nil {
+		utils.BadRequest(c, "id must be a UUID")
+		return uuid.Nil, false
+	}
+	return id, true
+}
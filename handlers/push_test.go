@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"splitwise-backend/models"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMutationTarget(t *testing.T) {
+	tests := []struct {
+		path string
+		ok   bool
+	}{
+		{"/api/expenses/123", true},
+		{"/api/groups/abc/expenses", true},
+		{"/api/sync/push", false},
+		{"/api/sync", false},
+		{"/api/%73ync/push", false}, // decodes to /api/sync/push
+		{"/api/sync%2Fpush", false},
+		{"/api/expenses/../sync/push", false},
+		{"/api/expenses//1", false},
+		{"/api/expenses/1/", false},
+		{"/api/expenses/1?force=true", false},
+		{"/api/expenses/1?", false},
+		{"/api/expenses/1#frag", false},
+		{"http://evil.example/api/expenses/1", false},
+		{"//evil.example/api/expenses/1", false},
+		{"/health", false},
+		{"api/expenses/1", false},
+	}
+	for _, tt := range tests {
+		if _, ok := mutationTarget(tt.path); ok != tt.ok {
+			t.Errorf("mutationTarget(%q) ok = %v, want %v", tt.path, ok, tt.ok)
+		}
+	}
+}
+
+// Replays reach the normal routes, but a push replayed inside a push is refused
+func TestPushMutationsRefusesNesting(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/api/sync/push", PushMutations(r))
+	r.POST("/api/things", func(c *gin.Context) {
+		if c.Request.Context().Value(replayingPush{}) == nil {
+			t.Error("replayed request not marked as coming from a push")
+		}
+		c.JSON(http.StatusCreated, gin.H{"ok": true})
+	})
+
+	push := func(ctx context.Context, mutations ...models.Mutation) *httptest.ResponseRecorder {
+		raw, _ := json.Marshal(models.PushRequest{Mutations: mutations})
+		req := httptest.NewRequest(http.MethodPost, "/api/sync/push", bytes.NewReader(raw)).WithContext(ctx)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := push(context.Background(),
+		models.Mutation{ID: "a", Method: http.MethodPost, Path: "/api/things"},
+		models.Mutation{ID: "b", Method: http.MethodPost, Path: "/api/%73ync/push", Body: json.RawMessage(`{"mutations":[]}`)},
+	)
+	var body struct {
+		Data []models.MutationResult `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || len(body.Data) != 2 {
+		t.Fatalf("push: %d %s", w.Code, w.Body.String())
+	}
+	if body.Data[0].Status != http.StatusCreated {
+		t.Errorf("plain mutation: %d, want 201", body.Data[0].Status)
+	}
+	if body.Data[1].Status != http.StatusBadRequest {
+		t.Errorf("escaped sync path: %d, want 400", body.Data[1].Status)
+	}
+
+	nested := context.WithValue(context.Background(), replayingPush{}, true)
+	if w := push(nested, models.Mutation{ID: "c", Method: http.MethodPost, Path: "/api/things"}); w.Code != http.StatusBadRequest {
+		t.Errorf("push inside a push: %d, want 400", w.Code)
+	}
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"net/http"
+	"splitwise-backend/database"
+	"splitwise-backend/models"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// List endpoints must load related rows in batches: the number of statements a request runs
+// stays the same however many groups, members and expenses there are
+func TestListQueryBudgets(t *testing.T) {
+	requireDB(t)
+
+	alice := createTestUser(t, "Alice")
+	others := []models.User{createTestUser(t, "Bob"), createTestUser(t, "Carol"), createTestUser(t, "Dave")}
+	everyone := append([]models.User{alice}, others...)
+
+	var groups []models.Group
+	for g := 0; g < 3; g++ {
+		group := createTestGroup(t, alice, others...)
+		groups = append(groups, group)
+
+		for i, payer := range everyone {
+			expense := models.Expense{GroupID: group.ID, PaidBy: payer.ID, Description: "Dinner", Amount: 40, SplitType: "equal"}
+			if err := database.DB.Create(&expense).Error; err != nil {
+				t.Fatal(err)
+			}
+			var splits []models.ExpenseSplit
+			for _, u := range everyone {
+				split := models.ExpenseSplit{ExpenseID: expense.ID, UserID: u.ID, OwedAmount: 10}
+				if u.ID == payer.ID {
+					split.PaidAmount = 40
+				}
+				splits = append(splits, split)
+			}
+			if err := database.DB.Create(&splits).Error; err != nil {
+				t.Fatal(err)
+			}
+
+			// Uneven settlements so the balances aren't all zero
+			settlement := models.Settlement{GroupID: group.ID, PaidBy: payer.ID, PaidTo: everyone[(i+1)%len(everyone)].ID, Amount: float64(5 * (i + 1))}
+			if err := database.DB.Create(&settlement).Error; err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	group := groups[0].ID.String()
+	tests := []struct {
+		name    string
+		handler gin.HandlerFunc
+		route   string
+		path    string
+		budget  int
+	}{
+		{"groups", GetGroups, "/api/groups", "/api/groups", 4},
+		{"group expenses", GetGroupExpenses, "/api/groups/:id/expenses", "/api/groups/" + group + "/expenses?limit=100", 6},
+		{"group balances", GetGroupBalances, "/api/groups/:id/balances", "/api/groups/" + group + "/balances", 8},
+		{"overall balances", GetOverallBalances, "/api/balances", "/api/balances", 6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var code int
+			used := countQueries(t, func() {
+				code = serveAs(alice, tt.handler, http.MethodGet, tt.route, tt.path, nil).Code
+			})
+			if code != http.StatusOK {
+				t.Fatalf("status = %d", code)
+			}
+			if used > tt.budget {
+				t.Errorf("%s ran %d queries, budget %d — check for N+1 queries", tt.path, used, tt.budget)
+			}
+		})
+	}
+}
+
+// countQueries runs fn with database.DB swapped for a GORM instance of its own, sharing the
+// connection pool but with a counting callback, so only fn's statements are counted
+func countQueries(t *testing.T, fn func()) int {
+	t.Helper()
+
+	pool, err := database.DB.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: pool}), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var count atomic.Int64
+	inc := func(*gorm.DB) { count.Add(1) }
+	for _, err := range []error{
+		db.Callback().Create().After("gorm:create").Register("test:count_create", inc),
+		db.Callback().Query().After("gorm:query").Register("test:count_query", inc),
+		db.Callback().Update().After("gorm:update").Register("test:count_update", inc),
+		db.Callback().Delete().After("gorm:delete").Register("test:count_delete", inc),
+		db.Callback().Row().After("gorm:row").Register("test:count_row", inc),
+		db.Callback().Raw().After("gorm:raw").Register("test:count_raw", inc),
+	} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	prev := database.DB
+	database.DB = db
+	defer func() { database.DB = prev }()
+
+	fn()
+	return int(count.Load())
+}
@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"splitwise-backend/config"
+	"splitwise-backend/database"
+	"splitwise-backend/models"
+	"splitwise-backend/services"
+	"splitwise-backend/storage"
+	"splitwise-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// POST /api/expenses/:id/receipt — multipart upload, form field "receipt"
+func UploadReceipt(c *gin.Context) {
+	expense, ok := loadModifiableExpense(c)
+	if !ok {
+		return
+	}
+
+	data, ok := readReceiptUpload(c)
+	if !ok {
+		return
+	}
+
+	stored, err := services.StoreReceipt(c.Request.Context(), expense.ID, data)
+	if err == services.ErrUnsupportedReceipt {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+	if err != nil {
+		utils.InternalError(c, "Failed to store receipt")
+		return
+	}
+
+	previous := expense
+	database.DB.Model(&expense).Updates(map[string]interface{}{
+		"receipt_url":          fmt.Sprintf("%s/api/expenses/%s/receipt", config.AppConfig.AppURL, expense.ID),
+		"receipt_key":          stored.Key,
+		"receipt_thumb_key":    stored.ThumbKey,
+		"receipt_content_type": stored.ContentType,
+	})
+
+	// Replacing a receipt: drop the old files
+	go services.DeleteReceipt(previous)
+
+	response := buildExpenseResponse(expense.ID)
+	utils.SuccessResponse(c, http.StatusOK, "Receipt uploaded", response)
+}
+
+// GET /api/expenses/:id/receipt — streams the receipt to group members (?size=thumb for the thumbnail)
+func GetReceipt(c *gin.Context) {
+	expenseID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid expense ID")
+		return
+	}
+
+	var expense models.Expense
+	if err := database.DB.First(&expense, expenseID).Error; err != nil {
+		utils.NotFound(c, "Expense not found")
+		return
+	}
+
+	if _, ok := authorize(c, expense.GroupID, models.PermViewGroup); !ok {
+		return
+	}
+
+	key, contentType := expense.ReceiptKey, expense.ReceiptContentType
+	if c.Query("size") == "thumb" && expense.ReceiptThumbKey != "" {
+		key, contentType = expense.ReceiptThumbKey, "image/jpeg"
+	}
+	if key == "" {
+		utils.NotFound(c, "This expense has no receipt")
+		return
+	}
+
+	data, err := storage.Files.Get(c.Request.Context(), key)
+	if err == storage.ErrNotFound {
+		utils.NotFound(c, "Receipt not found")
+		return
+	}
+	if err != nil {
+		utils.InternalError(c, "Failed to load receipt")
+		return
+	}
+
+	// Receipts are personal data: never let shared caches keep them
+	c.Header("Cache-Control", "private, max-age=3600")
+	c.Data(http.StatusOK, contentType, data)
+}
+
+// DELETE /api/expenses/:id/receipt
+func RemoveReceipt(c *gin.Context) {
+	expense, ok := loadModifiableExpense(c)
+	if !ok {
+		return
+	}
+
+	if expense.ReceiptKey == "" {
+		utils.NotFound(c, "This expense has no receipt")
+		return
+	}
+
+	previous := expense
+	database.DB.Model(&expense).Updates(map[string]interface{}{
+		"receipt_url":          "",
+		"receipt_key":          "",
+		"receipt_thumb_key":    "",
+		"receipt_content_type": "",
+	})
+	go services.DeleteReceipt(previous)
+
+	utils.SuccessResponse(c, http.StatusOK, "Receipt removed", nil)
+}
+
+// Helper: read the multipart "receipt" field, enforcing the size limit
+func readReceiptUpload(c *gin.Context) ([]byte, bool) {
+	// Leave some room for the multipart envelope on top of the file itself
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, services.MaxReceiptSize+1<<20)
+
+	file, err := c.FormFile("receipt")
+	if err != nil {
+		utils.BadRequest(c, "Attach the receipt as multipart form field \"receipt\" (max 10 MB)")
+		return nil, false
+	}
+	if file.Size > services.MaxReceiptSize {
+		utils.BadRequest(c, "Receipt must be 10 MB or smaller")
+		return nil, false
+	}
+
+	f, err := file.Open()
+	if err != nil {
+		utils.BadRequest(c, "Could not read uploaded file")
+		return nil, false
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(io.LimitReader(f, services.MaxReceiptSize+1))
+	if err != nil || len(data) == 0 {
+		utils.BadRequest(c, "Could not read uploaded file")
+		return nil, false
+	}
+	if len(data) > services.MaxReceiptSize {
+		utils.BadRequest(c, "Receipt must be 10 MB or smaller")
+		return nil, false
+	}
+
+	return data, true
+}
+
+// Helper: load :id and make sure the current user may change it
+func loadModifiableExpense(c *gin.Context) (models.Expense, bool) {
+	var expense models.Expense
+
+	expenseID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid expense ID")
+		return expense, false
+	}
+
+	if err := database.DB.First(&expense, expenseID).Error; err != nil {
+		utils.NotFound(c, "Expense not found")
+		return expense, false
+	}
+
+	access, ok := authorize(c, expense.GroupID, models.PermViewGroup)
+	if !ok {
+		return expense, false
+	}
+	if !access.Group.CanModifyExpense(access.Member, expense) {
+		utils.Forbidden(c, "Only the payer or a group admin can change this expense")
+		return expense, false
+	}
+
+	return expense, true
+}
+
+// Helper: thumbnail URL for an expense, empty when there is none
+func receiptThumbURL(expense models.Expense) string {
+	if expense.ReceiptThumbKey == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s/api/expenses/%s/receipt?size=thumb", config.AppConfig.AppURL, expense.ID)
+}
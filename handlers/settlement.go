@@ -10,6 +10,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 // POST /api/groups/:id/settle
@@ -21,8 +22,8 @@ func CreateSettlement(c *gin.Context) {
 		return
 	}
 
-	if !isMember(groupID, userID) {
-		utils.Unauthorized(c, "You are not a member of this group")
+	access, ok := authorize(c, groupID, models.PermSettle)
+	if !ok {
 		return
 	}
 
@@ -38,7 +39,24 @@ func CreateSettlement(c *gin.Context) {
 		return
 	}
 
+	clientID, ok := parseClientID(c, req.ID)
+	if !ok {
+		return
+	}
+	if clientID != uuid.Nil {
+		var existing models.Settlement
+		if database.DB.First(&existing, clientID).Error == nil {
+			if existing.GroupID != groupID || existing.PaidBy != userID {
+				utils.Conflict(c, "This settlement ID is already in use", nil)
+				return
+			}
+			utils.SuccessResponse(c, http.StatusOK, "Settlement already recorded", existing)
+			return
+		}
+	}
+
 	settlement := models.Settlement{
+		ID:      clientID,
 		GroupID: groupID,
 		PaidBy:  userID,
 		PaidTo:  paidTo,
@@ -46,51 +64,88 @@ func CreateSettlement(c *gin.Context) {
 		Notes:   req.Notes,
 	}
 
-	if err := database.DB.Create(&settlement).Error; err != nil {
-		utils.InternalError(c, "Failed to create settlement")
-		return
-	}
-
-	// Log activity
 	var payer, payee models.User
 	database.DB.First(&payer, userID)
 	database.DB.First(&payee, paidTo)
-	var group models.Group
-	database.DB.First(&group, groupID)
-
-	database.DB.Create(&models.Activity{
-		GroupID:     groupID,
-		UserID:      userID,
-		Type:        "settlement",
-		ReferenceID: settlement.ID,
-		Description: fmt.Sprintf("%s paid %s %s %.2f", payer.Name, payee.Name, "INR", req.Amount),
+	group := access.Group
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&settlement).Error; err != nil {
+			return err
+		}
+
+		// Log activity
+		err := tx.Create(&models.Activity{
+			GroupID:     groupID,
+			UserID:      userID,
+			Type:        "settlement",
+			ReferenceID: settlement.ID,
+			Description: fmt.Sprintf("%s paid %s %s %.2f", payer.Name, payee.Name, "INR", req.Amount),
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		// Notify the payee
+		return services.GetNotificationService().NotifySettlement(tx, settlement, payer, payee, group)
 	})
+	if err != nil {
+		utils.InternalError(c, "Failed to create settlement")
+		return
+	}
 
-	// Notify the payee
-	go services.GetNotificationService().NotifySettlement(settlement, payer, payee, group)
+	archiveIfSettled(group, userID)
 
 	utils.SuccessResponse(c, http.StatusCreated, "Settlement recorded", settlement)
 }
 
 // GET /api/groups/:id/settlements
 func GetGroupSettlements(c *gin.Context) {
-	userID := utils.GetCurrentUserID(c)
 	groupID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
 		utils.BadRequest(c, "Invalid group ID")
 		return
 	}
 
-	if !isMember(groupID, userID) {
-		utils.Unauthorized(c, "You are not a member of this group")
+	if _, ok := authorize(c, groupID, models.PermViewGroup); !ok {
 		return
 	}
 
-	var settlements []models.Settlement
+	pagination, err := utils.BindPagination(c)
+	if err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	// Without limit or cursor, the whole list as before
+	if !pagination.Requested() {
+		settlements := []models.Settlement{}
+		database.DB.Where("group_id = ?", groupID).
+			Preload("Payer").Preload("Payee").
+			Order("created_at DESC, id DESC").
+			Find(&settlements)
+		utils.SuccessResponse(c, http.StatusOK, "", settlements)
+		return
+	}
+
+	var total int64
+	database.DB.Model(&models.Settlement{}).Where("group_id = ?", groupID).Count(&total)
+
+	settlements := []models.Settlement{}
 	database.DB.Where("group_id = ?", groupID).
 		Preload("Payer").Preload("Payee").
-		Order("created_at DESC").
+		Scopes(pagination.Scope(createdAtKeyset)).
+		Order("created_at DESC, id DESC").
 		Find(&settlements)
 
-	utils.SuccessResponse(c, http.StatusOK, "", settlements)
+	fetched := len(settlements)
+	if fetched > pagination.Limit {
+		settlements = settlements[:pagination.Limit]
+	}
+
+	var last utils.Cursor
+	if n := len(settlements); n > 0 {
+		last = utils.Cursor{CreatedAt: settlements[n-1].CreatedAt, ID: settlements[n-1].ID}
+	}
+	utils.SuccessResponseWithMeta(c, http.StatusOK, "", settlements, pagination.Meta(total, fetched, last))
 }
@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"splitwise-backend/database"
+	"splitwise-backend/models"
+	"splitwise-backend/realtime"
+	"splitwise-backend/utils"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	streamHeartbeat = 25 * time.Second
+	streamRecentIDs = 256 // sent event IDs remembered to skip duplicates
+)
+
+// GET /api/stream — Server-Sent Events for the user's groups.
+// Reconnect with the Last-Event-ID header (or ?last_event_id=) to receive what was missed.
+func Stream(c *gin.Context) {
+	userID := utils.GetCurrentUserID(c)
+
+	var lastID int64
+	if raw := c.GetHeader("Last-Event-ID"); raw != "" {
+		lastID, _ = strconv.ParseInt(raw, 10, 64)
+	} else if raw := c.Query("last_event_id"); raw != "" {
+		lastID, _ = strconv.ParseInt(raw, 10, 64)
+	}
+
+	// Subscribe before replaying history so nothing falls in between; duplicates are skipped by ID.
+	// IDs are remembered rather than compared to the last one, so an event that arrives after a
+	// later one is still sent.
+	sub := realtime.Default.Subscribe()
+	defer realtime.Default.Unsubscribe(sub)
+
+	groups := streamGroups(userID)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // stop nginx from buffering the stream
+	c.Status(http.StatusOK)
+
+	sent := newRecentIDs(streamRecentIDs)
+	send := func(e realtime.Event) {
+		if sent.has(e.ID) || !groups.allows(e) {
+			return
+		}
+		sent.add(e.ID)
+		if e.ID > lastID {
+			lastID = e.ID
+		}
+		writeEvent(c.Writer, e, lastID)
+	}
+
+	if lastID > 0 {
+		missed, ok := realtime.Default.Since(lastID)
+		if !ok {
+			// Too far behind, or the server restarted: the client should refresh through /api/sync
+			fmt.Fprint(c.Writer, "event: resync\ndata: {}\n\n")
+			lastID = 0
+		}
+		for _, e := range missed {
+			send(e)
+		}
+	}
+	fmt.Fprint(c.Writer, ": connected\n\n")
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case e, open := <-sub.C:
+			if !open {
+				return // fell behind; the client reconnects with Last-Event-ID
+			}
+			send(e)
+			c.Writer.Flush()
+		case <-heartbeat.C:
+			fmt.Fprint(c.Writer, ": ping\n\n")
+			c.Writer.Flush()
+		}
+	}
+}
+
+// Activity types that can change who is in a group
+var membershipEvents = map[string]bool{
+	"group_created": true,
+	"member_joined": true,
+	"member_left":   true,
+}
+
+// streamGroupSet tracks which groups a streaming user may see
+type streamGroupSet struct {
+	userID uuid.UUID
+	ids    map[uuid.UUID]bool
+}
+
+func streamGroups(userID uuid.UUID) *streamGroupSet {
+	set := &streamGroupSet{userID: userID, ids: make(map[uuid.UUID]bool)}
+
+	var memberships []models.GroupMember
+	database.DB.Where("user_id = ?", userID).Find(&memberships)
+	for _, m := range memberships {
+		set.ids[m.GroupID] = true
+	}
+	return set
+}
+
+// Membership changes are re-checked against the database so joining or leaving
+// takes effect without reconnecting. Only changes to this user's own membership can
+// do that, so other members coming and going doesn't cost a query.
+func (s *streamGroupSet) allows(e realtime.Event) bool {
+	if membershipEvents[e.Type] && s.affectedBy(e) {
+		var count int64
+		database.DB.Model(&models.GroupMember{}).
+			Where("group_id = ? AND user_id = ?", e.GroupID, s.userID).
+			Count(&count)
+		s.ids[e.GroupID] = count > 0
+	}
+	return s.ids[e.GroupID]
+}
+
+// Helper: whether a membership event may concern the streaming user. Events from before
+// member_id was recorded can only be checked for groups the user is already in.
+func (s *streamGroupSet) affectedBy(e realtime.Event) bool {
+	var data models.ActivityEvent
+	if json.Unmarshal(e.Data, &data) != nil || data.MemberID == nil {
+		return s.ids[e.GroupID]
+	}
+	return *data.MemberID == s.userID
+}
+
+// recentIDs is a fixed-size set of the most recently added event IDs
+type recentIDs struct {
+	ids   map[int64]bool
+	order []int64 // ring of the IDs in ids, oldest at next once full
+	next  int
+}
+
+func newRecentIDs(size int) *recentIDs {
+	return &recentIDs{ids: make(map[int64]bool, size), order: make([]int64, 0, size)}
+}
+
+func (r *recentIDs) has(id int64) bool {
+	return r.ids[id]
+}
+
+func (r *recentIDs) add(id int64) {
+	if r.ids[id] {
+		return
+	}
+	if len(r.order) < cap(r.order) {
+		r.order = append(r.order, id)
+	} else {
+		delete(r.ids, r.order[r.next])
+		r.order[r.next] = id
+		r.next = (r.next + 1) % len(r.order)
+	}
+	r.ids[id] = true
+}
+
+// Helper: one SSE frame. Its id is the highest sent so far, so an event that arrived
+// late doesn't move a reconnecting client's Last-Event-ID backwards.
+func writeEvent(w io.Writer, e realtime.Event, lastID int64) {
+	data, _ := json.Marshal(e)
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", lastID, e.Type, data)
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"splitwise-backend/database"
+	"splitwise-backend/models"
+	"splitwise-backend/realtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Other people joining or leaving is decided without a query (without TEST_DATABASE_URL
+// database.DB is nil, so a re-check would panic)
+func TestStreamIgnoresOtherMembersChanges(t *testing.T) {
+	me, other := uuid.New(), uuid.New()
+	mine, elsewhere := uuid.New(), uuid.New()
+	set := &streamGroupSet{userID: me, ids: map[uuid.UUID]bool{mine: true}}
+
+	event := func(eventType string, groupID uuid.UUID, member *uuid.UUID) realtime.Event {
+		data, _ := json.Marshal(models.ActivityEvent{ActivityID: uuid.New(), UserID: other, MemberID: member})
+		return realtime.Event{Type: eventType, GroupID: groupID, Data: data}
+	}
+
+	tests := []struct {
+		name string
+		e    realtime.Event
+		want bool
+	}{
+		{"someone joins my group", event("member_joined", mine, &other), true},
+		{"someone leaves my group", event("member_left", mine, &other), true},
+		{"someone joins another group", event("member_joined", elsewhere, &other), false},
+		{"group created by someone else", event("group_created", elsewhere, &other), false},
+		{"old event without member_id elsewhere", event("member_joined", elsewhere, nil), false},
+		{"expense in my group", event("expense_added", mine, nil), true},
+		{"expense elsewhere", event("expense_added", elsewhere, nil), false},
+	}
+	for _, tt := range tests {
+		if got := set.allows(tt.e); got != tt.want {
+			t.Errorf("%s: allows = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestStreamRechecksOwnMembership(t *testing.T) {
+	requireDB(t)
+
+	alice, bob := createTestUser(t, "Alice"), createTestUser(t, "Bob")
+	group := createTestGroup(t, alice)
+	set := streamGroups(bob.ID)
+
+	data, _ := json.Marshal(models.ActivityEvent{UserID: alice.ID, MemberID: &bob.ID})
+	joined := realtime.Event{Type: "member_joined", GroupID: group.ID, Data: data}
+	if set.allows(joined) {
+		t.Fatal("allowed before Bob is a member")
+	}
+
+	if err := database.DB.Create(&models.GroupMember{GroupID: group.ID, UserID: bob.ID, Role: "member"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if !set.allows(joined) {
+		t.Error("not allowed after Bob joined")
+	}
+}
+
+func TestRecentIDs(t *testing.T) {
+	r := newRecentIDs(3)
+	for _, id := range []int64{5, 3, 5, 4} {
+		r.add(id)
+	}
+	for _, id := range []int64{3, 4, 5} {
+		if !r.has(id) {
+			t.Errorf("%d forgotten", id)
+		}
+	}
+
+	r.add(9) // evicts 5, the oldest added
+	if r.has(5) || !r.has(3) || !r.has(9) {
+		t.Errorf("after a fourth ID: %v, want 3, 4 and 9", r.ids)
+	}
+}
+
+// Events published out of ID order are all sent, once each
+func TestStreamOutOfOrderEvents(t *testing.T) {
+	requireDB(t)
+
+	alice := createTestUser(t, "Alice")
+	group := createTestGroup(t, alice)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/api/stream", func(c *gin.Context) { c.Set("user_id", alice.ID) }, Stream)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/api/stream", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	lines := bufio.NewScanner(resp.Body)
+	for lines.Scan() && lines.Text() != ": connected" {
+	}
+
+	base := time.Now().UnixNano() // well clear of IDs other tests deliver
+	for _, offset := range []int64{2, 1, 2, 3} {
+		realtime.Default.Deliver(realtime.Event{ID: base + offset, Type: "expense_added", GroupID: group.ID, Data: json.RawMessage(`{}`)})
+	}
+
+	var frameIDs, eventIDs []int64
+	for lines.Scan() && len(eventIDs) < 3 {
+		line := lines.Text()
+		if strings.HasPrefix(line, "id: ") {
+			var id int64
+			json.Unmarshal([]byte(strings.TrimPrefix(line, "id: ")), &id)
+			frameIDs = append(frameIDs, id-base)
+		}
+		if strings.HasPrefix(line, "data: ") {
+			var e realtime.Event
+			json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &e)
+			eventIDs = append(eventIDs, e.ID-base)
+		}
+	}
+
+	if !reflect.DeepEqual(eventIDs, []int64{2, 1, 3}) {
+		t.Errorf("events sent = %v, want 2, 1 and 3 once each", eventIDs)
+	}
+	if !reflect.DeepEqual(frameIDs, []int64{2, 2, 3}) {
+		t.Errorf("frame ids = %v, want the stream position never to go back", frameIDs)
+	}
+}
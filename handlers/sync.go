@@ -0,0 +1,275 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"splitwise-backend/database"
+	"splitwise-backend/models"
+	"splitwise-backend/utils"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const syncTokenPrefix = "s2:"
+
+// syncPageSize is how many rows of each kind one response holds (tests lower it)
+var syncPageSize = 500
+
+// syncCursor pins a sync to one range of changes while the client pages through it
+type syncCursor struct {
+	Since  int64 `json:"s"` // from the client's token; 0 for a full sync
+	Issued int64 `json:"i"` // when that token was issued, unix seconds
+	Full   bool  `json:"f"`
+	UpTo   int64 `json:"u"` // the new token's sequence, read when the sync started
+	Read   int64 `json:"r"` // when UpTo was read, unix seconds
+	After  int64 `json:"a"` // changes up to here were sent on earlier pages
+}
+
+// GET /api/sync?since=<token> — everything that changed in the user's groups since the token.
+// Without a token (or for groups joined since) the data is sent in full. Large results come
+// in pages: follow next_cursor while has_more is set, then keep the token for next time.
+func Sync(c *gin.Context) {
+	userID := utils.GetCurrentUserID(c)
+
+	cur, ok := bindSyncCursor(c)
+	if !ok {
+		return
+	}
+	firstPage := cur.After == 0
+
+	var memberships []models.GroupMember
+	database.DB.Where("user_id = ?", userID).Find(&memberships)
+
+	// Groups the client hasn't seen (or whose membership changed, e.g. rejoined) are sent in full.
+	// Membership changes after UpTo wait for the next sync so every page agrees on the split.
+	groupIDs := []uuid.UUID{}
+	snapshotIDs := []uuid.UUID{}
+	deltaIDs := []uuid.UUID{}
+	for _, m := range memberships {
+		groupIDs = append(groupIDs, m.GroupID)
+		if cur.Full || (m.ChangeSeq > cur.Since && m.ChangeSeq <= cur.UpTo) {
+			snapshotIDs = append(snapshotIDs, m.GroupID)
+		} else {
+			deltaIDs = append(deltaIDs, m.GroupID)
+		}
+	}
+
+	// Replacing local data is only right on the first page; later pages add to it
+	response := models.SyncResponse{
+		Token:          encodeSyncToken(cur.UpTo, cur.Read),
+		Full:           cur.Full && firstPage,
+		SnapshotGroups: []uuid.UUID{},
+		Groups:         []models.Group{},
+		Members:        []models.GroupMember{},
+		Expenses:       []models.Expense{},
+		Splits:         []models.ExpenseSplit{},
+		Settlements:    []models.Settlement{},
+		Activity:       []models.Activity{},
+		Deleted:        []models.Tombstone{},
+	}
+	if firstPage {
+		response.SnapshotGroups = snapshotIDs
+	}
+
+	if len(groupIDs) > 0 {
+		database.DB.Scopes(changedSince("groups.id", "groups", snapshotIDs, deltaIDs, cur)).
+			Preload("Creator").Find(&response.Groups)
+		database.DB.Scopes(changedSince("group_members.group_id", "group_members", snapshotIDs, deltaIDs, cur)).
+			Preload("User").Find(&response.Members)
+		database.DB.Scopes(changedSince("expenses.group_id", "expenses", snapshotIDs, deltaIDs, cur)).
+			Preload("Payer").Find(&response.Expenses)
+		database.DB.Joins("JOIN expenses ON expenses.id = expense_splits.expense_id").
+			Scopes(changedSince("expenses.group_id", "expense_splits", snapshotIDs, deltaIDs, cur)).
+			Preload("User").Find(&response.Splits)
+		database.DB.Scopes(changedSince("settlements.group_id", "settlements", snapshotIDs, deltaIDs, cur)).
+			Preload("Payer").Preload("Payee").Find(&response.Settlements)
+		database.DB.Scopes(changedSince("activities.group_id", "activities", snapshotIDs, deltaIDs, cur)).
+			Preload("User").Find(&response.Activity)
+	}
+
+	// Deletions only matter to clients that already have data. Losing a membership row is
+	// reported to that user even though they can no longer see the group.
+	if !cur.Full {
+		database.DB.Where("seq > ? AND seq <= ?", max(cur.Since, cur.After), cur.UpTo).
+			Where("group_id IN ? OR (entity_type = ? AND user_id = ?)", groupIDs, models.EntityMember, userID).
+			Order("seq").
+			Limit(syncPageSize + 1).
+			Find(&response.Deleted)
+	}
+
+	// Every list is in change order. When one is over the page size, the page ends at the
+	// lowest sequence number any full list reached, and the rest of every list comes next time.
+	var seqs [7][]int64
+	for _, g := range response.Groups {
+		seqs[0] = append(seqs[0], g.ChangeSeq)
+	}
+	for _, m := range response.Members {
+		seqs[1] = append(seqs[1], m.ChangeSeq)
+	}
+	for _, e := range response.Expenses {
+		seqs[2] = append(seqs[2], e.ChangeSeq)
+	}
+	for _, s := range response.Splits {
+		seqs[3] = append(seqs[3], s.ChangeSeq)
+	}
+	for _, s := range response.Settlements {
+		seqs[4] = append(seqs[4], s.ChangeSeq)
+	}
+	for _, a := range response.Activity {
+		seqs[5] = append(seqs[5], a.ChangeSeq)
+	}
+	for _, t := range response.Deleted {
+		seqs[6] = append(seqs[6], t.Seq)
+	}
+
+	boundary := cur.UpTo
+	for _, list := range seqs {
+		if len(list) > syncPageSize && list[syncPageSize-1] < boundary {
+			boundary = list[syncPageSize-1]
+		}
+	}
+	if boundary < cur.UpTo {
+		response.Groups = response.Groups[:countUpTo(seqs[0], boundary)]
+		response.Members = response.Members[:countUpTo(seqs[1], boundary)]
+		response.Expenses = response.Expenses[:countUpTo(seqs[2], boundary)]
+		response.Splits = response.Splits[:countUpTo(seqs[3], boundary)]
+		response.Settlements = response.Settlements[:countUpTo(seqs[4], boundary)]
+		response.Activity = response.Activity[:countUpTo(seqs[5], boundary)]
+		response.Deleted = response.Deleted[:countUpTo(seqs[6], boundary)]
+
+		cur.After = boundary
+		response.HasMore = true
+		response.NextCursor = encodeSyncCursor(cur)
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "", response)
+}
+
+// Helper: start a sync from ?since= or continue one from ?cursor=. Tokens older than
+// models.SyncTokenTTL are refused: the deletions they would need may have been pruned.
+func bindSyncCursor(c *gin.Context) (syncCursor, bool) {
+	if raw := c.Query("cursor"); raw != "" {
+		cur, ok := decodeSyncCursor(raw)
+		if !ok {
+			utils.BadRequest(c, "Invalid sync cursor, sync again without one")
+			return cur, false
+		}
+		if !cur.Full && syncTokenExpired(cur.Issued) {
+			respondResyncRequired(c)
+			return cur, false
+		}
+		return cur, true
+	}
+
+	cur := syncCursor{Full: c.Query("since") == ""}
+	if !cur.Full {
+		var ok bool
+		if cur.Since, cur.Issued, ok = decodeSyncToken(c.Query("since")); !ok {
+			utils.BadRequest(c, "Invalid sync token, sync again without one")
+			return cur, false
+		}
+		if syncTokenExpired(cur.Issued) {
+			respondResyncRequired(c)
+			return cur, false
+		}
+	}
+
+	// Read the new token first: anything that changes while we're querying is sent again next time
+	upTo, err := database.CurrentChangeSeq()
+	if err != nil {
+		utils.InternalError(c, "Failed to read change sequence")
+		return cur, false
+	}
+	cur.UpTo, cur.Read = upTo, time.Now().Unix()
+	return cur, true
+}
+
+func syncTokenExpired(issued int64) bool {
+	return time.Since(time.Unix(issued, 0)) > models.SyncTokenTTL
+}
+
+func respondResyncRequired(c *gin.Context) {
+	utils.ErrorResponse(c, http.StatusGone, "Sync token expired, full resync required: sync again without one")
+}
+
+// Helper: rows of snapshot groups, plus rows of the other groups changed after since, within
+// this page's range of changes, in change order with one extra row to tell if the page is full
+func changedSince(groupColumn, table string, snapshotIDs, deltaIDs []uuid.UUID, cur syncCursor) func(*gorm.DB) *gorm.DB {
+	seq := table + ".change_seq"
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where(
+			"("+groupColumn+" IN ? AND "+seq+" > ?) OR ("+groupColumn+" IN ? AND "+seq+" > ?)",
+			snapshotIDs, cur.After, deltaIDs, max(cur.Since, cur.After),
+		).
+			Where(seq+" <= ?", cur.UpTo).
+			Order(seq).
+			Limit(syncPageSize + 1)
+	}
+}
+
+// Helper: how many of the ascending sequence numbers are at or below boundary
+func countUpTo(seqs []int64, boundary int64) int {
+	return sort.Search(len(seqs), func(i int) bool { return seqs[i] > boundary })
+}
+
+// Helper: sync tokens are opaque to clients so the format can change later. Tokens from
+// before they carried an issue time ("s1:") decode as issued long ago, so they expire.
+func encodeSyncToken(seq int64, issued int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%s%d:%d", syncTokenPrefix, seq, issued)))
+}
+
+func decodeSyncToken(token string) (seq int64, issued int64, ok bool) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	value := string(raw)
+	switch {
+	case strings.HasPrefix(value, "s1:"):
+		value = strings.TrimPrefix(value, "s1:") + ":0"
+	case strings.HasPrefix(value, syncTokenPrefix):
+		value = strings.TrimPrefix(value, syncTokenPrefix)
+	default:
+		return 0, 0, false
+	}
+
+	parts := strings.Split(value, ":")
+	if len(parts) != 2 {
+		return 0, 0, false
+	}
+	seq, err = strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || seq < 0 {
+		return 0, 0, false
+	}
+	issued, err = strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || issued < 0 {
+		return 0, 0, false
+	}
+	return seq, issued, true
+}
+
+func encodeSyncCursor(cur syncCursor) string {
+	raw, _ := json.Marshal(cur)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeSyncCursor(s string) (syncCursor, bool) {
+	var cur syncCursor
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || json.Unmarshal(raw, &cur) != nil {
+		return cur, false
+	}
+	if cur.UpTo <= 0 || cur.After <= 0 || cur.After > cur.UpTo || cur.Since < 0 {
+		return cur, false
+	}
+	return cur, true
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"splitwise-backend/database"
+	"splitwise-backend/models"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestSyncTokenRoundTrip(t *testing.T) {
+	now := time.Now().Unix()
+	seq, issued, ok := decodeSyncToken(encodeSyncToken(12847, now))
+	if !ok || seq != 12847 || issued != now {
+		t.Errorf("decoded %d %d %v, want 12847 %d true", seq, issued, ok, now)
+	}
+	if syncTokenExpired(issued) {
+		t.Error("fresh token counted as expired")
+	}
+
+	// Tokens from before issue times were recorded still decode, but have expired
+	old := base64.RawURLEncoding.EncodeToString([]byte("s1:12847"))
+	seq, issued, ok = decodeSyncToken(old)
+	if !ok || seq != 12847 || !syncTokenExpired(issued) {
+		t.Errorf("s1 token: %d %d %v, want 12847 and expired", seq, issued, ok)
+	}
+
+	for _, bad := range []string{"", "!!", base64.RawURLEncoding.EncodeToString([]byte("s2:12")), base64.RawURLEncoding.EncodeToString([]byte("s2:-1:5"))} {
+		if _, _, ok := decodeSyncToken(bad); ok {
+			t.Errorf("%q decoded as a valid token", bad)
+		}
+	}
+}
+
+// An expired token is refused before anything is read, telling the client to start over
+func TestSyncExpiredTokenNeedsFullResync(t *testing.T) {
+	issued := time.Now().Add(-models.SyncTokenTTL - time.Hour).Unix()
+	path := "/api/sync?since=" + url.QueryEscape(encodeSyncToken(100, issued))
+
+	w := serveAs(models.User{ID: uuid.New()}, Sync, http.MethodGet, "/api/sync", path, nil)
+	if w.Code != http.StatusGone {
+		t.Errorf("status = %d, want 410", w.Code)
+	}
+}
+
+// Paging through a full sync delivers every row exactly once and the same token on every page
+func TestSyncPagesThroughSnapshot(t *testing.T) {
+	requireDB(t)
+
+	prev := syncPageSize
+	syncPageSize = 3
+	t.Cleanup(func() { syncPageSize = prev })
+
+	alice := createTestUser(t, "Alice")
+	bob := createTestUser(t, "Bob")
+	group := createTestGroup(t, alice, bob)
+
+	want := map[uuid.UUID]bool{}
+	for i := 0; i < 8; i++ {
+		expense := models.Expense{GroupID: group.ID, PaidBy: alice.ID, Description: "Coffee", Amount: 4, SplitType: "equal"}
+		if err := database.DB.Create(&expense).Error; err != nil {
+			t.Fatal(err)
+		}
+		want[expense.ID] = true
+	}
+
+	got := map[uuid.UUID]int{}
+	path := "/api/sync"
+	var token string
+	for page := 0; ; page++ {
+		if page > 20 {
+			t.Fatal("sync never finished paging")
+		}
+		data := syncPage(t, alice, path)
+		if data.Full != (page == 0) {
+			t.Errorf("page %d: full = %v", page, data.Full)
+		}
+		if token != "" && data.Token != token {
+			t.Errorf("page %d: token changed", page)
+		}
+		token = data.Token
+		for _, e := range data.Expenses {
+			got[e.ID]++
+		}
+		if !data.HasMore {
+			break
+		}
+		path = "/api/sync?cursor=" + url.QueryEscape(data.NextCursor)
+	}
+
+	for id := range want {
+		if got[id] != 1 {
+			t.Errorf("expense %s sent %d times, want once", id, got[id])
+		}
+	}
+
+	// A delta sync afterwards reports the deletion
+	var gone models.Expense
+	database.DB.Where("group_id = ?", group.ID).First(&gone)
+	database.DB.Delete(&gone)
+
+	data := syncPage(t, alice, "/api/sync?since="+url.QueryEscape(token))
+	found := false
+	for _, d := range data.Deleted {
+		found = found || (d.EntityID != nil && *d.EntityID == gone.ID)
+	}
+	if !found {
+		t.Errorf("deleted expense %s not in %+v", gone.ID, data.Deleted)
+	}
+}
+
+func syncPage(t *testing.T, user models.User, path string) models.SyncResponse {
+	t.Helper()
+
+	w := serveAs(user, Sync, http.MethodGet, "/api/sync", path, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET %s: %d %s", path, w.Code, w.Body.String())
+	}
+	var body struct {
+		Data models.SyncResponse `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	return body.Data
+}
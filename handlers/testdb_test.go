@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"os"
+	"splitwise-backend/config"
+	"splitwise-backend/database"
+	"splitwise-backend/models"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Tests that need Postgres run against TEST_DATABASE_URL and are skipped without it.
+// Point it at a scratch database: it is migrated on first use, and tests leave their rows behind.
+var connectOnce sync.Once
+
+func requireDB(t *testing.T) {
+	t.Helper()
+
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	connectOnce.Do(func() {
+		config.AppConfig = &config.Config{
+			DatabaseURL: url,
+			JWTSecret:   "test-secret",
+			AppName:     "SplitApp",
+			AppURL:      "http://localhost:8080",
+		}
+		database.Connect()
+	})
+}
+
+func createTestUser(t *testing.T, name string) models.User {
+	t.Helper()
+
+	user := models.User{
+		Email:        fmt.Sprintf("%s-%s@example.test", strings.ToLower(name), uuid.NewString()[:8]),
+		Name:         name,
+		PasswordHash: "not-a-real-hash",
+	}
+	if err := database.DB.Create(&user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	return user
+}
+
+// createTestGroup makes a group administered by its creator, with the others as plain members
+func createTestGroup(t *testing.T, creator models.User, others ...models.User) models.Group {
+	t.Helper()
+
+	group := models.Group{Name: "Test group", Type: "other", CreatedBy: creator.ID}
+	if err := database.DB.Create(&group).Error; err != nil {
+		t.Fatalf("create group: %v", err)
+	}
+
+	members := []models.GroupMember{{GroupID: group.ID, UserID: creator.ID, Role: "admin"}}
+	for _, u := range others {
+		members = append(members, models.GroupMember{GroupID: group.ID, UserID: u.ID, Role: "member"})
+	}
+	if err := database.DB.Create(&members).Error; err != nil {
+		t.Fatalf("add members: %v", err)
+	}
+	return group
+}
+
+// serveAs runs handler on one request made by user. route is the gin pattern the handler is
+// mounted on ("/api/expenses/:id"), path the URL requested; body, if any, is sent as JSON.
+func serveAs(user models.User, handler gin.HandlerFunc, method, route, path string, body interface{}) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Handle(method, route, func(c *gin.Context) { c.Set("user_id", user.ID) }, handler)
+
+	var reader io.Reader
+	if body != nil {
+		raw, _ := json.Marshal(body)
+		reader = bytes.NewReader(raw)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// decodeData unmarshals the "data" field of a successful JSON response into v
+func decodeData(t *testing.T, w *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+
+	var body struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("response %q: %v", w.Body.String(), err)
+	}
+	if err := json.Unmarshal(body.Data, v); err != nil {
+		t.Fatalf("response data %s: %v", body.Data, err)
+	}
+}
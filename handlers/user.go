@@ -5,8 +5,11 @@ import (
 	"splitwise-backend/database"
 	"splitwise-backend/models"
 	"splitwise-backend/utils"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 type UpdateProfileRequest struct {
@@ -16,10 +19,6 @@ type UpdateProfileRequest struct {
 	Currency  string `json:"currency"`
 }
 
-type UpdateFCMTokenRequest struct {
-	Token string `json:"token" binding:"required"`
-}
-
 // GET /api/users/me
 func GetProfile(c *gin.Context) {
 	userID := utils.GetCurrentUserID(c)
@@ -55,6 +54,7 @@ func UpdateProfile(c *gin.Context) {
 	}
 	if req.Phone != "" {
 		updates["phone"] = req.Phone
+		updates["phone_hash"] = utils.HashContact(utils.NormalizePhone(req.Phone))
 	}
 	if req.AvatarURL != "" {
 		updates["avatar_url"] = req.AvatarURL
@@ -68,41 +68,200 @@ func UpdateProfile(c *gin.Context) {
 	utils.SuccessResponse(c, http.StatusOK, "Profile updated", user.ToResponse())
 }
 
-// PUT /api/users/me/fcm-token
+// PUT /api/users/me/fcm-token — kept for older app versions; registers the token as a device
 func UpdateFCMToken(c *gin.Context) {
 	userID := utils.GetCurrentUserID(c)
 
-	var req UpdateFCMTokenRequest
+	var req models.RegisterDeviceRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.BadRequest(c, err.Error())
 		return
 	}
 
-	database.DB.Model(&models.User{}).Where("id = ?", userID).Update("fcm_token", req.Token)
+	if _, err := registerDevice(userID, req); err != nil {
+		utils.InternalError(c, "Failed to update FCM token")
+		return
+	}
 
 	utils.SuccessResponse(c, http.StatusOK, "FCM token updated", nil)
 }
 
 // POST /api/users/search
+// Fuzzy search is limited to people who share a group with the caller.
+// Anyone else can only be found by an exact email or phone match,
+// and only if their privacy settings allow it.
 func SearchUsers(c *gin.Context) {
-	var req struct {
-		Query string `json:"query" binding:"required"`
+	userID := utils.GetCurrentUserID(c)
+
+	var req models.SearchUsersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
 	}
+
+	query := strings.TrimSpace(req.Query)
+	pattern := "%" + escapeLike(query) + "%"
+
+	var connections []models.User
+	database.DB.Where("id IN (?)", connectedUserIDs(userID)).
+		Where("email ILIKE ? OR name ILIKE ? OR phone ILIKE ?", pattern, pattern, pattern).
+		Limit(20).
+		Find(&connections)
+
+	responses := []models.UserSearchResult{}
+	seen := make(map[uuid.UUID]bool)
+	for _, u := range connections {
+		responses = append(responses, u.ToSearchResult(true))
+		seen[u.ID] = true
+	}
+
+	// Exact-match lookup outside the caller's circle
+	var exact []models.User
+	if strings.Contains(query, "@") {
+		database.DB.Where("email = ? AND privacy_discoverable_by_email = ?", utils.NormalizeEmail(query), true).
+			Find(&exact)
+	} else if phone := utils.NormalizePhone(query); len(phone) >= 7 {
+		database.DB.Where("phone_hash = ? AND privacy_discoverable_by_phone = ?", utils.HashContact(phone), true).
+			Find(&exact)
+	}
+
+	for _, u := range exact {
+		if u.ID == userID || seen[u.ID] {
+			continue
+		}
+		responses = append(responses, u.ToSearchResult(false))
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "", responses)
+}
+
+// POST /api/users/contacts/match
+// Clients send SHA-256 hashes of normalized address-book emails and phones;
+// raw contact details never leave the device.
+func MatchContacts(c *gin.Context) {
+	userID := utils.GetCurrentUserID(c)
+
+	var req models.ContactMatchRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.BadRequest(c, err.Error())
 		return
 	}
 
+	hashes := make([]string, 0, len(req.Hashes))
+	for _, h := range req.Hashes {
+		h = strings.ToLower(strings.TrimSpace(h))
+		if len(h) == 64 {
+			hashes = append(hashes, h)
+		}
+	}
+
+	responses := []models.UserSearchResult{}
+	if len(hashes) == 0 {
+		utils.SuccessResponse(c, http.StatusOK, "", responses)
+		return
+	}
+
 	var users []models.User
-	database.DB.Where("email ILIKE ? OR name ILIKE ? OR phone ILIKE ?",
-		"%"+req.Query+"%", "%"+req.Query+"%", "%"+req.Query+"%").
-		Limit(20).
+	database.DB.Where("id <> ? AND privacy_contact_matching = ?", userID, true).
+		Where("(email_hash IN ? AND privacy_discoverable_by_email = ?) OR (phone_hash IN ? AND privacy_discoverable_by_phone = ?)",
+			hashes, true, hashes, true).
 		Find(&users)
 
-	var responses []models.UserResponse
+	connected := make(map[uuid.UUID]bool)
+	var connectedIDs []uuid.UUID
+	connectedUserIDs(userID).Pluck("gm2.user_id", &connectedIDs)
+	for _, id := range connectedIDs {
+		connected[id] = true
+	}
+
+	requested := make(map[string]bool, len(hashes))
+	for _, h := range hashes {
+		requested[h] = true
+	}
+
 	for _, u := range users {
-		responses = append(responses, u.ToResponse())
+		result := u.ToSearchResult(connected[u.ID])
+		if u.Privacy.DiscoverableByEmail && requested[u.EmailHash] {
+			result.MatchedHash = u.EmailHash
+		} else {
+			result.MatchedHash = u.PhoneHash
+		}
+		responses = append(responses, result)
 	}
 
 	utils.SuccessResponse(c, http.StatusOK, "", responses)
 }
+
+// GET /api/users/me/privacy
+func GetPrivacySettings(c *gin.Context) {
+	userID := utils.GetCurrentUserID(c)
+
+	var user models.User
+	if err := database.DB.First(&user, userID).Error; err != nil {
+		utils.NotFound(c, "User not found")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "", user.Privacy)
+}
+
+// PUT /api/users/me/privacy
+func UpdatePrivacySettings(c *gin.Context) {
+	userID := utils.GetCurrentUserID(c)
+
+	var req models.UpdatePrivacyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, userID).Error; err != nil {
+		utils.NotFound(c, "User not found")
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if req.DiscoverableByEmail != nil {
+		updates["privacy_discoverable_by_email"] = *req.DiscoverableByEmail
+	}
+	if req.DiscoverableByPhone != nil {
+		updates["privacy_discoverable_by_phone"] = *req.DiscoverableByPhone
+	}
+	if req.ContactMatching != nil {
+		updates["privacy_contact_matching"] = *req.ContactMatching
+	}
+
+	database.DB.Model(&user).Updates(updates)
+	database.DB.First(&user, userID)
+
+	utils.SuccessResponse(c, http.StatusOK, "Privacy settings updated", user.Privacy)
+}
+
+// Helper: subquery of users who share at least one group with userID
+func connectedUserIDs(userID uuid.UUID) *gorm.DB {
+	return database.DB.Table("group_members AS gm1").
+		Distinct("gm2.user_id").
+		Joins("JOIN group_members AS gm2 ON gm2.group_id = gm1.group_id").
+		Where("gm1.user_id = ? AND gm2.user_id <> ?", userID, userID)
+}
+
+// Helper: escape LIKE wildcards in user input
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// Helper: load users by ID in a single query, keyed by ID (duplicates are fine)
+func loadUsers(ids []uuid.UUID) map[uuid.UUID]models.User {
+	users := make(map[uuid.UUID]models.User, len(ids))
+	if len(ids) == 0 {
+		return users
+	}
+
+	var found []models.User
+	database.DB.Where("id IN ?", ids).Find(&found)
+	for _, u := range found {
+		users[u.ID] = u
+	}
+	return users
+}
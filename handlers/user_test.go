@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"splitwise-backend/database"
+	"splitwise-backend/models"
+	"splitwise-backend/utils"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func searchUsers(t *testing.T, user models.User, query string) map[uuid.UUID]models.UserSearchResult {
+	t.Helper()
+
+	w := serveAs(user, SearchUsers, http.MethodPost, "/api/users/search", "/api/users/search", models.SearchUsersRequest{Query: query})
+	if w.Code != http.StatusOK {
+		t.Fatalf("search %q: %d %s", query, w.Code, w.Body.String())
+	}
+	var results []models.UserSearchResult
+	decodeData(t, w, &results)
+
+	found := make(map[uuid.UUID]models.UserSearchResult)
+	for _, r := range results {
+		found[r.ID] = r
+	}
+	return found
+}
+
+func matchContacts(t *testing.T, user models.User, hashes ...string) map[uuid.UUID]models.UserSearchResult {
+	t.Helper()
+
+	w := serveAs(user, MatchContacts, http.MethodPost, "/api/users/contacts/match", "/api/users/contacts/match", models.ContactMatchRequest{Hashes: hashes})
+	if w.Code != http.StatusOK {
+		t.Fatalf("match contacts: %d %s", w.Code, w.Body.String())
+	}
+	var results []models.UserSearchResult
+	decodeData(t, w, &results)
+
+	found := make(map[uuid.UUID]models.UserSearchResult)
+	for _, r := range results {
+		found[r.ID] = r
+	}
+	return found
+}
+
+// Helper: give a test user a phone number
+func setPhone(t *testing.T, user *models.User, phone string) {
+	t.Helper()
+
+	user.Phone = phone
+	user.SetContactHashes()
+	if err := database.DB.Model(user).Updates(map[string]interface{}{"phone": user.Phone, "phone_hash": user.PhoneHash}).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func setPrivacy(t *testing.T, user models.User, column string, value bool) {
+	t.Helper()
+
+	if err := database.DB.Model(&user).Update(column, value).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSearchUsersPrivacy(t *testing.T) {
+	requireDB(t)
+
+	alice := createTestUser(t, "Alice")
+	friend := createTestUser(t, "Farah")
+	stranger := createTestUser(t, "Stella")
+	createTestGroup(t, alice, friend)
+	setPhone(t, &stranger, fmt.Sprintf("+91 98765 %05d", rand.Intn(100000)))
+
+	// The part of an email before "@" is unique per test user
+	localPart := func(u models.User) string { return strings.Split(u.Email, "@")[0] }
+
+	t.Run("co-members by partial match, with contact details", func(t *testing.T) {
+		got, ok := searchUsers(t, alice, localPart(friend))[friend.ID]
+		if !ok || !got.IsConnection || got.Email != friend.Email {
+			t.Errorf("co-member result = %+v, found %v", got, ok)
+		}
+	})
+
+	t.Run("others not by partial match", func(t *testing.T) {
+		if _, ok := searchUsers(t, alice, localPart(stranger))[stranger.ID]; ok {
+			t.Error("stranger found by part of their email")
+		}
+		if _, ok := searchUsers(t, alice, stranger.Phone[:8])[stranger.ID]; ok {
+			t.Error("stranger found by part of their phone number")
+		}
+	})
+
+	t.Run("others by exact email, without contact details", func(t *testing.T) {
+		got, ok := searchUsers(t, alice, "  "+strings.ToUpper(stranger.Email)+" ")[stranger.ID]
+		if !ok {
+			t.Fatal("stranger not found by exact email")
+		}
+		if got.IsConnection || got.Email != "" || got.Phone != "" {
+			t.Errorf("stranger result leaks contact details: %+v", got)
+		}
+	})
+
+	t.Run("others by exact phone in any format", func(t *testing.T) {
+		formatted := strings.ReplaceAll(stranger.Phone, " ", "-")
+		if _, ok := searchUsers(t, alice, formatted)[stranger.ID]; !ok {
+			t.Errorf("stranger not found by %q", formatted)
+		}
+	})
+
+	t.Run("opted out of email discovery", func(t *testing.T) {
+		setPrivacy(t, stranger, "privacy_discoverable_by_email", false)
+		if _, ok := searchUsers(t, alice, stranger.Email)[stranger.ID]; ok {
+			t.Error("found by email after opting out")
+		}
+		if _, ok := searchUsers(t, alice, stranger.Phone)[stranger.ID]; !ok {
+			t.Error("phone discovery should be unaffected")
+		}
+	})
+
+	t.Run("opted out of phone discovery", func(t *testing.T) {
+		setPrivacy(t, stranger, "privacy_discoverable_by_phone", false)
+		if _, ok := searchUsers(t, alice, stranger.Phone)[stranger.ID]; ok {
+			t.Error("found by phone after opting out")
+		}
+	})
+
+	t.Run("co-members regardless of discovery settings", func(t *testing.T) {
+		setPrivacy(t, friend, "privacy_discoverable_by_email", false)
+		if _, ok := searchUsers(t, alice, friend.Email)[friend.ID]; !ok {
+			t.Error("co-member hidden by their discovery settings")
+		}
+	})
+}
+
+func TestMatchContactsPrivacy(t *testing.T) {
+	requireDB(t)
+
+	alice := createTestUser(t, "Alice")
+	carol := createTestUser(t, "Carol")
+	setPhone(t, &carol, fmt.Sprintf("+44 7700 %06d", rand.Intn(1000000)))
+	emailHash := utils.HashContact(utils.NormalizeEmail(carol.Email))
+	phoneHash := utils.HashContact(utils.NormalizePhone(carol.Phone))
+
+	got, ok := matchContacts(t, alice, strings.ToUpper(emailHash))[carol.ID]
+	if !ok || got.MatchedHash != emailHash || got.Email != "" {
+		t.Fatalf("email hash match = %+v, found %v", got, ok)
+	}
+	if got := matchContacts(t, alice, phoneHash)[carol.ID]; got.MatchedHash != phoneHash {
+		t.Errorf("phone hash match = %+v", got)
+	}
+	if _, ok := matchContacts(t, carol, emailHash)[carol.ID]; ok {
+		t.Error("users match themselves")
+	}
+
+	// Per-channel discovery settings apply to contact matching too
+	setPrivacy(t, carol, "privacy_discoverable_by_email", false)
+	if _, ok := matchContacts(t, alice, emailHash)[carol.ID]; ok {
+		t.Error("matched by email hash after opting out of email discovery")
+	}
+	if _, ok := matchContacts(t, alice, phoneHash)[carol.ID]; !ok {
+		t.Error("phone hash should still match")
+	}
+
+	setPrivacy(t, carol, "privacy_contact_matching", false)
+	if _, ok := matchContacts(t, alice, emailHash, phoneHash)[carol.ID]; ok {
+		t.Error("matched after turning contact matching off")
+	}
+}
@@ -0,0 +1,324 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"splitwise-backend/database"
+	"splitwise-backend/models"
+	"splitwise-backend/services"
+	"splitwise-backend/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const maxWebhooksPerOwner = 10
+
+// POST /api/groups/:id/webhooks — group admins subscribe an endpoint to the group's events
+func CreateGroupWebhook(c *gin.Context) {
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid group ID")
+		return
+	}
+
+	if _, ok := authorize(c, groupID, models.PermManageWebhooks); !ok {
+		return
+	}
+
+	createWebhook(c, &groupID)
+}
+
+// GET /api/groups/:id/webhooks
+func GetGroupWebhooks(c *gin.Context) {
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid group ID")
+		return
+	}
+
+	if _, ok := authorize(c, groupID, models.PermManageWebhooks); !ok {
+		return
+	}
+
+	hooks := []models.Webhook{}
+	database.DB.Where("group_id = ?", groupID).Order("created_at DESC").Find(&hooks)
+	utils.SuccessResponse(c, http.StatusOK, "", hooks)
+}
+
+// POST /api/webhooks — personal webhook receiving events from all of the user's groups
+func CreateUserWebhook(c *gin.Context) {
+	createWebhook(c, nil)
+}
+
+// GET /api/webhooks — the user's personal webhooks
+func GetUserWebhooks(c *gin.Context) {
+	userID := utils.GetCurrentUserID(c)
+
+	hooks := []models.Webhook{}
+	database.DB.Where("user_id = ? AND group_id IS NULL", userID).Order("created_at DESC").Find(&hooks)
+	utils.SuccessResponse(c, http.StatusOK, "", hooks)
+}
+
+// PUT /api/webhooks/:id
+func UpdateWebhook(c *gin.Context) {
+	hook, ok := loadManagedWebhook(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if req.URL != nil {
+		if err := validateWebhookURL(*req.URL); err != nil {
+			utils.BadRequest(c, err.Error())
+			return
+		}
+		updates["url"] = *req.URL
+	}
+	if req.Events != nil {
+		if err := validateWebhookEvents(*req.Events); err != nil {
+			utils.BadRequest(c, err.Error())
+			return
+		}
+		updates["events"] = models.StringList(*req.Events)
+	}
+	if req.Active != nil {
+		updates["active"] = *req.Active
+	}
+
+	database.DB.Model(&hook).Updates(updates)
+	database.DB.First(&hook, hook.ID)
+
+	utils.SuccessResponse(c, http.StatusOK, "Webhook updated", hook)
+}
+
+// DELETE /api/webhooks/:id — also drops its delivery log
+func DeleteWebhook(c *gin.Context) {
+	hook, ok := loadManagedWebhook(c)
+	if !ok {
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("webhook_id = ?", hook.ID).Delete(&models.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&hook).Error
+	})
+	if err != nil {
+		utils.InternalError(c, "Failed to delete webhook")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Webhook deleted", nil)
+}
+
+// POST /api/webhooks/:id/ping — sends a test event now and returns the delivery result
+func PingWebhook(c *gin.Context) {
+	hook, ok := loadManagedWebhook(c)
+	if !ok {
+		return
+	}
+	if !hook.Active {
+		utils.BadRequest(c, "Enable the webhook before pinging it")
+		return
+	}
+
+	delivery, err := services.PingWebhook(hook)
+	if err != nil {
+		utils.InternalError(c, "Failed to send ping")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "", toDeliveryResponse(delivery))
+}
+
+// GET /api/webhooks/:id/deliveries — delivery log, newest first; ?status=dead lists the dead letters
+func GetWebhookDeliveries(c *gin.Context) {
+	hook, ok := loadManagedWebhook(c)
+	if !ok {
+		return
+	}
+
+	pagination, err := utils.BindPagination(c)
+	if err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	scope := func(db *gorm.DB) *gorm.DB {
+		db = db.Where("webhook_id = ?", hook.ID)
+		if status := c.Query("status"); status != "" {
+			db = db.Where("status = ?", status)
+		}
+		return db
+	}
+
+	var total int64
+	database.DB.Model(&models.WebhookDelivery{}).Scopes(scope).Count(&total)
+
+	var deliveries []models.WebhookDelivery
+	database.DB.Scopes(scope, pagination.Scope(createdAtKeyset)).
+		Order("created_at DESC, id DESC").
+		Find(&deliveries)
+
+	fetched := len(deliveries)
+	if fetched > pagination.Limit {
+		deliveries = deliveries[:pagination.Limit]
+	}
+
+	responses := []models.WebhookDeliveryResponse{}
+	var last utils.Cursor
+	for _, d := range deliveries {
+		responses = append(responses, toDeliveryResponse(d))
+		last = utils.Cursor{CreatedAt: d.CreatedAt, ID: d.ID}
+	}
+	utils.SuccessResponseWithMeta(c, http.StatusOK, "", responses, pagination.Meta(total, fetched, last))
+}
+
+// POST /api/webhooks/:id/deliveries/:deliveryId/redeliver — queue a delivery again, e.g. a dead letter
+func RedeliverWebhook(c *gin.Context) {
+	hook, ok := loadManagedWebhook(c)
+	if !ok {
+		return
+	}
+
+	deliveryID, err := uuid.Parse(c.Param("deliveryId"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid delivery ID")
+		return
+	}
+
+	var delivery models.WebhookDelivery
+	if err := database.DB.Where("id = ? AND webhook_id = ?", deliveryID, hook.ID).First(&delivery).Error; err != nil {
+		utils.NotFound(c, "Delivery not found")
+		return
+	}
+
+	database.DB.Model(&delivery).Updates(map[string]interface{}{
+		"status":          models.DeliveryPending,
+		"attempts":        0,
+		"next_attempt_at": time.Now(),
+	})
+	database.DB.First(&delivery, delivery.ID)
+
+	utils.SuccessResponse(c, http.StatusOK, "Delivery queued", toDeliveryResponse(delivery))
+}
+
+// Helper: shared by group and personal webhook creation
+func createWebhook(c *gin.Context, groupID *uuid.UUID) {
+	userID := utils.GetCurrentUserID(c)
+
+	var req models.CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+	if err := validateWebhookURL(req.URL); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+	if err := validateWebhookEvents(req.Events); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	var count int64
+	query := database.DB.Model(&models.Webhook{})
+	if groupID != nil {
+		query = query.Where("group_id = ?", *groupID)
+	} else {
+		query = query.Where("user_id = ? AND group_id IS NULL", userID)
+	}
+	query.Count(&count)
+	if count >= maxWebhooksPerOwner {
+		utils.BadRequest(c, fmt.Sprintf("At most %d webhooks are allowed", maxWebhooksPerOwner))
+		return
+	}
+
+	secret, err := utils.RandomToken(32)
+	if err != nil {
+		utils.InternalError(c, "Failed to create webhook")
+		return
+	}
+
+	hook := models.Webhook{
+		GroupID: groupID,
+		UserID:  userID,
+		URL:     req.URL,
+		Secret:  secret,
+		Events:  models.StringList(req.Events),
+		Active:  true,
+	}
+	if err := database.DB.Create(&hook).Error; err != nil {
+		utils.InternalError(c, "Failed to create webhook")
+		return
+	}
+
+	// The secret is only ever shown here
+	utils.SuccessResponse(c, http.StatusCreated, "Webhook created", models.WebhookResponse{Webhook: hook, Secret: secret})
+}
+
+// Helper: load :id and check the current user may manage it. Personal webhooks are
+// visible only to their owner; group webhooks to the group's admins.
+func loadManagedWebhook(c *gin.Context) (models.Webhook, bool) {
+	var hook models.Webhook
+
+	hookID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid webhook ID")
+		return hook, false
+	}
+
+	if err := database.DB.First(&hook, hookID).Error; err != nil {
+		utils.NotFound(c, "Webhook not found")
+		return hook, false
+	}
+
+	if hook.GroupID == nil {
+		if hook.UserID != utils.GetCurrentUserID(c) {
+			utils.NotFound(c, "Webhook not found")
+			return hook, false
+		}
+		return hook, true
+	}
+
+	_, ok := authorize(c, *hook.GroupID, models.PermManageWebhooks)
+	return hook, ok
+}
+
+// Helper: only absolute http(s) URLs; where they may point is checked when connecting
+func validateWebhookURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return fmt.Errorf("url must be an absolute http(s) URL")
+	}
+	return nil
+}
+
+func validateWebhookEvents(events []string) error {
+	known := make(map[string]bool, len(models.ActivityTypes))
+	for _, t := range models.ActivityTypes {
+		known[t] = true
+	}
+	for _, e := range events {
+		if !known[e] {
+			return fmt.Errorf("unknown event type \"%s\"", e)
+		}
+	}
+	return nil
+}
+
+func toDeliveryResponse(d models.WebhookDelivery) models.WebhookDeliveryResponse {
+	return models.WebhookDeliveryResponse{WebhookDelivery: d, Payload: json.RawMessage(d.Payload)}
+}
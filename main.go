@@ -1,16 +1,33 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
+	"net/http"
+	"os/signal"
 	"splitwise-backend/config"
 	"splitwise-backend/database"
+	"splitwise-backend/email"
 	"splitwise-backend/handlers"
 	"splitwise-backend/middleware"
+	"splitwise-backend/models"
+	"splitwise-backend/ocr"
+	"splitwise-backend/push"
+	"splitwise-backend/realtime"
+	"splitwise-backend/services"
+	"splitwise-backend/storage"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 func main() {
+	// Cancelled on SIGINT/SIGTERM; background workers stop and drain when it is
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
 	// Load configuration
 	config.Load()
 
@@ -20,6 +37,32 @@ func main() {
 	// Connect to Redis (optional, won't crash if unavailable)
 	database.ConnectRedis()
 
+	// Real-time events (fanned out through Redis when it's available)
+	realtime.Setup()
+
+	// Outgoing webhooks
+	services.SetupWebhooks()
+	webhooksDone := services.StartWebhookWorker(ctx)
+
+	// Push notifications (FCM, optional)
+	push.Setup()
+
+	// Email (SendGrid, SMTP, or a local sink for development)
+	email.Setup()
+
+	// Push and email notifications queued in the outbox
+	notificationsDone := services.StartNotificationWorker(ctx)
+
+	// Tombstones for delta sync expire along with the tokens that could need them
+	services.StartTombstonePruner(ctx)
+
+	// Receipt storage (local disk or S3-compatible)
+	storage.Connect()
+
+	// Receipt OCR (optional)
+	ocr.Setup()
+	services.StartOCRJobReaper(ctx)
+
 	// Setup router
 	r := gin.Default()
 	r.Use(middleware.CORSMiddleware())
@@ -41,6 +84,24 @@ func main() {
 		auth.POST("/login", handlers.Login)
 	}
 
+	// Invite link preview (public)
+	r.GET("/invites/:token", handlers.PreviewInvite)
+
+	// Email unsubscribe links (public, signed)
+	r.GET("/notifications/unsubscribe", handlers.UnsubscribePage)
+	r.POST("/notifications/unsubscribe", handlers.Unsubscribe)
+
+	// Captured emails (development sinks in debug mode only: the endpoints are public)
+	if inbox, ok := email.Default.(email.Inbox); ok {
+		if gin.Mode() == gin.DebugMode {
+			r.GET("/dev/emails", handlers.GetCapturedEmails(inbox))
+			r.GET("/dev/emails/:id", handlers.GetCapturedEmail(inbox))
+			log.Println("⚠️  Captured emails are readable at /dev/emails")
+		} else {
+			log.Printf("⚠️  Emails are captured but /dev/emails is off in %s mode", gin.Mode())
+		}
+	}
+
 	// ==========================================
 	// API ROUTES (authenticated)
 	// ==========================================
@@ -50,24 +111,79 @@ func main() {
 		// User
 		api.GET("/users/me", handlers.GetProfile)
 		api.PUT("/users/me", handlers.UpdateProfile)
+		api.DELETE("/users/me", handlers.DeleteAccount)
+		api.GET("/users/me/export", handlers.ExportUserData)
 		api.PUT("/users/me/fcm-token", handlers.UpdateFCMToken)
+		api.POST("/users/me/devices", handlers.RegisterDevice)
+		api.GET("/users/me/devices", handlers.GetDevices)
+		api.DELETE("/users/me/devices/:id", handlers.RemoveDevice)
 		api.POST("/users/search", handlers.SearchUsers)
+		api.POST("/users/contacts/match", handlers.MatchContacts)
+		api.GET("/users/me/privacy", handlers.GetPrivacySettings)
+		api.PUT("/users/me/privacy", handlers.UpdatePrivacySettings)
+		api.GET("/users/me/notification-preferences", handlers.GetNotificationPreferences)
+		api.PUT("/users/me/notification-preferences", handlers.UpdateNotificationPreferences)
 
 		// Groups
 		api.POST("/groups", handlers.CreateGroup)
 		api.GET("/groups", handlers.GetGroups)
 		api.GET("/groups/:id", handlers.GetGroup)
 		api.PUT("/groups/:id", handlers.UpdateGroup)
+		api.PUT("/groups/:id/settings", handlers.UpdateGroupSettings)
+		api.DELETE("/groups/:id", handlers.DeleteGroup)
+		api.POST("/groups/:id/archive", handlers.ArchiveGroup)
+		api.POST("/groups/:id/unarchive", handlers.UnarchiveGroup)
+		api.POST("/groups/:id/close", handlers.CloseGroup)
 		api.POST("/groups/:id/members", handlers.AddMember)
 		api.DELETE("/groups/:id/members/:uid", handlers.RemoveMember)
+		api.PUT("/groups/:id/members/:uid/role", handlers.UpdateMemberRole)
+		api.POST("/groups/:id/transfer-ownership", handlers.TransferOwnership)
 		api.POST("/groups/:id/invite", handlers.InviteToGroupHandler)
+		api.POST("/groups/:id/invite-links", handlers.CreateInviteLink)
+		api.GET("/groups/:id/invite-links", handlers.GetInviteLinks)
+		api.DELETE("/groups/:id/invite-links/:linkId", handlers.RevokeInviteLink)
+		api.POST("/invites/:token/join", handlers.JoinViaInviteLink)
+		api.GET("/groups/:id/invitations", handlers.GetGroupInvitations)
+		api.DELETE("/groups/:id/invitations/:invId", handlers.CancelInvitation)
+		api.GET("/groups/:id/notification-preferences", handlers.GetGroupNotificationPreferences)
+		api.PUT("/groups/:id/notification-preferences", handlers.UpdateGroupNotificationPreferences)
+		api.DELETE("/groups/:id/notification-preferences", handlers.ResetGroupNotificationPreferences)
+
+		// Invitations
+		api.GET("/invitations", handlers.GetMyInvitations)
+		api.POST("/invitations/:id/accept", handlers.AcceptInvitation)
+		api.POST("/invitations/:id/decline", handlers.DeclineInvitation)
 
 		// Expenses
 		api.POST("/groups/:id/expenses", handlers.CreateExpense)
 		api.GET("/groups/:id/expenses", handlers.GetGroupExpenses)
+		api.GET("/expenses", handlers.SearchExpenses)
 		api.GET("/expenses/:id", handlers.GetExpense)
 		api.PUT("/expenses/:id", handlers.UpdateExpense)
 		api.DELETE("/expenses/:id", handlers.DeleteExpense)
+		api.POST("/expenses/:id/receipt", handlers.UploadReceipt)
+		api.GET("/expenses/:id/receipt", handlers.GetReceipt)
+		api.DELETE("/expenses/:id/receipt", handlers.RemoveReceipt)
+		api.POST("/expenses/:id/receipt/scan", handlers.ScanExpenseReceipt)
+		api.POST("/groups/:id/receipts/scan", handlers.ScanReceipt)
+		api.GET("/ocr-jobs/:id", handlers.GetOCRJob)
+
+		// Comments & reactions
+		api.GET("/expenses/:id/comments", handlers.GetComments(models.TargetExpense))
+		api.POST("/expenses/:id/comments", handlers.CreateComment(models.TargetExpense))
+		api.DELETE("/expenses/:id/comments/:commentId", handlers.DeleteComment(models.TargetExpense))
+		api.GET("/settlements/:id/comments", handlers.GetComments(models.TargetSettlement))
+		api.POST("/settlements/:id/comments", handlers.CreateComment(models.TargetSettlement))
+		api.DELETE("/settlements/:id/comments/:commentId", handlers.DeleteComment(models.TargetSettlement))
+		api.GET("/expenses/:id/reactions", handlers.GetReactions(models.TargetExpense))
+		api.POST("/expenses/:id/reactions", handlers.AddReaction(models.TargetExpense))
+		api.DELETE("/expenses/:id/reactions", handlers.RemoveReaction(models.TargetExpense))
+		api.GET("/settlements/:id/reactions", handlers.GetReactions(models.TargetSettlement))
+		api.POST("/settlements/:id/reactions", handlers.AddReaction(models.TargetSettlement))
+		api.DELETE("/settlements/:id/reactions", handlers.RemoveReaction(models.TargetSettlement))
+		api.GET("/comments/:id/reactions", handlers.GetReactions(models.TargetComment))
+		api.POST("/comments/:id/reactions", handlers.AddReaction(models.TargetComment))
+		api.DELETE("/comments/:id/reactions", handlers.RemoveReaction(models.TargetComment))
 
 		// Balances
 		api.GET("/groups/:id/balances", handlers.GetGroupBalances)
@@ -80,6 +196,29 @@ func main() {
 		// Activity
 		api.GET("/activity", handlers.GetActivity)
 		api.GET("/groups/:id/activity", handlers.GetGroupActivity)
+
+		// Notification inbox
+		api.GET("/notifications", handlers.GetNotifications)
+		api.GET("/notifications/unread-count", handlers.GetUnreadNotificationCount)
+		api.POST("/notifications/read", handlers.MarkNotificationsRead)
+
+		// Sync
+		api.GET("/sync", handlers.Sync)
+		api.POST("/sync/push", handlers.PushMutations(r))
+
+		// Real-time events
+		api.GET("/stream", handlers.Stream)
+
+		// Webhooks
+		api.POST("/groups/:id/webhooks", handlers.CreateGroupWebhook)
+		api.GET("/groups/:id/webhooks", handlers.GetGroupWebhooks)
+		api.POST("/webhooks", handlers.CreateUserWebhook)
+		api.GET("/webhooks", handlers.GetUserWebhooks)
+		api.PUT("/webhooks/:id", handlers.UpdateWebhook)
+		api.DELETE("/webhooks/:id", handlers.DeleteWebhook)
+		api.POST("/webhooks/:id/ping", handlers.PingWebhook)
+		api.GET("/webhooks/:id/deliveries", handlers.GetWebhookDeliveries)
+		api.POST("/webhooks/:id/deliveries/:deliveryId/redeliver", handlers.RedeliverWebhook)
 	}
 
 	// Start server
@@ -89,7 +228,33 @@ func main() {
 
 	addr := "0.0.0.0:" + port
 	log.Printf("🚀 Listening on %s", addr)
-	if err := r.Run(addr); err != nil {
-		log.Fatal("Failed to start server:", err)
+
+	srv := &http.Server{Addr: addr, Handler: r}
+	srv.RegisterOnShutdown(realtime.Default.Close) // end open event streams so Shutdown doesn't wait on them
+
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal("Failed to start server:", err)
+		}
+	}()
+
+	<-ctx.Done()
+	stop() // a second signal kills the process right away
+	log.Println("🛑 Shutting down, finishing in-flight requests and notifications...")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("⚠️  Server shutdown: %v", err)
+	}
+	for _, done := range []<-chan struct{}{notificationsDone, webhooksDone} {
+		select {
+		case <-done:
+		case <-shutdownCtx.Done():
+			log.Println("⚠️  Workers did not finish in time, unsent messages are retried on next start")
+			return
+		}
 	}
+	log.Println("✅ Stopped")
 }
@@ -2,6 +2,8 @@ package middleware
 
 import (
 	"net/http"
+	"splitwise-backend/database"
+	"splitwise-backend/models"
 	"splitwise-backend/utils"
 	"strings"
 
@@ -41,6 +43,18 @@ func AuthRequired() gin.HandlerFunc {
 			return
 		}
 
+		// Tokens outlive account deletion: refuse them once the account is gone
+		var active int64
+		database.DB.Model(&models.User{}).Where("id = ? AND anonymized_at IS NULL", claims.UserID).Count(&active)
+		if active == 0 {
+			c.JSON(http.StatusUnauthorized, utils.APIResponse{
+				Success: false,
+				Message: "Account no longer exists",
+			})
+			c.Abort()
+			return
+		}
+
 		// Set user info in context
 		c.Set("user_id", claims.UserID)
 		c.Set("user_email", claims.Email)
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserDataExport is the JSON document included in GET /api/users/me/export
+type UserDataExport struct {
+	ExportedAt  time.Time      `json:"exported_at"`
+	Profile     UserResponse   `json:"profile"`
+	Groups      []Group        `json:"groups"`
+	Expenses    []Expense      `json:"expenses"`
+	Splits      []ExpenseSplit `json:"splits"`
+	Settlements []Settlement   `json:"settlements"`
+	Activity    []Activity     `json:"activity"`
+}
+
+// OutstandingBalance is a non-zero net balance that blocks account deletion
+type OutstandingBalance struct {
+	GroupID   uuid.UUID `json:"group_id"`
+	GroupName string    `json:"group_name"`
+	Amount    float64   `json:"amount"` // positive = others owe you, negative = you owe others
+}
+
+type DeleteAccountRequest struct {
+	Password string `json:"password" binding:"required"`
+}
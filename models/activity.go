@@ -8,15 +8,25 @@ import (
 )
 
 type Activity struct {
-	ID          uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
-	GroupID     uuid.UUID `gorm:"type:uuid;index" json:"group_id"`
-	GroupName   string    `gorm:"-" json:"group_name,omitempty"`
-	UserID      uuid.UUID `gorm:"type:uuid" json:"user_id"`
-	User        User      `gorm:"foreignKey:UserID" json:"user,omitempty"`
-	Type        string    `gorm:"not null;size:30" json:"type"` // expense_added, expense_updated, expense_deleted, settlement, member_joined, member_left
-	ReferenceID uuid.UUID `gorm:"type:uuid" json:"reference_id,omitempty"`
-	Description string    `json:"description"`
-	CreatedAt   time.Time `json:"created_at"`
+	ID          uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	GroupID     uuid.UUID  `gorm:"type:uuid;index" json:"group_id"`
+	GroupName   string     `gorm:"-" json:"group_name,omitempty"`
+	UserID      uuid.UUID  `gorm:"type:uuid" json:"user_id"`
+	User        User       `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	Type        string     `gorm:"not null;size:30" json:"type"` // expense_added, expense_updated, expense_deleted, settlement, member_joined, member_left, comment_added
+	ReferenceID uuid.UUID  `gorm:"type:uuid" json:"reference_id,omitempty"`
+	MemberID    *uuid.UUID `gorm:"type:uuid" json:"member_id,omitempty"` // who joined or left, on membership activities
+	Description string     `json:"description"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ChangeSeq   int64      `gorm:"index;not null;default:0" json:"-"`
+}
+
+// ActivityTypes lists every Activity.Type, e.g. for choosing webhook events
+var ActivityTypes = []string{
+	"group_created", "group_archived", "group_restored", "group_closing",
+	"member_joined", "member_left", "role_changed", "ownership_transferred",
+	"expense_added", "expense_updated", "expense_deleted",
+	"settlement", "comment_added",
 }
 
 func (a *Activity) BeforeCreate(tx *gorm.DB) error {
@@ -25,3 +35,37 @@ func (a *Activity) BeforeCreate(tx *gorm.DB) error {
 	}
 	return nil
 }
+
+// ActivityEvent is how an activity is described in real-time events and webhook payloads
+type ActivityEvent struct {
+	ActivityID  uuid.UUID  `json:"activity_id"`
+	UserID      uuid.UUID  `json:"user_id"`
+	ReferenceID uuid.UUID  `json:"reference_id,omitempty"`
+	MemberID    *uuid.UUID `json:"member_id,omitempty"`
+	Description string     `json:"description"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+func (a *Activity) Event() ActivityEvent {
+	return ActivityEvent{
+		ActivityID:  a.ID,
+		UserID:      a.UserID,
+		ReferenceID: a.ReferenceID,
+		MemberID:    a.MemberID,
+		Description: a.Description,
+		CreatedAt:   a.CreatedAt,
+	}
+}
+
+// ActivitiesIn returns the activities written by a GORM create, given the statement's Dest
+func ActivitiesIn(dest interface{}) []Activity {
+	switch dest := dest.(type) {
+	case *Activity:
+		return []Activity{*dest}
+	case []Activity:
+		return dest
+	case *[]Activity:
+		return *dest
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// What a comment or reaction is attached to
+const (
+	TargetExpense    = "expense"
+	TargetSettlement = "settlement"
+	TargetComment    = "comment"
+)
+
+// Comment is a message on an expense or settlement. Replies point at their parent.
+type Comment struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	GroupID    uuid.UUID  `gorm:"type:uuid;index" json:"group_id"`
+	TargetType string     `gorm:"size:20;not null;index:idx_comment_target" json:"target_type"` // expense, settlement
+	TargetID   uuid.UUID  `gorm:"type:uuid;not null;index:idx_comment_target" json:"target_id"`
+	ParentID   *uuid.UUID `gorm:"type:uuid;index" json:"parent_id,omitempty"`
+	UserID     uuid.UUID  `gorm:"type:uuid" json:"user_id"`
+	User       User       `gorm:"foreignKey:UserID" json:"-"`
+	Body       string     `gorm:"type:text;not null" json:"body"`
+	Mentions   StringList `gorm:"type:jsonb" json:"mentions"` // user IDs
+	DeletedAt  *time.Time `json:"deleted_at,omitempty"`       // deleted comments with replies stay as placeholders
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+func (cm *Comment) BeforeCreate(tx *gorm.DB) error {
+	if cm.ID == uuid.Nil {
+		cm.ID = uuid.New()
+	}
+	return nil
+}
+
+// Reaction is one user's emoji on an expense, settlement or comment
+type Reaction struct {
+	TargetType string    `gorm:"primaryKey;size:20" json:"target_type"`
+	TargetID   uuid.UUID `gorm:"type:uuid;primaryKey" json:"target_id"`
+	UserID     uuid.UUID `gorm:"type:uuid;primaryKey" json:"user_id"`
+	Emoji      string    `gorm:"primaryKey;size:32" json:"emoji"`
+	GroupID    uuid.UUID `gorm:"type:uuid;index" json:"group_id"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Request structs
+type CreateCommentRequest struct {
+	Body     string   `json:"body" binding:"required,max=2000"`
+	ParentID string   `json:"parent_id"`
+	Mentions []string `json:"mentions"` // user IDs of mentioned group members
+}
+
+type ReactionRequest struct {
+	Emoji string `json:"emoji" binding:"required"`
+}
+
+// Response structs
+type CommentResponse struct {
+	ID        uuid.UUID          `json:"id"`
+	ParentID  *uuid.UUID         `json:"parent_id,omitempty"`
+	UserID    uuid.UUID          `json:"user_id"`
+	UserName  string             `json:"user_name"`
+	AvatarURL string             `json:"avatar_url,omitempty"`
+	Body      string             `json:"body"`
+	Mentions  []string           `json:"mentions"`
+	Deleted   bool               `json:"deleted"`
+	Reactions []ReactionSummary  `json:"reactions"`
+	Replies   []*CommentResponse `json:"replies"`
+	CreatedAt time.Time          `json:"created_at"`
+}
+
+type ReactionSummary struct {
+	Emoji   string      `json:"emoji"`
+	Count   int         `json:"count"`
+	Reacted bool        `json:"reacted"` // whether the current user used this emoji
+	UserIDs []uuid.UUID `json:"user_ids"`
+}
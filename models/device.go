@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// DeviceToken is an FCM registration token for one of a user's devices.
+// Tokens FCM reports as unregistered are deleted when a push to them fails.
+type DeviceToken struct {
+	ID         uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID     uuid.UUID `gorm:"type:uuid;not null;index" json:"-"`
+	Token      string    `gorm:"type:text;not null;uniqueIndex" json:"-"`
+	Platform   string    `gorm:"size:10" json:"platform,omitempty"` // ios, android or web
+	LastSeenAt time.Time `json:"last_seen_at"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+func (d *DeviceToken) BeforeCreate(tx *gorm.DB) error {
+	if d.ID == uuid.Nil {
+		d.ID = uuid.New()
+	}
+	return nil
+}
+
+// Request structs
+type RegisterDeviceRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Platform string `json:"platform" binding:"omitempty,oneof=ios android web"`
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"splitwise-backend/utils"
 	"time"
 
 	"github.com/google/uuid"
@@ -8,22 +9,27 @@ import (
 )
 
 type Expense struct {
-	ID          uuid.UUID      `gorm:"type:uuid;primaryKey" json:"id"`
-	GroupID     uuid.UUID      `gorm:"type:uuid;index" json:"group_id"`
-	Group       Group          `gorm:"foreignKey:GroupID" json:"-"`
-	PaidBy      uuid.UUID      `gorm:"type:uuid" json:"paid_by"`
-	Payer       User           `gorm:"foreignKey:PaidBy" json:"payer,omitempty"`
-	Description string         `gorm:"not null;size:255" json:"description"`
-	Amount      float64        `gorm:"type:decimal(12,2);not null" json:"amount"`
-	Currency    string         `gorm:"default:INR;size:3" json:"currency"`
-	Category    string         `gorm:"size:50" json:"category"` // food, transport, rent, utilities, entertainment, other
-	SplitType   string         `gorm:"not null;size:20" json:"split_type"` // equal, exact, percentage, shares
-	ReceiptURL  string         `json:"receipt_url,omitempty"`
-	Notes       string         `json:"notes,omitempty"`
-	ExpenseDate time.Time      `gorm:"type:date;default:CURRENT_DATE" json:"expense_date"`
-	Splits      []ExpenseSplit `gorm:"foreignKey:ExpenseID" json:"splits,omitempty"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
+	ID                 uuid.UUID      `gorm:"type:uuid;primaryKey" json:"id"`
+	GroupID            uuid.UUID      `gorm:"type:uuid;index" json:"group_id"`
+	Group              Group          `gorm:"foreignKey:GroupID" json:"-"`
+	PaidBy             uuid.UUID      `gorm:"type:uuid" json:"paid_by"`
+	Payer              User           `gorm:"foreignKey:PaidBy" json:"payer,omitempty"`
+	Description        string         `gorm:"not null;size:255" json:"description"`
+	Amount             float64        `gorm:"type:decimal(12,2);not null" json:"amount"`
+	Currency           string         `gorm:"default:INR;size:3" json:"currency"`
+	Category           string         `gorm:"size:50" json:"category"`            // food, transport, rent, utilities, entertainment, other
+	SplitType          string         `gorm:"not null;size:20" json:"split_type"` // equal, exact, percentage, shares, adjustment
+	ReceiptURL         string         `json:"receipt_url,omitempty"`
+	ReceiptKey         string         `json:"-"` // storage key of the uploaded file
+	ReceiptThumbKey    string         `json:"-"`
+	ReceiptContentType string         `gorm:"size:50" json:"-"`
+	Notes              string         `json:"notes,omitempty"`
+	ExpenseDate        time.Time      `gorm:"type:date;default:CURRENT_DATE" json:"expense_date"`
+	Splits             []ExpenseSplit `gorm:"foreignKey:ExpenseID" json:"splits,omitempty"`
+	CreatedAt          time.Time      `json:"created_at"`
+	UpdatedAt          time.Time      `json:"updated_at"`
+	ChangeSeq          int64          `gorm:"index;not null;default:0" json:"-"` // bumped by a database trigger on every write (delta sync)
+	Version            int            `gorm:"not null;default:1" json:"version"` // incremented on every edit, sent as the ETag
 }
 
 func (e *Expense) BeforeCreate(tx *gorm.DB) error {
@@ -41,6 +47,7 @@ type ExpenseSplit struct {
 	OwedAmount float64   `gorm:"type:decimal(12,2);not null" json:"owed_amount"`
 	PaidAmount float64   `gorm:"type:decimal(12,2);default:0" json:"paid_amount"`
 	CreatedAt  time.Time `json:"created_at"`
+	ChangeSeq  int64     `gorm:"index;not null;default:0" json:"-"`
 }
 
 func (es *ExpenseSplit) BeforeCreate(tx *gorm.DB) error {
@@ -52,46 +59,52 @@ func (es *ExpenseSplit) BeforeCreate(tx *gorm.DB) error {
 
 // Request structs
 type CreateExpenseRequest struct {
-	GroupID     string        `json:"group_id" binding:"required"`
-	Description string        `json:"description" binding:"required"`
-	Amount      float64       `json:"amount" binding:"required,gt=0"`
-	Currency    string        `json:"currency"`
-	Category    string        `json:"category"`
-	SplitType   string        `json:"split_type" binding:"required,oneof=equal exact percentage shares"`
-	Notes       string        `json:"notes"`
-	ExpenseDate string        `json:"expense_date"` // YYYY-MM-DD
-	Splits      []SplitInput  `json:"splits"`       // required for exact, percentage, shares
+	ID           string       `json:"id,omitempty"` // optional client-generated UUID; retries with the same ID don't duplicate
+	GroupID      string       `json:"group_id" binding:"required"`
+	Description  string       `json:"description" binding:"required"`
+	Amount       float64      `json:"amount" binding:"required,gt=0"`
+	Currency     string       `json:"currency"`
+	Category     string       `json:"category"`
+	SplitType    string       `json:"split_type" binding:"omitempty,oneof=equal exact percentage shares adjustment"` // defaults to the group's setting
+	Notes        string       `json:"notes"`
+	ExpenseDate  string       `json:"expense_date"` // YYYY-MM-DD
+	Splits       []SplitInput `json:"splits"`       // required for exact, percentage, shares unless the group has default splits
+	Participants []string     `json:"participants"` // user IDs for an equal split; empty = all active members
 }
 
 type SplitInput struct {
 	UserID string  `json:"user_id" binding:"required"`
-	Value  float64 `json:"value"` // exact amount, percentage, or share count
+	Value  float64 `json:"value"` // exact amount, percentage, share count, or +/- adjustment
 }
 
 type UpdateExpenseRequest struct {
-	Description string       `json:"description"`
-	Amount      float64      `json:"amount"`
-	Category    string       `json:"category"`
-	SplitType   string       `json:"split_type"`
-	Notes       string       `json:"notes"`
-	Splits      []SplitInput `json:"splits"`
+	Description  string       `json:"description"`
+	Amount       float64      `json:"amount"`
+	Category     string       `json:"category"`
+	SplitType    string       `json:"split_type"`
+	Notes        string       `json:"notes"`
+	Splits       []SplitInput `json:"splits"`
+	Participants []string     `json:"participants"` // equal split only; omitted keeps the current participants
 }
 
 // Response
 type ExpenseResponse struct {
-	ID          uuid.UUID            `json:"id"`
-	GroupID     uuid.UUID            `json:"group_id"`
-	PaidBy      uuid.UUID            `json:"paid_by"`
-	PayerName   string               `json:"payer_name"`
-	Description string               `json:"description"`
-	Amount      float64              `json:"amount"`
-	Currency    string               `json:"currency"`
-	Category    string               `json:"category"`
-	SplitType   string               `json:"split_type"`
-	Notes       string               `json:"notes,omitempty"`
-	ExpenseDate time.Time            `json:"expense_date"`
-	Splits      []SplitResponse      `json:"splits"`
-	CreatedAt   time.Time            `json:"created_at"`
+	ID              uuid.UUID       `json:"id"`
+	GroupID         uuid.UUID       `json:"group_id"`
+	PaidBy          uuid.UUID       `json:"paid_by"`
+	PayerName       string          `json:"payer_name"`
+	Description     string          `json:"description"`
+	Amount          float64         `json:"amount"`
+	Currency        string          `json:"currency"`
+	Category        string          `json:"category"`
+	SplitType       string          `json:"split_type"`
+	Notes           string          `json:"notes,omitempty"`
+	ReceiptURL      string          `json:"receipt_url,omitempty"`
+	ReceiptThumbURL string          `json:"receipt_thumb_url,omitempty"`
+	ExpenseDate     time.Time       `json:"expense_date"`
+	Splits          []SplitResponse `json:"splits"`
+	Version         int             `json:"version"`
+	CreatedAt       time.Time       `json:"created_at"`
 }
 
 type SplitResponse struct {
@@ -100,3 +113,39 @@ type SplitResponse struct {
 	OwedAmount float64   `json:"owed_amount"`
 	PaidAmount float64   `json:"paid_amount"`
 }
+
+// ExpenseFilter is the query string accepted by the expense list endpoints
+type ExpenseFilter struct {
+	Query       string  `form:"q"`        // full-text search over description and notes
+	GroupID     string  `form:"group_id"` // cross-group endpoint only
+	Category    string  `form:"category"`
+	PaidBy      string  `form:"paid_by"`     // user ID
+	Participant string  `form:"participant"` // user ID with a share in the expense
+	MinAmount   float64 `form:"min_amount"`
+	MaxAmount   float64 `form:"max_amount"`
+	From        string  `form:"from"` // YYYY-MM-DD, inclusive
+	To          string  `form:"to"`   // YYYY-MM-DD, inclusive
+	Currency    string  `form:"currency"`
+	InvolvesMe  bool    `form:"involves_me"` // I paid or have a share
+	IPaid       bool    `form:"i_paid"`
+}
+
+// ExpenseListMeta is the response metadata of the expense list endpoints
+type ExpenseListMeta struct {
+	utils.PageMeta
+	Totals ExpenseTotals `json:"totals"`
+}
+
+// ExpenseTotals summarizes a filtered set of expenses, per currency
+type ExpenseTotals struct {
+	Count      int64           `json:"count"`
+	ByCurrency []CurrencyTotal `json:"by_currency"`
+}
+
+type CurrencyTotal struct {
+	Currency string  `json:"currency"`
+	Count    int64   `json:"count"`
+	Amount   float64 `json:"amount"`   // sum of expense amounts
+	MyShare  float64 `json:"my_share"` // what the current user owes across these expenses
+	IPaid    float64 `json:"i_paid"`   // what the current user paid
+}
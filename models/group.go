@@ -8,15 +8,23 @@ import (
 )
 
 type Group struct {
-	ID        uuid.UUID     `gorm:"type:uuid;primaryKey" json:"id"`
-	Name      string        `gorm:"not null;size:100" json:"name"`
-	Type      string        `gorm:"default:other;size:20" json:"type"` // home, trip, couple, other
-	ImageURL  string        `json:"image_url,omitempty"`
-	CreatedBy uuid.UUID     `gorm:"type:uuid" json:"created_by"`
-	Creator   User          `gorm:"foreignKey:CreatedBy" json:"creator,omitempty"`
-	Members   []GroupMember `gorm:"foreignKey:GroupID" json:"members,omitempty"`
-	CreatedAt time.Time     `json:"created_at"`
-	UpdatedAt time.Time     `json:"updated_at"`
+	ID                uuid.UUID     `gorm:"type:uuid;primaryKey" json:"id"`
+	Name              string        `gorm:"not null;size:100" json:"name"`
+	Type              string        `gorm:"default:other;size:20" json:"type"` // home, trip, couple, other
+	ImageURL          string        `json:"image_url,omitempty"`
+	CreatedBy         uuid.UUID     `gorm:"type:uuid" json:"created_by"`
+	Creator           User          `gorm:"foreignKey:CreatedBy" json:"creator,omitempty"`
+	Members           []GroupMember `gorm:"foreignKey:GroupID" json:"members,omitempty"`
+	ExpenseEditPolicy string        `gorm:"default:payer_or_admin;size:20" json:"expense_edit_policy"` // payer_or_admin, members
+	MemberAddPolicy   string        `gorm:"default:members;size:20" json:"member_add_policy"`          // members, admins
+	JoinPolicy        string        `gorm:"default:auto;size:20" json:"join_policy"`                   // auto, consent
+	ArchivedAt        *time.Time    `gorm:"index" json:"archived_at,omitempty"`                        // archived groups are read-only
+	ClosingAt         *time.Time    `json:"closing_at,omitempty"`                                      // set by "close trip" until final transfers are recorded
+	Settings          GroupSettings `gorm:"embedded;embeddedPrefix:settings_" json:"settings"`
+	CreatedAt         time.Time     `json:"created_at"`
+	UpdatedAt         time.Time     `json:"updated_at"`
+	ChangeSeq         int64         `gorm:"index;not null;default:0" json:"-"` // bumped by a database trigger on every write (delta sync)
+	Version           int           `gorm:"not null;default:1" json:"version"` // optimistic locking, see UpdateGroup
 }
 
 func (g *Group) BeforeCreate(tx *gorm.DB) error {
@@ -26,19 +34,101 @@ func (g *Group) BeforeCreate(tx *gorm.DB) error {
 	return nil
 }
 
+// Allows checks a permission against the role table and this group's policies
+func (g *Group) Allows(role string, perm Permission) bool {
+	if !RoleHasPermission(role, perm) {
+		return false
+	}
+
+	switch perm {
+	case PermAddMember, PermInvite:
+		if g.MemberAddPolicy == MemberAddAdmins {
+			return RoleAtLeast(role, RoleAdmin)
+		}
+	case PermAddExpense:
+		if g.Settings.AdminsOnlyExpenses {
+			return RoleAtLeast(role, RoleAdmin)
+		}
+	}
+	return true
+}
+
+// IsArchived reports whether the group is archived (read-only)
+func (g *Group) IsArchived() bool {
+	return g.ArchivedAt != nil
+}
+
+// AllowsMember is Allows for a specific membership.
+// Former members can only view and settle; archived groups only allow viewing, restoring and deleting.
+func (g *Group) AllowsMember(member GroupMember, perm Permission) bool {
+	if !member.IsActive() && perm != PermViewGroup && perm != PermSettle {
+		return false
+	}
+	if g.IsArchived() && perm != PermViewGroup && perm != PermArchiveGroup && perm != PermDeleteGroup {
+		return false
+	}
+	return g.Allows(member.Role, perm)
+}
+
+// CanModifyExpense reports whether member may edit or delete the expense
+func (g *Group) CanModifyExpense(member GroupMember, expense Expense) bool {
+	if g.AllowsMember(member, PermEditAnyExpense) {
+		return true
+	}
+	if !g.AllowsMember(member, PermAddExpense) {
+		return false
+	}
+	if g.ExpenseEditPolicy == ExpenseEditMembers {
+		return true
+	}
+	return expense.PaidBy == member.UserID
+}
+
 type GroupMember struct {
-	GroupID  uuid.UUID `gorm:"type:uuid;primaryKey" json:"group_id"`
-	UserID   uuid.UUID `gorm:"type:uuid;primaryKey" json:"user_id"`
-	User     User      `gorm:"foreignKey:UserID" json:"user,omitempty"`
-	Role     string    `gorm:"default:member;size:20" json:"role"` // admin, member
-	JoinedAt time.Time `gorm:"autoCreateTime" json:"joined_at"`
+	GroupID   uuid.UUID  `gorm:"type:uuid;primaryKey" json:"group_id"`
+	UserID    uuid.UUID  `gorm:"type:uuid;primaryKey" json:"user_id"`
+	User      User       `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	Role      string     `gorm:"default:member;size:20" json:"role"`   // owner, admin, member, viewer
+	Status    string     `gorm:"default:active;size:20" json:"status"` // active, former
+	JoinedAt  time.Time  `gorm:"autoCreateTime" json:"joined_at"`
+	LeftAt    *time.Time `json:"left_at,omitempty"`
+	ChangeSeq int64      `gorm:"index;not null;default:0" json:"-"`
+}
+
+// Membership statuses. Former members keep read access and can still settle up.
+const (
+	MemberActive = "active"
+	MemberFormer = "former"
+)
+
+func (m *GroupMember) IsActive() bool {
+	return m.Status != MemberFormer
 }
 
 // Request structs
 type CreateGroupRequest struct {
-	Name    string   `json:"name" binding:"required"`
-	Type    string   `json:"type"`
-	Members []string `json:"members"` // list of user IDs or emails
+	ID         string   `json:"id"` // optional, chosen by offline clients
+	Name       string   `json:"name" binding:"required"`
+	Type       string   `json:"type"`
+	Members    []string `json:"members"` // list of user IDs or emails
+	JoinPolicy string   `json:"join_policy" binding:"omitempty,oneof=auto consent"`
+}
+
+type UpdateGroupRequest struct {
+	Name              string `json:"name"`
+	Type              string `json:"type"`
+	ImageURL          string `json:"image_url"`
+	ExpenseEditPolicy string `json:"expense_edit_policy" binding:"omitempty,oneof=payer_or_admin members"`
+	MemberAddPolicy   string `json:"member_add_policy" binding:"omitempty,oneof=members admins"`
+	JoinPolicy        string `json:"join_policy" binding:"omitempty,oneof=auto consent"`
+}
+
+type UpdateMemberRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=admin member viewer"`
+}
+
+type TransferOwnershipRequest struct {
+	UserID string `json:"user_id" binding:"required"`
 }
 
 type AddMemberRequest struct {
@@ -49,20 +139,29 @@ type AddMemberRequest struct {
 
 // Response structs
 type GroupResponse struct {
-	ID        uuid.UUID            `json:"id"`
-	Name      string               `json:"name"`
-	Type      string               `json:"type"`
-	ImageURL  string               `json:"image_url,omitempty"`
-	CreatedBy uuid.UUID            `json:"created_by"`
-	Members   []GroupMemberResponse `json:"members"`
-	CreatedAt time.Time            `json:"created_at"`
+	ID                uuid.UUID             `json:"id"`
+	Name              string                `json:"name"`
+	Type              string                `json:"type"`
+	ImageURL          string                `json:"image_url,omitempty"`
+	CreatedBy         uuid.UUID             `json:"created_by"`
+	Members           []GroupMemberResponse `json:"members"`
+	ExpenseEditPolicy string                `json:"expense_edit_policy"`
+	MemberAddPolicy   string                `json:"member_add_policy"`
+	JoinPolicy        string                `json:"join_policy"`
+	ArchivedAt        *time.Time            `json:"archived_at,omitempty"`
+	ClosingAt         *time.Time            `json:"closing_at,omitempty"`
+	Settings          GroupSettings         `json:"settings"`
+	Version           int                   `json:"version"`
+	CreatedAt         time.Time             `json:"created_at"`
 }
 
 type GroupMemberResponse struct {
-	UserID   uuid.UUID `json:"user_id"`
-	Name     string    `json:"name"`
-	Email    string    `json:"email"`
-	AvatarURL string   `json:"avatar_url,omitempty"`
-	Role     string    `json:"role"`
-	JoinedAt time.Time `json:"joined_at"`
+	UserID    uuid.UUID  `json:"user_id"`
+	Name      string     `json:"name"`
+	Email     string     `json:"email"`
+	AvatarURL string     `json:"avatar_url,omitempty"`
+	Role      string     `json:"role"`
+	Status    string     `json:"status"`
+	JoinedAt  time.Time  `json:"joined_at"`
+	LeftAt    *time.Time `json:"left_at,omitempty"`
 }
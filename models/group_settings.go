@@ -0,0 +1,81 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// GroupSettings are per-group defaults applied when an expense request leaves fields out
+type GroupSettings struct {
+	DefaultCurrency    string       `gorm:"size:3" json:"default_currency,omitempty"`
+	DefaultSplitType   string       `gorm:"default:equal;size:20" json:"default_split_type"` // equal, percentage, shares
+	DefaultSplits      SplitWeights `gorm:"type:jsonb" json:"default_splits"`                // weights for percentage/shares, e.g. 60/40
+	AllowedCategories  StringList   `gorm:"type:jsonb" json:"allowed_categories"`            // empty = any category
+	AdminsOnlyExpenses bool         `gorm:"default:false" json:"admins_only_expenses"`
+}
+
+// AllowsCategory reports whether an expense category is permitted in the group
+func (s GroupSettings) AllowsCategory(category string) bool {
+	if category == "" || len(s.AllowedCategories) == 0 {
+		return true
+	}
+	for _, allowed := range s.AllowedCategories {
+		if allowed == category {
+			return true
+		}
+	}
+	return false
+}
+
+// SplitWeights is a list of per-member split values stored as JSON
+type SplitWeights []SplitInput
+
+func (w SplitWeights) Value() (driver.Value, error) {
+	if w == nil {
+		return "[]", nil
+	}
+	b, err := json.Marshal(w)
+	return string(b), err
+}
+
+func (w *SplitWeights) Scan(value interface{}) error {
+	return scanJSON(value, w)
+}
+
+// StringList is a list of strings stored as JSON
+type StringList []string
+
+func (l StringList) Value() (driver.Value, error) {
+	if l == nil {
+		return "[]", nil
+	}
+	b, err := json.Marshal(l)
+	return string(b), err
+}
+
+func (l *StringList) Scan(value interface{}) error {
+	return scanJSON(value, l)
+}
+
+func scanJSON(value interface{}, dest interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return json.Unmarshal(v, dest)
+	case string:
+		return json.Unmarshal([]byte(v), dest)
+	default:
+		return fmt.Errorf("cannot scan %T into JSON column", value)
+	}
+}
+
+// Request structs
+type UpdateGroupSettingsRequest struct {
+	DefaultCurrency    *string       `json:"default_currency" binding:"omitempty,len=3"`
+	DefaultSplitType   *string       `json:"default_split_type" binding:"omitempty,oneof=equal percentage shares"`
+	DefaultSplits      *[]SplitInput `json:"default_splits"`
+	AllowedCategories  *[]string     `json:"allowed_categories"`
+	AdminsOnlyExpenses *bool         `json:"admins_only_expenses"`
+}
@@ -7,22 +7,32 @@ import (
 	"gorm.io/gorm"
 )
 
+// Invitations are valid for this long unless accepted, declined or cancelled first
+const InvitationTTL = 30 * 24 * time.Hour
+
 type Invitation struct {
-	ID        uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
-	GroupID   uuid.UUID `gorm:"type:uuid;index" json:"group_id"`
-	Group     Group     `gorm:"foreignKey:GroupID" json:"group,omitempty"`
-	InvitedBy uuid.UUID `gorm:"type:uuid" json:"invited_by"`
-	Inviter   User      `gorm:"foreignKey:InvitedBy" json:"inviter,omitempty"`
-	Email     string    `gorm:"size:255" json:"email,omitempty"`
-	Phone     string    `gorm:"size:20" json:"phone,omitempty"`
-	Status    string    `gorm:"default:pending;size:20" json:"status"` // pending, accepted, declined
-	CreatedAt time.Time `json:"created_at"`
+	ID          uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	GroupID     uuid.UUID  `gorm:"type:uuid;index" json:"group_id"`
+	Group       Group      `gorm:"foreignKey:GroupID" json:"group,omitempty"`
+	InvitedBy   uuid.UUID  `gorm:"type:uuid" json:"invited_by"`
+	Inviter     User       `gorm:"foreignKey:InvitedBy" json:"inviter,omitempty"`
+	InviteeID   *uuid.UUID `gorm:"type:uuid;index" json:"invitee_id,omitempty"` // set once the invitee has an account
+	Email       string     `gorm:"size:255" json:"email,omitempty"`
+	Phone       string     `gorm:"size:20" json:"phone,omitempty"`
+	Status      string     `gorm:"default:pending;size:20" json:"status"` // pending, accepted, declined, cancelled, expired
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	RespondedAt *time.Time `json:"responded_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
 }
 
 func (i *Invitation) BeforeCreate(tx *gorm.DB) error {
 	if i.ID == uuid.Nil {
 		i.ID = uuid.New()
 	}
+	if i.ExpiresAt == nil {
+		expiresAt := time.Now().Add(InvitationTTL)
+		i.ExpiresAt = &expiresAt
+	}
 	return nil
 }
 
@@ -30,3 +40,31 @@ type InviteRequest struct {
 	Email string `json:"email"`
 	Phone string `json:"phone"`
 }
+
+type InvitationResponse struct {
+	ID          uuid.UUID  `json:"id"`
+	GroupID     uuid.UUID  `json:"group_id"`
+	GroupName   string     `json:"group_name"`
+	InvitedBy   uuid.UUID  `json:"invited_by"`
+	InviterName string     `json:"inviter_name"`
+	Email       string     `json:"email,omitempty"`
+	Phone       string     `json:"phone,omitempty"`
+	Status      string     `json:"status"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+func (i *Invitation) ToResponse() InvitationResponse {
+	return InvitationResponse{
+		ID:          i.ID,
+		GroupID:     i.GroupID,
+		GroupName:   i.Group.Name,
+		InvitedBy:   i.InvitedBy,
+		InviterName: i.Inviter.Name,
+		Email:       i.Email,
+		Phone:       i.Phone,
+		Status:      i.Status,
+		ExpiresAt:   i.ExpiresAt,
+		CreatedAt:   i.CreatedAt,
+	}
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// InviteLink is a shareable token that lets anyone with the link join a group
+type InviteLink struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	GroupID   uuid.UUID  `gorm:"type:uuid;index" json:"group_id"`
+	Token     string     `gorm:"uniqueIndex;not null;size:64" json:"token"`
+	CreatedBy uuid.UUID  `gorm:"type:uuid" json:"created_by"`
+	Creator   User       `gorm:"foreignKey:CreatedBy" json:"-"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	MaxUses   int        `gorm:"default:0" json:"max_uses"` // 0 = unlimited
+	Uses      int        `gorm:"default:0" json:"uses"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func (l *InviteLink) BeforeCreate(tx *gorm.DB) error {
+	if l.ID == uuid.Nil {
+		l.ID = uuid.New()
+	}
+	return nil
+}
+
+// IsUsable reports whether the link can still be used to join
+func (l *InviteLink) IsUsable(now time.Time) bool {
+	if l.RevokedAt != nil {
+		return false
+	}
+	if l.ExpiresAt != nil && !now.Before(*l.ExpiresAt) {
+		return false
+	}
+	return l.MaxUses == 0 || l.Uses < l.MaxUses
+}
+
+type CreateInviteLinkRequest struct {
+	ExpiresInHours int `json:"expires_in_hours" binding:"omitempty,min=1"` // omit for no expiry
+	MaxUses        int `json:"max_uses" binding:"omitempty,min=1"`         // omit for unlimited
+}
+
+type InviteLinkResponse struct {
+	InviteLink
+	URL string `json:"url"`
+}
+
+// InvitePreview is the public view of an invite link, shown before joining
+type InvitePreview struct {
+	GroupName   string     `json:"group_name"`
+	GroupType   string     `json:"group_type"`
+	ImageURL    string     `json:"image_url,omitempty"`
+	InviterName string     `json:"inviter_name"`
+	MemberCount int64      `json:"member_count"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Notification is an entry in a user's in-app inbox. One is written for every notification
+// the user is sent, whatever channels they get it on; Data is the same deep link the push carries.
+type Notification struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID    uuid.UUID  `gorm:"type:uuid;not null;index:idx_notification_inbox,priority:1" json:"-"`
+	GroupID   *uuid.UUID `gorm:"type:uuid;index" json:"group_id,omitempty"`
+	Type      string     `gorm:"size:30;not null" json:"type"`
+	Title     string     `gorm:"not null" json:"title"`
+	Body      string     `gorm:"type:text" json:"body"`
+	Data      StringMap  `gorm:"type:jsonb" json:"data"`
+	ReadAt    *time.Time `json:"read_at"`
+	CreatedAt time.Time  `gorm:"index:idx_notification_inbox,priority:2" json:"created_at"`
+}
+
+func (n *Notification) BeforeCreate(tx *gorm.DB) error {
+	if n.ID == uuid.Nil {
+		n.ID = uuid.New()
+	}
+	return nil
+}
+
+// Request structs
+type MarkNotificationsReadRequest struct {
+	IDs []string `json:"ids" binding:"max=500"`
+	All bool     `json:"all"` // mark everything read instead
+}
+
+// Response structs
+type NotificationListResponse struct {
+	Notifications []Notification `json:"notifications"`
+	UnreadCount   int64          `json:"unread_count"`
+}
@@ -0,0 +1,148 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Notification events users can configure
+const (
+	NotifyExpenseAdded   = "expense_added"
+	NotifySettlement     = "settlement"
+	NotifyMemberAdded    = "member_added"
+	NotifyInvitation     = "invitation"
+	NotifyGroupClosing   = "group_closing"
+	NotifyCommentMention = "comment_mention"
+)
+
+var NotificationEvents = []string{
+	NotifyExpenseAdded,
+	NotifySettlement,
+	NotifyMemberAdded,
+	NotifyInvitation,
+	NotifyGroupClosing,
+	NotifyCommentMention,
+}
+
+// NotificationPreference picks the channels for one event type. GroupID nil is the user's
+// default; a row for a group overrides it there. Without any row both channels are on.
+type NotificationPreference struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primaryKey" json:"-"`
+	UserID    uuid.UUID  `gorm:"type:uuid;not null" json:"-"`
+	GroupID   *uuid.UUID `gorm:"type:uuid" json:"group_id,omitempty"`
+	EventType string     `gorm:"size:30;not null" json:"event_type"`
+	Push      bool       `gorm:"not null" json:"push"`
+	Email     bool       `gorm:"not null" json:"email"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// NotificationPreferenceKey is the unique index on notification_preferences, with the default
+// (NULL group) counted as a value so there is one per user and event type. Upserts must name
+// the same expression as their conflict target.
+const NotificationPreferenceKey = "user_id, COALESCE(group_id, '00000000-0000-0000-0000-000000000000'), event_type"
+
+func (p *NotificationPreference) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return nil
+}
+
+// Allows reports whether the preference lets a channel through
+func (p NotificationPreference) Allows(channel string) bool {
+	switch channel {
+	case ChannelPush:
+		return p.Push
+	case ChannelEmail:
+		return p.Email
+	}
+	return true
+}
+
+// QuietHours is a daily window, in the user's timezone, during which notifications are held back.
+// The window may wrap past midnight, e.g. 22:00-07:00.
+type QuietHours struct {
+	Enabled bool   `gorm:"default:false" json:"enabled"`
+	Start   string `gorm:"size:5" json:"start"` // HH:MM
+	End     string `gorm:"size:5" json:"end"`   // HH:MM
+}
+
+// Until returns when the quiet hours containing t end. ok is false when t is outside them.
+func (q QuietHours) Until(t time.Time, loc *time.Location) (end time.Time, ok bool) {
+	if !q.Enabled {
+		return time.Time{}, false
+	}
+	start, err1 := time.Parse("15:04", q.Start)
+	stop, err2 := time.Parse("15:04", q.End)
+	if err1 != nil || err2 != nil {
+		return time.Time{}, false
+	}
+
+	local := t.In(loc)
+	now := local.Hour()*60 + local.Minute()
+	from := start.Hour()*60 + start.Minute()
+	to := stop.Hour()*60 + stop.Minute()
+
+	var inside bool
+	switch {
+	case from == to:
+		inside = false
+	case from < to:
+		inside = now >= from && now < to
+	default: // wraps past midnight
+		inside = now >= from || now < to
+	}
+	if !inside {
+		return time.Time{}, false
+	}
+
+	end = time.Date(local.Year(), local.Month(), local.Day(), stop.Hour(), stop.Minute(), 0, 0, loc)
+	if !end.After(local) {
+		end = end.AddDate(0, 0, 1)
+	}
+	return end, true
+}
+
+// Validate checks the HH:MM times of enabled quiet hours
+func (q QuietHours) Validate() error {
+	if !q.Enabled {
+		return nil
+	}
+	for _, v := range []string{q.Start, q.End} {
+		if _, err := time.Parse("15:04", v); err != nil {
+			return fmt.Errorf("quiet hours must be given as HH:MM, got \"%s\"", v)
+		}
+	}
+	if q.Start == q.End {
+		return fmt.Errorf("quiet hours must start and end at different times")
+	}
+	return nil
+}
+
+// Request structs
+type NotificationChannelsInput struct {
+	EventType string `json:"event_type" binding:"required"`
+	Push      *bool  `json:"push"`
+	Email     *bool  `json:"email"`
+}
+
+type UpdateNotificationPreferencesRequest struct {
+	Timezone    *string                     `json:"timezone"` // IANA name, e.g. Asia/Kolkata
+	QuietHours  *QuietHours                 `json:"quiet_hours"`
+	Preferences []NotificationChannelsInput `json:"preferences"`
+}
+
+type UpdateGroupNotificationsRequest struct {
+	Preferences []NotificationChannelsInput `json:"preferences" binding:"required"`
+}
+
+// Response structs
+type NotificationPreferencesResponse struct {
+	Timezone    string                   `json:"timezone"`
+	QuietHours  QuietHours               `json:"quiet_hours"`
+	Preferences []NotificationPreference `json:"preferences"`     // defaults, one per event type
+	Groups      []NotificationPreference `json:"group_overrides"` // per-group exceptions
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// OCR job statuses
+const (
+	OCRJobPending    = "pending"
+	OCRJobProcessing = "processing"
+	OCRJobCompleted  = "completed"
+	OCRJobFailed     = "failed"
+)
+
+// OCRJob is one asynchronous receipt scan. Clients poll it until it completes.
+type OCRJob struct {
+	ID          uuid.UUID     `gorm:"type:uuid;primaryKey" json:"id"`
+	GroupID     uuid.UUID     `gorm:"type:uuid;index" json:"group_id"`
+	UserID      uuid.UUID     `gorm:"type:uuid;index" json:"user_id"`
+	ExpenseID   *uuid.UUID    `gorm:"type:uuid" json:"expense_id,omitempty"` // set when scanning an expense's existing receipt
+	Status      string        `gorm:"default:pending;size:20" json:"status"`
+	Itemize     bool          `json:"itemize"`
+	ImageKey    string        `json:"-"`
+	Draft       *ReceiptDraft `gorm:"type:jsonb" json:"draft,omitempty"`
+	Error       string        `json:"error,omitempty"`
+	WorkerID    string        `gorm:"size:100" json:"-"` // replica running the job
+	LeaseUntil  *time.Time    `gorm:"index" json:"-"`    // renewed while the job runs; once past, the worker is gone
+	CreatedAt   time.Time     `json:"created_at"`
+	CompletedAt *time.Time    `json:"completed_at,omitempty"`
+}
+
+func (j *OCRJob) BeforeCreate(tx *gorm.DB) error {
+	if j.ID == uuid.Nil {
+		j.ID = uuid.New()
+	}
+	return nil
+}
+
+// ReceiptDraft is what a scan produced: the raw fields plus a ready-to-confirm expense
+type ReceiptDraft struct {
+	Merchant         string                 `json:"merchant,omitempty"`
+	Date             string                 `json:"date,omitempty"` // YYYY-MM-DD
+	Total            float64                `json:"total"`
+	Tax              float64                `json:"tax"`
+	Items            []ReceiptLineItem      `json:"items"`
+	Expense          CreateExpenseRequest   `json:"expense"`
+	ItemizedExpenses []CreateExpenseRequest `json:"itemized_expenses,omitempty"` // one expense per line item, tax spread proportionally
+}
+
+type ReceiptLineItem struct {
+	Description string  `json:"description"`
+	Quantity    float64 `json:"quantity"`
+	Amount      float64 `json:"amount"`
+}
+
+func (d ReceiptDraft) Value() (driver.Value, error) {
+	b, err := json.Marshal(d)
+	return string(b), err
+}
+
+func (d *ReceiptDraft) Scan(value interface{}) error {
+	return scanJSON(value, d)
+}
@@ -0,0 +1,78 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Notification channels
+const (
+	ChannelPush  = "push"
+	ChannelEmail = "email"
+)
+
+// Outbox message statuses. Failed messages are retried with backoff until they go dead;
+// skipped ones had nowhere to go (no push token, email not configured).
+const (
+	OutboxPending = "pending"
+	OutboxSent    = "sent"
+	OutboxFailed  = "failed"
+	OutboxDead    = "dead"
+	OutboxSkipped = "skipped"
+)
+
+// OutboxMessage is one notification on one channel, queued in the same transaction as the
+// change that caused it and sent by the notification worker. Content is rendered when queued.
+type OutboxMessage struct {
+	ID            uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID        *uuid.UUID `gorm:"type:uuid;index" json:"user_id,omitempty"` // nil for emails to people without an account
+	GroupID       *uuid.UUID `gorm:"type:uuid" json:"group_id,omitempty"`
+	Channel       string     `gorm:"size:10;not null" json:"channel"`
+	EventType     string     `gorm:"size:30;not null" json:"event_type"`
+	Recipient     string     `json:"recipient,omitempty"` // email address; pushes go to the user's current devices
+	RecipientName string     `json:"recipient_name,omitempty"`
+	Title         string     `gorm:"not null" json:"title"` // push title or email subject
+	Body          string     `gorm:"type:text" json:"body,omitempty"`
+	HTML          string     `gorm:"type:text" json:"-"`
+	Unsubscribe   string     `json:"-"`                                // one-click unsubscribe URL for the List-Unsubscribe header
+	Data          StringMap  `gorm:"type:jsonb" json:"data,omitempty"` // push data payload
+	Status        string     `gorm:"size:20;not null;index:idx_outbox_due,priority:1" json:"status"`
+	Attempts      int        `gorm:"default:0" json:"attempts"`
+	NextAttemptAt time.Time  `gorm:"index:idx_outbox_due,priority:2" json:"next_attempt_at"`
+	LastError     string     `json:"last_error,omitempty"`
+	SentAt        *time.Time `json:"sent_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+func (m *OutboxMessage) BeforeCreate(tx *gorm.DB) error {
+	if m.ID == uuid.Nil {
+		m.ID = uuid.New()
+	}
+	if m.Status == "" {
+		m.Status = OutboxPending
+	}
+	if m.NextAttemptAt.IsZero() {
+		m.NextAttemptAt = time.Now()
+	}
+	return nil
+}
+
+// StringMap is a string-to-string map stored as JSON
+type StringMap map[string]string
+
+func (m StringMap) Value() (driver.Value, error) {
+	if m == nil {
+		return "{}", nil
+	}
+	b, err := json.Marshal(m)
+	return string(b), err
+}
+
+func (m *StringMap) Scan(value interface{}) error {
+	return scanJSON(value, m)
+}
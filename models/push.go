@@ -0,0 +1,25 @@
+package models
+
+import "encoding/json"
+
+// Request structs
+type PushRequest struct {
+	Mutations []Mutation `json:"mutations" binding:"required,min=1,max=100,dive"`
+}
+
+// Mutation is one queued offline write, replayed as if the client had sent it directly
+type Mutation struct {
+	ID      string          `json:"id"`                                                    // client's own reference, echoed back
+	Method  string          `json:"method" binding:"required,oneof=POST PUT PATCH DELETE"` // e.g. "PUT"
+	Path    string          `json:"path" binding:"required"`                               // e.g. "/api/expenses/<id>"
+	IfMatch string          `json:"if_match"`                                              // ETag the edit was based on
+	Body    json.RawMessage `json:"body"`
+}
+
+// Response structs
+type MutationResult struct {
+	ID     string          `json:"id"`
+	Status int             `json:"status"`
+	ETag   string          `json:"etag,omitempty"`
+	Body   json.RawMessage `json:"body,omitempty"` // the response the endpoint gave
+}
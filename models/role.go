@@ -0,0 +1,87 @@
+package models
+
+// Group roles, from most to least privileged
+const (
+	RoleOwner  = "owner"
+	RoleAdmin  = "admin"
+	RoleMember = "member"
+	RoleViewer = "viewer"
+)
+
+// Group policies
+const (
+	ExpenseEditPayerOrAdmin = "payer_or_admin" // only the payer or an admin may edit/delete an expense
+	ExpenseEditMembers      = "members"        // any member may edit/delete any expense
+	MemberAddMembers        = "members"        // any member may add or invite people
+	MemberAddAdmins         = "admins"         // only admins may add or invite people
+	JoinPolicyAuto          = "auto"           // existing users are added directly
+	JoinPolicyConsent       = "consent"        // existing users must accept an invitation
+)
+
+type Permission string
+
+const (
+	PermViewGroup         Permission = "view_group"
+	PermEditGroup         Permission = "edit_group"
+	PermAddMember         Permission = "add_member"
+	PermInvite            Permission = "invite"
+	PermManageInviteLinks Permission = "manage_invite_links"
+	PermRemoveMember      Permission = "remove_member"
+	PermManageRoles       Permission = "manage_roles"
+	PermTransferOwnership Permission = "transfer_ownership"
+	PermAddExpense        Permission = "add_expense"
+	PermEditAnyExpense    Permission = "edit_any_expense"
+	PermSettle            Permission = "settle"
+	PermArchiveGroup      Permission = "archive_group"
+	PermDeleteGroup       Permission = "delete_group"
+	PermComment           Permission = "comment"
+	PermManageWebhooks    Permission = "manage_webhooks"
+)
+
+var roleRank = map[string]int{
+	RoleViewer: 1,
+	RoleMember: 2,
+	RoleAdmin:  3,
+	RoleOwner:  4,
+}
+
+// Minimum role required for each permission
+var permissionMinRole = map[Permission]string{
+	PermViewGroup:         RoleViewer,
+	PermComment:           RoleViewer,
+	PermAddExpense:        RoleMember,
+	PermSettle:            RoleMember,
+	PermAddMember:         RoleMember,
+	PermInvite:            RoleMember,
+	PermEditGroup:         RoleAdmin,
+	PermRemoveMember:      RoleAdmin,
+	PermManageRoles:       RoleAdmin,
+	PermEditAnyExpense:    RoleAdmin,
+	PermManageInviteLinks: RoleAdmin,
+	PermArchiveGroup:      RoleAdmin,
+	PermDeleteGroup:       RoleAdmin,
+	PermManageWebhooks:    RoleAdmin,
+	PermTransferOwnership: RoleOwner,
+}
+
+// IsValidRole reports whether role is one of the known group roles
+func IsValidRole(role string) bool {
+	_, ok := roleRank[role]
+	return ok
+}
+
+// RoleRank orders roles so they can be compared; unknown roles rank 0
+func RoleRank(role string) int {
+	return roleRank[role]
+}
+
+// RoleAtLeast reports whether role is at least as privileged as min
+func RoleAtLeast(role, min string) bool {
+	return RoleRank(role) >= RoleRank(min)
+}
+
+// RoleHasPermission checks the static role table, ignoring group policies
+func RoleHasPermission(role string, perm Permission) bool {
+	min, ok := permissionMinRole[perm]
+	return ok && RoleAtLeast(role, min)
+}
@@ -17,6 +17,7 @@ type Settlement struct {
 	Amount    float64   `gorm:"type:decimal(12,2);not null" json:"amount"`
 	Notes     string    `json:"notes,omitempty"`
 	CreatedAt time.Time `json:"created_at"`
+	ChangeSeq int64     `gorm:"index;not null;default:0" json:"-"`
 }
 
 func (s *Settlement) BeforeCreate(tx *gorm.DB) error {
@@ -27,6 +28,7 @@ func (s *Settlement) BeforeCreate(tx *gorm.DB) error {
 }
 
 type CreateSettlementRequest struct {
+	ID      string  `json:"id"` // optional client-generated UUID
 	GroupID string  `json:"group_id" binding:"required"`
 	PaidTo  string  `json:"paid_to" binding:"required"`
 	Amount  float64 `json:"amount" binding:"required,gt=0"`
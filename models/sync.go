@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// A sync token is good for SyncTokenTTL; older ones get a "full resync required" answer.
+// Tombstones are kept a day longer so a token that is still valid never misses one.
+const (
+	SyncTokenTTL       = 30 * 24 * time.Hour
+	TombstoneRetention = SyncTokenTTL + 24*time.Hour
+)
+
+// Entity types used in sync tombstones
+const (
+	EntityGroup      = "group"
+	EntityMember     = "member"
+	EntityExpense    = "expense"
+	EntitySplit      = "split"
+	EntitySettlement = "settlement"
+	EntityActivity   = "activity"
+)
+
+// Tombstone records a deleted row so delta sync can tell clients to drop it.
+// Rows are written by a database trigger, never by the application.
+type Tombstone struct {
+	Seq        int64      `gorm:"primaryKey;autoIncrement:false" json:"-"`
+	EntityType string     `gorm:"size:20;not null" json:"type"`
+	EntityID   *uuid.UUID `gorm:"type:uuid" json:"id,omitempty"` // empty for members, which are keyed by group + user
+	GroupID    *uuid.UUID `gorm:"type:uuid;index" json:"group_id,omitempty"`
+	UserID     *uuid.UUID `gorm:"type:uuid;index" json:"user_id,omitempty"` // members only
+	ParentID   *uuid.UUID `gorm:"type:uuid" json:"parent_id,omitempty"`     // expense of a split
+	DeletedAt  time.Time  `json:"deleted_at"`
+}
+
+// Response structs
+type SyncResponse struct {
+	Token          string         `json:"token"`           // pass as ?since= next time
+	Full           bool           `json:"full"`            // no token was given: replace the whole local cache (first page only)
+	SnapshotGroups []uuid.UUID    `json:"snapshot_groups"` // groups sent in full (e.g. newly joined): replace their local data (first page only)
+	Groups         []Group        `json:"groups"`
+	Members        []GroupMember  `json:"members"`
+	Expenses       []Expense      `json:"expenses"`
+	Splits         []ExpenseSplit `json:"splits"`
+	Settlements    []Settlement   `json:"settlements"`
+	Activity       []Activity     `json:"activity"`
+	Deleted        []Tombstone    `json:"deleted"`
+	HasMore        bool           `json:"has_more"` // more changes in this sync: ask again with next_cursor
+	NextCursor     string         `json:"next_cursor,omitempty"`
+}
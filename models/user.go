@@ -1,6 +1,7 @@
 package models
 
 import (
+	"splitwise-backend/utils"
 	"time"
 
 	"github.com/google/uuid"
@@ -8,25 +9,52 @@ import (
 )
 
 type User struct {
-	ID           uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
-	Email        string    `gorm:"uniqueIndex;not null;size:255" json:"email"`
-	Phone        string    `gorm:"size:20" json:"phone,omitempty"`
-	Name         string    `gorm:"not null;size:100" json:"name"`
-	PasswordHash string    `gorm:"not null;size:255" json:"-"`
-	AvatarURL    string    `json:"avatar_url,omitempty"`
-	FCMToken     string    `json:"-"`
-	Currency     string    `gorm:"default:INR;size:3" json:"currency"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID           uuid.UUID       `gorm:"type:uuid;primaryKey" json:"id"`
+	Email        string          `gorm:"uniqueIndex;not null;size:255" json:"email"`
+	Phone        string          `gorm:"size:20" json:"phone,omitempty"`
+	Name         string          `gorm:"not null;size:100" json:"name"`
+	PasswordHash string          `gorm:"not null;size:255" json:"-"`
+	AvatarURL    string          `json:"avatar_url,omitempty"`
+	Currency     string          `gorm:"default:INR;size:3" json:"currency"`
+	EmailHash    string          `gorm:"size:64;index" json:"-"` // SHA-256 of normalized email, for contact matching
+	PhoneHash    string          `gorm:"size:64;index" json:"-"` // SHA-256 of normalized phone, for contact matching
+	Privacy      PrivacySettings `gorm:"embedded;embeddedPrefix:privacy_" json:"-"`
+	Timezone     string          `gorm:"size:64;default:UTC" json:"-"` // IANA name, for quiet hours
+	QuietHours   QuietHours      `gorm:"embedded;embeddedPrefix:quiet_" json:"-"`
+	AnonymizedAt *time.Time      `json:"-"` // set when the account is deleted
+	CreatedAt    time.Time       `json:"created_at"`
+	UpdatedAt    time.Time       `json:"updated_at"`
+}
+
+// Location is the user's timezone, UTC if unset or unknown
+func (u *User) Location() *time.Location {
+	if loc, err := time.LoadLocation(u.Timezone); err == nil && u.Timezone != "" {
+		return loc
+	}
+	return time.UTC
+}
+
+// PrivacySettings controls how a user can be found by people outside their groups
+type PrivacySettings struct {
+	DiscoverableByEmail bool `gorm:"default:true" json:"discoverable_by_email"`
+	DiscoverableByPhone bool `gorm:"default:true" json:"discoverable_by_phone"`
+	ContactMatching     bool `gorm:"default:true" json:"contact_matching"`
 }
 
 func (u *User) BeforeCreate(tx *gorm.DB) error {
 	if u.ID == uuid.Nil {
 		u.ID = uuid.New()
 	}
+	u.SetContactHashes()
 	return nil
 }
 
+// SetContactHashes recomputes the hashed email/phone used for contact matching
+func (u *User) SetContactHashes() {
+	u.EmailHash = utils.HashContact(utils.NormalizeEmail(u.Email))
+	u.PhoneHash = utils.HashContact(utils.NormalizePhone(u.Phone))
+}
+
 // Response struct (what we return to clients)
 type UserResponse struct {
 	ID        uuid.UUID `json:"id"`
@@ -49,3 +77,44 @@ func (u *User) ToResponse() UserResponse {
 		CreatedAt: u.CreatedAt,
 	}
 }
+
+// UserSearchResult is returned by search and contact matching.
+// Email and phone are only included for users who already share a group.
+type UserSearchResult struct {
+	ID           uuid.UUID `json:"id"`
+	Name         string    `json:"name"`
+	Email        string    `json:"email,omitempty"`
+	Phone        string    `json:"phone,omitempty"`
+	AvatarURL    string    `json:"avatar_url,omitempty"`
+	IsConnection bool      `json:"is_connection"`
+	MatchedHash  string    `json:"matched_hash,omitempty"` // set by contact matching
+}
+
+func (u *User) ToSearchResult(isConnection bool) UserSearchResult {
+	result := UserSearchResult{
+		ID:           u.ID,
+		Name:         u.Name,
+		AvatarURL:    u.AvatarURL,
+		IsConnection: isConnection,
+	}
+	if isConnection {
+		result.Email = u.Email
+		result.Phone = u.Phone
+	}
+	return result
+}
+
+// Request structs
+type SearchUsersRequest struct {
+	Query string `json:"query" binding:"required,min=2"`
+}
+
+type ContactMatchRequest struct {
+	Hashes []string `json:"hashes" binding:"required,max=1000"`
+}
+
+type UpdatePrivacyRequest struct {
+	DiscoverableByEmail *bool `json:"discoverable_by_email"`
+	DiscoverableByPhone *bool `json:"discoverable_by_phone"`
+	ContactMatching     *bool `json:"contact_matching"`
+}
@@ -0,0 +1,109 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Webhook delivery statuses. Failed deliveries are retried with backoff until they go dead.
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+	DeliveryDead      = "dead"
+)
+
+// WebhookPing is the event type of test deliveries
+const WebhookPing = "ping"
+
+// Webhook posts group events to an external URL. Group webhooks (GroupID set) are managed
+// by group admins; personal ones (GroupID nil) get events from all of the owner's groups.
+type Webhook struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	GroupID   *uuid.UUID `gorm:"type:uuid;index" json:"group_id,omitempty"`
+	UserID    uuid.UUID  `gorm:"type:uuid;index" json:"user_id"` // creator; owner of a personal webhook
+	URL       string     `gorm:"not null" json:"url"`
+	Secret    string     `gorm:"not null;size:64" json:"-"`
+	Events    StringList `gorm:"type:jsonb" json:"events"` // Activity types; empty = all
+	Active    bool       `gorm:"default:true" json:"active"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+func (w *Webhook) BeforeCreate(tx *gorm.DB) error {
+	if w.ID == uuid.Nil {
+		w.ID = uuid.New()
+	}
+	return nil
+}
+
+// Wants reports whether the webhook subscribes to an event type
+func (w *Webhook) Wants(eventType string) bool {
+	if eventType == WebhookPing || len(w.Events) == 0 {
+		return true
+	}
+	for _, e := range w.Events {
+		if e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery is one event queued for one webhook, and its delivery log
+type WebhookDelivery struct {
+	ID             uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	WebhookID      uuid.UUID  `gorm:"type:uuid;index" json:"webhook_id"`
+	EventType      string     `gorm:"size:30;not null" json:"event_type"`
+	Payload        string     `gorm:"type:jsonb;not null" json:"-"` // exact body that is signed and sent
+	Status         string     `gorm:"size:20;not null;index:idx_delivery_due,priority:1" json:"status"`
+	Attempts       int        `gorm:"default:0" json:"attempts"`
+	NextAttemptAt  time.Time  `gorm:"index:idx_delivery_due,priority:2" json:"next_attempt_at"`
+	LastStatusCode int        `json:"last_status_code,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+func (d *WebhookDelivery) BeforeCreate(tx *gorm.DB) error {
+	if d.ID == uuid.Nil {
+		d.ID = uuid.New()
+	}
+	return nil
+}
+
+// WebhookPayload is the JSON body POSTed to webhook URLs
+type WebhookPayload struct {
+	ID        uuid.UUID   `json:"id"` // delivery ID, stable across retries
+	Type      string      `json:"type"`
+	GroupID   *uuid.UUID  `json:"group_id,omitempty"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// Request structs
+type CreateWebhookRequest struct {
+	URL    string   `json:"url" binding:"required,url"`
+	Events []string `json:"events"` // omit for all events
+}
+
+type UpdateWebhookRequest struct {
+	URL    *string   `json:"url" binding:"omitempty,url"`
+	Events *[]string `json:"events"`
+	Active *bool     `json:"active"`
+}
+
+// Response structs
+type WebhookResponse struct {
+	Webhook
+	Secret string `json:"secret,omitempty"` // only when the webhook is created
+}
+
+type WebhookDeliveryResponse struct {
+	WebhookDelivery
+	Payload json.RawMessage `json:"payload"`
+}
@@ -0,0 +1,87 @@
+package ocr
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os/exec"
+	"splitwise-backend/config"
+	"strings"
+)
+
+var ErrDisabled = errors.New("receipt scanning is not configured")
+
+// Engine turns a receipt image into plain text
+type Engine interface {
+	Recognize(ctx context.Context, image []byte) (string, error)
+}
+
+var Default Engine
+
+// Setup picks the engine from config: tesseract, fake or none
+func Setup() {
+	switch config.AppConfig.OCREngine {
+	case "tesseract":
+		path, err := exec.LookPath(config.AppConfig.TesseractPath)
+		if err != nil {
+			log.Printf("⚠️  Tesseract not found at %q, receipt scanning disabled", config.AppConfig.TesseractPath)
+			return
+		}
+		Default = &TesseractEngine{Binary: path}
+		log.Printf("✅ Receipt OCR using %s", path)
+	case "fake":
+		Default = &FakeEngine{Text: SampleReceipt}
+		log.Println("⚠️  Receipt OCR using the fake engine")
+	default:
+		log.Println("⚠️  Receipt OCR disabled")
+	}
+}
+
+// TesseractEngine shells out to a local tesseract binary
+type TesseractEngine struct {
+	Binary string
+}
+
+func (e *TesseractEngine) Recognize(ctx context.Context, image []byte) (string, error) {
+	// "stdin stdout": read the image from stdin and print the text; psm 4 treats the receipt as one column
+	cmd := exec.CommandContext(ctx, e.Binary, "stdin", "stdout", "--psm", "4")
+	cmd.Stdin = bytes.NewReader(image)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("tesseract: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+// FakeEngine returns fixed text whatever the image, so the pipeline is deterministic in tests and dev
+type FakeEngine struct {
+	Text string
+	Err  error
+}
+
+func (e *FakeEngine) Recognize(ctx context.Context, image []byte) (string, error) {
+	return e.Text, e.Err
+}
+
+// SampleReceipt is what the fake engine "reads" by default
+const SampleReceipt = `THE BEACH SHACK
+Calangute, Goa
+Date: 14/03/2025  20:41
+
+Fish Thali          2 x 450.00     900.00
+Prawn Curry                        650.00
+Fresh Lime Soda     3 x 80.00      240.00
+
+Subtotal                          1790.00
+CGST 2.5%                           44.75
+SGST 2.5%                           44.75
+TOTAL                             1879.50
+
+Paid by UPI
+Thank you, visit again!`
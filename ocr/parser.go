@@ -0,0 +1,170 @@
+package ocr
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Receipt is what could be read off a receipt. Zero values mean "not found".
+type Receipt struct {
+	Merchant string     `json:"merchant,omitempty"`
+	Date     *time.Time `json:"date,omitempty"`
+	Total    float64    `json:"total"`
+	Tax      float64    `json:"tax"`
+	Items    []LineItem `json:"items"`
+}
+
+type LineItem struct {
+	Description string  `json:"description"`
+	Quantity    float64 `json:"quantity"`
+	Amount      float64 `json:"amount"`
+}
+
+var (
+	amountRe   = regexp.MustCompile(`(?:^|[\s:₹$€£])(-?\d{1,3}(?:,\d{3})+(?:\.\d{1,2})?|-?\d+[.,]\d{2})\s*$`)
+	quantityRe = regexp.MustCompile(`\s(\d+)\s*[xX@]\s*(?:\d{1,3}(?:,\d{3})+|\d+)(?:[.,]\d{1,2})?\s*$`)
+	lettersRe  = regexp.MustCompile(`[A-Za-z]{2,}`)
+
+	totalRe    = regexp.MustCompile(`(?i)\b(grand\s*total|total\s*due|amount\s*due|balance\s*due|net\s*amount|total)\b`)
+	subtotalRe = regexp.MustCompile(`(?i)\bsub\s*-?\s*total\b`)
+	taxRe      = regexp.MustCompile(`(?i)\b(tax|vat|gst|cgst|sgst|igst|service\s*tax)\b`)
+	skipRe     = regexp.MustCompile(`(?i)\b(change|cash|card|upi|tendered|paid|tip|discount|round\s*off|invoice|bill\s*no|table|phone|tel|gstin)\b`)
+
+	dateFormats = []struct {
+		re     *regexp.Regexp
+		layout []string
+	}{
+		{regexp.MustCompile(`\b(\d{4}-\d{2}-\d{2})\b`), []string{"2006-01-02"}},
+		{regexp.MustCompile(`\b(\d{1,2}[/.-]\d{1,2}[/.-]\d{4})\b`), []string{"02/01/2006", "2/1/2006", "01/02/2006", "1/2/2006"}},
+		{regexp.MustCompile(`\b(\d{1,2}[/.-]\d{1,2}[/.-]\d{2})\b`), []string{"02/01/06", "2/1/06", "01/02/06", "1/2/06"}},
+		{regexp.MustCompile(`(?i)\b(\d{1,2}\s+[a-z]{3}[a-z]*\s+\d{4})\b`), []string{"2 Jan 2006", "2 January 2006"}},
+		{regexp.MustCompile(`(?i)\b([a-z]{3}[a-z]*\s+\d{1,2},?\s+\d{4})\b`), []string{"Jan 2, 2006", "Jan 2 2006", "January 2, 2006", "January 2 2006"}},
+	}
+)
+
+// Parse pulls merchant, date, total, tax and line items out of OCR text.
+// It is heuristic: the result is a draft for the user to confirm, not a source of truth.
+func Parse(text string) Receipt {
+	receipt := Receipt{Items: []LineItem{}}
+	var amounts []float64
+	var itemsTotal float64
+	pastItems := false
+
+	for _, raw := range strings.Split(text, "\n") {
+		line := strings.TrimSpace(raw)
+		if line == "" {
+			continue
+		}
+
+		if receipt.Date == nil {
+			receipt.Date = parseDate(line)
+		}
+
+		amount, hasAmount := lineAmount(line)
+		if hasAmount {
+			amounts = append(amounts, amount)
+		}
+
+		switch {
+		case subtotalRe.MatchString(line):
+			pastItems = true
+		case taxRe.MatchString(line) && hasAmount:
+			receipt.Tax += amount
+			pastItems = true
+		case totalRe.MatchString(line) && hasAmount:
+			// The last "total" wins: receipts often print a pre-tax total before the grand total
+			receipt.Total = amount
+			pastItems = true
+		case receipt.Merchant == "" && !hasAmount && lettersRe.MatchString(line) && receipt.Date == nil:
+			receipt.Merchant = line
+		case hasAmount && !pastItems && amount > 0 && !skipRe.MatchString(line):
+			if item, ok := parseItem(line, amount); ok {
+				receipt.Items = append(receipt.Items, item)
+				itemsTotal += amount
+			}
+		}
+	}
+
+	receipt.Tax = round2(receipt.Tax)
+	if receipt.Total == 0 {
+		if itemsTotal > 0 {
+			receipt.Total = round2(itemsTotal + receipt.Tax)
+		} else {
+			for _, a := range amounts {
+				if a > receipt.Total {
+					receipt.Total = a
+				}
+			}
+		}
+	}
+
+	return receipt
+}
+
+// Helper: trailing amount on a line, e.g. "Prawn Curry   650.00"
+func lineAmount(line string) (float64, bool) {
+	m := amountRe.FindStringSubmatch(line)
+	if m == nil {
+		return 0, false
+	}
+	return parseAmount(m[1])
+}
+
+func parseAmount(s string) (float64, bool) {
+	// "1,879.50" and "1879,50" both mean 1879.50
+	if strings.Count(s, ",") == 1 && strings.LastIndex(s, ",") == len(s)-3 && !strings.Contains(s, ".") {
+		s = strings.Replace(s, ",", ".", 1)
+	}
+	v, err := strconv.ParseFloat(strings.ReplaceAll(s, ",", ""), 64)
+	if err != nil {
+		return 0, false
+	}
+	return round2(v), true
+}
+
+// Helper: description and quantity of an item line
+func parseItem(line string, amount float64) (LineItem, bool) {
+	loc := amountRe.FindStringIndex(line)
+	desc := strings.TrimSpace(line[:loc[0]])
+
+	quantity := 1.0
+	if m := quantityRe.FindStringSubmatchIndex(desc); m != nil {
+		if q, err := strconv.ParseFloat(desc[m[2]:m[3]], 64); err == nil && q > 0 {
+			quantity = q
+		}
+		desc = strings.TrimSpace(desc[:m[0]])
+	}
+
+	desc = strings.Trim(desc, " .:-*")
+	if !lettersRe.MatchString(desc) {
+		return LineItem{}, false
+	}
+	return LineItem{Description: desc, Quantity: quantity, Amount: amount}, true
+}
+
+// Helper: first recognizable date on a line. Numeric dates are read day-first, falling back to month-first.
+func parseDate(line string) *time.Time {
+	for _, f := range dateFormats {
+		m := f.re.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		value := strings.NewReplacer("-", "/", ".", "/").Replace(m[1])
+		if f.layout[0] == "2006-01-02" {
+			value = m[1]
+		}
+		for _, layout := range f.layout {
+			if t, err := time.Parse(layout, value); err == nil {
+				return &t
+			}
+		}
+	}
+	return nil
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
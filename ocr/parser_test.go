@@ -0,0 +1,173 @@
+package ocr
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		merchant string
+		date     string // YYYY-MM-DD, empty for none
+		total    float64
+		tax      float64
+		items    []LineItem
+	}{
+		{
+			name:     "sample receipt",
+			text:     SampleReceipt,
+			merchant: "THE BEACH SHACK",
+			date:     "2025-03-14",
+			total:    1879.50,
+			tax:      89.50,
+			items: []LineItem{
+				{Description: "Fish Thali", Quantity: 2, Amount: 900},
+				{Description: "Prawn Curry", Quantity: 1, Amount: 650},
+				{Description: "Fresh Lime Soda", Quantity: 3, Amount: 240},
+			},
+		},
+		{
+			name:     "grand total wins over an earlier total",
+			text:     "Corner Cafe\n2024-06-01\nLatte 4.50\nCroissant 3.20\nTotal 7.70\nVAT 1.54\nGrand Total 9.24\nCard 9.24",
+			merchant: "Corner Cafe",
+			date:     "2024-06-01",
+			total:    9.24,
+			tax:      1.54,
+			items: []LineItem{
+				{Description: "Latte", Quantity: 1, Amount: 4.50},
+				{Description: "Croissant", Quantity: 1, Amount: 3.20},
+			},
+		},
+		{
+			name:     "no total line: items plus tax",
+			text:     "Deli\nBagel 2 x 3.00 6.00\nJuice 4.00\nTax 0.80",
+			merchant: "Deli",
+			total:    10.80,
+			tax:      0.80,
+			items: []LineItem{
+				{Description: "Bagel", Quantity: 2, Amount: 6.00},
+				{Description: "Juice", Quantity: 1, Amount: 4.00},
+			},
+		},
+		{
+			name:     "comma decimals",
+			text:     "Bäckerei Schmidt\n05.02.2024\nBrot 2 x 1,75 3,50\nKuchen 12,50\nVAT 7% 1,12\nTotal 17,12",
+			merchant: "Bäckerei Schmidt",
+			date:     "2024-02-05",
+			total:    17.12,
+			tax:      1.12,
+			items: []LineItem{
+				{Description: "Brot", Quantity: 2, Amount: 3.50},
+				{Description: "Kuchen", Quantity: 1, Amount: 12.50},
+			},
+		},
+		{
+			name:     "thousands separators",
+			text:     "Hotel Mirage\nRoom 2 x 1,200.00 2,400.00\nGST 288.00\nTotal Due 2,688.00",
+			merchant: "Hotel Mirage",
+			total:    2688,
+			tax:      288,
+			items:    []LineItem{{Description: "Room", Quantity: 2, Amount: 2400}},
+		},
+		{
+			name:     "day-first date",
+			text:     "Shop\n13/04/2024\nTotal 5.00",
+			merchant: "Shop",
+			date:     "2024-04-13",
+			total:    5,
+			items:    []LineItem{},
+		},
+		{
+			name:     "month-first date when day-first is impossible",
+			text:     "Shop\n04/13/2024\nTotal 5.00",
+			merchant: "Shop",
+			date:     "2024-04-13",
+			total:    5,
+			items:    []LineItem{},
+		},
+		{
+			name:     "ambiguous numeric date reads day-first",
+			text:     "Shop\n03/04/2024\nTotal 5.00",
+			merchant: "Shop",
+			date:     "2024-04-03",
+			total:    5,
+			items:    []LineItem{},
+		},
+		{
+			name:     "two-digit year",
+			text:     "Shop\n7.8.24\nTotal 5.00",
+			merchant: "Shop",
+			date:     "2024-08-07",
+			total:    5,
+			items:    []LineItem{},
+		},
+		{
+			name:     "written-out dates",
+			text:     "Shop\nMarch 9, 2024\nTotal 5.00",
+			merchant: "Shop",
+			date:     "2024-03-09",
+			total:    5,
+			items:    []LineItem{},
+		},
+		{
+			name:     "payment lines are not items",
+			text:     "Kiosk\nWater 1.00\nCash 5.00\nChange 4.00",
+			merchant: "Kiosk",
+			total:    1.00,
+			items:    []LineItem{{Description: "Water", Quantity: 1, Amount: 1.00}},
+		},
+		{
+			name:  "nothing readable",
+			text:  "\n\n  \n",
+			items: []LineItem{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Parse(tt.text)
+
+			if got.Merchant != tt.merchant {
+				t.Errorf("merchant = %q, want %q", got.Merchant, tt.merchant)
+			}
+			var date string
+			if got.Date != nil {
+				date = got.Date.Format("2006-01-02")
+			}
+			if date != tt.date {
+				t.Errorf("date = %q, want %q", date, tt.date)
+			}
+			if got.Total != tt.total {
+				t.Errorf("total = %.2f, want %.2f", got.Total, tt.total)
+			}
+			if got.Tax != tt.tax {
+				t.Errorf("tax = %.2f, want %.2f", got.Tax, tt.tax)
+			}
+			if !reflect.DeepEqual(got.Items, tt.items) {
+				t.Errorf("items = %+v, want %+v", got.Items, tt.items)
+			}
+		})
+	}
+}
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		in   string
+		want float64
+	}{
+		{"12.50", 12.50},
+		{"12,50", 12.50},
+		{"1,879.50", 1879.50},
+		{"1,879", 1879},
+		{"1,234,567.89", 1234567.89},
+		{"-3.00", -3},
+	}
+	for _, tt := range tests {
+		got, ok := parseAmount(tt.in)
+		if !ok || got != tt.want {
+			t.Errorf("parseAmount(%q) = %v, %v; want %v", tt.in, got, ok, tt.want)
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"splitwise-backend/config"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	messagingScope  = "https://www.googleapis.com/auth/firebase.messaging"
+	defaultTokenURI = "https://oauth2.googleapis.com/token"
+)
+
+// ErrUnregistered means FCM no longer knows the device token (app uninstalled, token rotated);
+// the token should be forgotten
+var ErrUnregistered = errors.New("device token is no longer registered")
+
+// Default sends pushes for the app. It is nil when no Firebase credentials are configured.
+var Default *FCM
+
+// Message is one push to one device
+type Message struct {
+	Token string
+	Title string
+	Body  string
+	Data  map[string]string // deep-link payload, handed to the app as-is
+}
+
+// FCM sends through the Firebase Cloud Messaging HTTP v1 API, authenticating as a
+// service account: a signed JWT is exchanged for an OAuth2 access token, cached until it expires.
+type FCM struct {
+	projectID   string
+	clientEmail string
+	tokenURI    string
+	key         *rsa.PrivateKey
+	baseURL     string
+	client      *http.Client
+
+	mu          sync.Mutex
+	accessToken string
+	expiresAt   time.Time
+}
+
+// Setup reads the service account file at FIREBASE_CREDENTIALS. Without it pushes are skipped.
+func Setup() {
+	raw, err := os.ReadFile(config.AppConfig.FirebaseCredPath)
+	if err != nil {
+		log.Printf("⚠️  Firebase credentials not found at %q, push notifications disabled", config.AppConfig.FirebaseCredPath)
+		return
+	}
+	fcm, err := NewFCM(raw, config.AppConfig.FCMBaseURL)
+	if err != nil {
+		log.Printf("❌ Invalid Firebase credentials, push notifications disabled: %v", err)
+		return
+	}
+	Default = fcm
+	log.Printf("✅ Push notifications via FCM for project %s", fcm.projectID)
+}
+
+// NewFCM parses a service account JSON key. baseURL is normally https://fcm.googleapis.com;
+// tests point it (and the key's token_uri) at a local stub.
+func NewFCM(credentials []byte, baseURL string) (*FCM, error) {
+	var creds struct {
+		Type        string `json:"type"`
+		ProjectID   string `json:"project_id"`
+		PrivateKey  string `json:"private_key"`
+		ClientEmail string `json:"client_email"`
+		TokenURI    string `json:"token_uri"`
+	}
+	if err := json.Unmarshal(credentials, &creds); err != nil {
+		return nil, err
+	}
+	if creds.Type != "service_account" || creds.ProjectID == "" || creds.ClientEmail == "" {
+		return nil, errors.New("not a service account key")
+	}
+
+	key, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(creds.PrivateKey))
+	if err != nil {
+		return nil, fmt.Errorf("private key: %w", err)
+	}
+	if creds.TokenURI == "" {
+		creds.TokenURI = defaultTokenURI
+	}
+
+	return &FCM{
+		projectID:   creds.ProjectID,
+		clientEmail: creds.ClientEmail,
+		tokenURI:    creds.TokenURI,
+		key:         key,
+		baseURL:     strings.TrimSuffix(baseURL, "/"),
+		client:      &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// Send delivers one message. It returns ErrUnregistered for tokens FCM has dropped.
+func (f *FCM) Send(ctx context.Context, msg Message) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"message": map[string]interface{}{
+			"token": msg.Token,
+			"notification": map[string]string{
+				"title": msg.Title,
+				"body":  msg.Body,
+			},
+			"data": msg.Data,
+			"android": map[string]interface{}{
+				"notification": map[string]string{"sound": "default"},
+			},
+			"apns": map[string]interface{}{
+				"payload": map[string]interface{}{"aps": map[string]string{"sound": "default"}},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	resp, err := f.post(ctx, body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		// The cached access token was revoked or expired early: mint a new one and try once more
+		resp.Body.Close()
+		f.mu.Lock()
+		f.accessToken = ""
+		f.mu.Unlock()
+		if resp, err = f.post(ctx, body); err != nil {
+			return err
+		}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	return sendError(resp)
+}
+
+// Helper: POST to messages:send with a current access token
+func (f *FCM) post(ctx context.Context, body []byte) (*http.Response, error) {
+	token, err := f.token(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := fmt.Sprintf("%s/v1/projects/%s/messages:send", f.baseURL, url.PathEscape(f.projectID))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	return f.client.Do(req)
+}
+
+// Helper: cached OAuth2 access token, refreshed a minute before it expires
+func (f *FCM) token(ctx context.Context) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.accessToken != "" && time.Now().Before(f.expiresAt.Add(-time.Minute)) {
+		return f.accessToken, nil
+	}
+
+	now := time.Now()
+	assertion, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":   f.clientEmail,
+		"scope": messagingScope,
+		"aud":   f.tokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	}).SignedString(f.key)
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, f.tokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 200))
+		return "", fmt.Errorf("token exchange returned %d: %s", resp.StatusCode, bytes.TrimSpace(snippet))
+	}
+
+	var result struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", err
+	}
+	if result.AccessToken == "" {
+		return "", errors.New("token exchange returned no access token")
+	}
+
+	f.accessToken = result.AccessToken
+	f.expiresAt = now.Add(time.Duration(result.ExpiresIn) * time.Second)
+	return f.accessToken, nil
+}
+
+// Helper: map an FCM error response. UNREGISTERED, and INVALID_ARGUMENT about the token,
+// mean the token is useless; everything else is worth retrying.
+func sendError(resp *http.Response) error {
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+
+	var body struct {
+		Error struct {
+			Message string `json:"message"`
+			Status  string `json:"status"`
+			Details []struct {
+				ErrorCode string `json:"errorCode"`
+			} `json:"details"`
+		} `json:"error"`
+	}
+	json.Unmarshal(raw, &body)
+
+	for _, d := range body.Error.Details {
+		if d.ErrorCode == "UNREGISTERED" {
+			return ErrUnregistered
+		}
+	}
+	if body.Error.Status == "INVALID_ARGUMENT" && strings.Contains(strings.ToLower(body.Error.Message), "registration token") {
+		return ErrUnregistered
+	}
+
+	if body.Error.Message != "" {
+		return fmt.Errorf("FCM returned %d: %s", resp.StatusCode, body.Error.Message)
+	}
+	return fmt.Errorf("FCM returned %d: %s", resp.StatusCode, bytes.TrimSpace(raw))
+}
@@ -0,0 +1,232 @@
+package push
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// fcmStub plays both Google's token endpoint and the FCM send API. Access tokens are checked
+// on every send; revoke one to make the next send with it answer 401.
+type fcmStub struct {
+	t         *testing.T
+	server    *httptest.Server
+	key       *rsa.PrivateKey
+	expiresIn int
+
+	mu        sync.Mutex
+	minted    int
+	sends     int
+	valid     map[string]bool
+	refuseAll bool // answer 401 even to fresh tokens
+	lastSent  map[string]interface{}
+}
+
+func newFCMStub(t *testing.T) *fcmStub {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stub := &fcmStub{t: t, key: key, expiresIn: 3600, valid: map[string]bool{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", stub.serveToken)
+	mux.HandleFunc("/v1/projects/demo-project/messages:send", stub.serveSend)
+	stub.server = httptest.NewServer(mux)
+	t.Cleanup(stub.server.Close)
+	return stub
+}
+
+// credentials is a service account key whose token_uri points at the stub
+func (s *fcmStub) credentials() []byte {
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(s.key)})
+	raw, _ := json.Marshal(map[string]string{
+		"type":         "service_account",
+		"project_id":   "demo-project",
+		"private_key":  string(keyPEM),
+		"client_email": "push@demo-project.iam.gserviceaccount.com",
+		"token_uri":    s.server.URL + "/token",
+	})
+	return raw
+}
+
+func (s *fcmStub) serveToken(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	if r.Form.Get("grant_type") != "urn:ietf:params:oauth:grant-type:jwt-bearer" {
+		http.Error(w, `{"error":"unsupported_grant_type"}`, http.StatusBadRequest)
+		return
+	}
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(r.Form.Get("assertion"), claims, func(*jwt.Token) (interface{}, error) {
+		return &s.key.PublicKey, nil
+	}, jwt.WithValidMethods([]string{"RS256"}), jwt.WithAudience(s.server.URL+"/token"), jwt.WithIssuer("push@demo-project.iam.gserviceaccount.com"))
+	if err != nil || claims["scope"] != messagingScope {
+		s.t.Errorf("bad assertion: %v, claims %v", err, claims)
+		http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	s.minted++
+	token := fmt.Sprintf("access-%d", s.minted)
+	s.valid[token] = true
+	s.mu.Unlock()
+
+	json.NewEncoder(w).Encode(map[string]interface{}{"access_token": token, "expires_in": s.expiresIn, "token_type": "Bearer"})
+}
+
+func (s *fcmStub) serveSend(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Message map[string]interface{} `json:"message"`
+	}
+	json.NewDecoder(r.Body).Decode(&body)
+
+	s.mu.Lock()
+	s.sends++
+	s.lastSent = body.Message
+	authorized := s.valid[strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")] && !s.refuseAll
+	s.mu.Unlock()
+
+	if !authorized {
+		w.WriteHeader(http.StatusUnauthorized)
+		io.WriteString(w, `{"error":{"code":401,"message":"Request had invalid authentication credentials.","status":"UNAUTHENTICATED"}}`)
+		return
+	}
+	switch body.Message["token"] {
+	case "uninstalled":
+		w.WriteHeader(http.StatusNotFound)
+		io.WriteString(w, `{"error":{"code":404,"message":"Requested entity was not found.","status":"NOT_FOUND",
+			"details":[{"@type":"type.googleapis.com/google.firebase.fcm.v1.FcmError","errorCode":"UNREGISTERED"}]}}`)
+	case "garbage":
+		w.WriteHeader(http.StatusBadRequest)
+		io.WriteString(w, `{"error":{"code":400,"message":"The registration token is not a valid FCM registration token","status":"INVALID_ARGUMENT"}}`)
+	case "overloaded":
+		w.WriteHeader(http.StatusServiceUnavailable)
+		io.WriteString(w, `{"error":{"code":503,"message":"The service is currently unavailable.","status":"UNAVAILABLE"}}`)
+	default:
+		io.WriteString(w, `{"name":"projects/demo-project/messages/1"}`)
+	}
+}
+
+func (s *fcmStub) revokeAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.valid = map[string]bool{}
+}
+
+func (s *fcmStub) counts() (minted, sends int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.minted, s.sends
+}
+
+func newTestFCM(t *testing.T, stub *fcmStub) *FCM {
+	fcm, err := NewFCM(stub.credentials(), stub.server.URL+"/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fcm
+}
+
+func TestFCMSendCachesAccessToken(t *testing.T) {
+	stub := newFCMStub(t)
+	fcm := newTestFCM(t, stub)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		err := fcm.Send(ctx, Message{Token: "device-1", Title: "Dinner", Body: "Asha added Dinner", Data: map[string]string{"group_id": "g1"}})
+		if err != nil {
+			t.Fatalf("send %d: %v", i, err)
+		}
+	}
+	if minted, sends := stub.counts(); minted != 1 || sends != 3 {
+		t.Errorf("minted %d tokens for %d sends, want 1 for 3", minted, sends)
+	}
+
+	notification, _ := stub.lastSent["notification"].(map[string]interface{})
+	data, _ := stub.lastSent["data"].(map[string]interface{})
+	if stub.lastSent["token"] != "device-1" || notification["title"] != "Dinner" || data["group_id"] != "g1" {
+		t.Errorf("sent message = %v", stub.lastSent)
+	}
+}
+
+// Tokens within a minute of expiring are replaced before use
+func TestFCMRefreshesExpiringToken(t *testing.T) {
+	stub := newFCMStub(t)
+	stub.expiresIn = 30
+	fcm := newTestFCM(t, stub)
+
+	for i := 0; i < 2; i++ {
+		if err := fcm.Send(context.Background(), Message{Token: "device-1"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if minted, _ := stub.counts(); minted != 2 {
+		t.Errorf("minted %d tokens, want a fresh one per send", minted)
+	}
+}
+
+func TestFCMRetriesOnceAfter401(t *testing.T) {
+	stub := newFCMStub(t)
+	fcm := newTestFCM(t, stub)
+	ctx := context.Background()
+
+	if err := fcm.Send(ctx, Message{Token: "device-1"}); err != nil {
+		t.Fatal(err)
+	}
+	stub.revokeAll()
+	if err := fcm.Send(ctx, Message{Token: "device-1"}); err != nil {
+		t.Fatalf("send after revocation: %v", err)
+	}
+	if minted, sends := stub.counts(); minted != 2 || sends != 3 {
+		t.Errorf("minted %d, sent %d; want a new token and one retried send (2, 3)", minted, sends)
+	}
+
+	// A fresh token that is refused as well is an error, not a loop
+	stub.mu.Lock()
+	stub.refuseAll = true
+	stub.mu.Unlock()
+	err := fcm.Send(ctx, Message{Token: "device-1"})
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("send with every token refused: %v, want a 401 error", err)
+	}
+	if _, sends := stub.counts(); sends != 5 {
+		t.Errorf("%d sends, want 5", sends)
+	}
+}
+
+func TestFCMSendErrors(t *testing.T) {
+	stub := newFCMStub(t)
+	fcm := newTestFCM(t, stub)
+
+	tests := []struct {
+		token        string
+		unregistered bool
+		wantErr      string
+	}{
+		{"uninstalled", true, ""},
+		{"garbage", true, ""},
+		{"overloaded", false, "currently unavailable"},
+	}
+	for _, tt := range tests {
+		err := fcm.Send(context.Background(), Message{Token: tt.token})
+		if errors.Is(err, ErrUnregistered) != tt.unregistered {
+			t.Errorf("%s: %v, unregistered = %v", tt.token, err, tt.unregistered)
+		}
+		if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("%s: %v, want it to mention %q", tt.token, err, tt.wantErr)
+		}
+	}
+}
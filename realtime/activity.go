@@ -0,0 +1,33 @@
+package realtime
+
+import (
+	"log"
+	"splitwise-backend/database"
+	"splitwise-backend/models"
+
+	"gorm.io/gorm"
+)
+
+// Every change worth showing to other members already logs an Activity, so publishing
+// when one is created covers expenses, settlements and membership in one place.
+func registerActivityHook() {
+	err := database.DB.Callback().Create().After("gorm:create").Register("realtime:activity", func(db *gorm.DB) {
+		if db.Error != nil {
+			return
+		}
+
+		// Inside a transaction the row may still be rolled back, and members who react to
+		// the event wouldn't see the change yet: publish once it is committed
+		for _, a := range models.ActivitiesIn(db.Statement.Dest) {
+			database.AfterCommit(db, func() { go publishActivity(a) })
+		}
+	})
+	if err != nil {
+		log.Printf("⚠️  Failed to register real-time activity hook: %v", err)
+	}
+}
+
+// Clients fetch the referenced expense, settlement or member through /api/sync
+func publishActivity(a models.Activity) {
+	Publish(a.Type, a.GroupID, a.Event())
+}
@@ -0,0 +1,132 @@
+package realtime
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	historySize      = 1000 // events kept for clients resuming with Last-Event-ID
+	subscriberBuffer = 64   // a subscriber this far behind is dropped and has to reconnect
+)
+
+// Event is something that happened in a group, delivered to the group's members
+type Event struct {
+	ID      int64           `json:"id"`
+	Type    string          `json:"type"` // Activity.Type, e.g. expense_added
+	GroupID uuid.UUID       `json:"group_id"`
+	Data    json.RawMessage `json:"data"`
+	At      time.Time       `json:"at"`
+}
+
+// Subscription receives every event published after it was created.
+// C is closed when the subscriber falls too far behind or unsubscribes.
+type Subscription struct {
+	C      chan Event
+	closed bool
+}
+
+// Broker fans events out to the subscribers of this process and keeps
+// a short history so reconnecting clients can catch up.
+type Broker struct {
+	mu      sync.Mutex
+	subs    map[*Subscription]struct{}
+	history []Event // ring buffer, oldest first once full
+	start   int     // index of the oldest event when the ring is full
+}
+
+func NewBroker() *Broker {
+	return &Broker{subs: make(map[*Subscription]struct{})}
+}
+
+func (b *Broker) Subscribe() *Subscription {
+	sub := &Subscription{C: make(chan Event, subscriberBuffer)}
+	b.mu.Lock()
+	b.subs[sub] = struct{}{}
+	b.mu.Unlock()
+	return sub
+}
+
+func (b *Broker) Unsubscribe(sub *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.drop(sub)
+}
+
+// Deliver records an event and hands it to every subscriber
+func (b *Broker) Deliver(event Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.history) < historySize {
+		b.history = append(b.history, event)
+	} else {
+		b.history[b.start] = event
+		b.start = (b.start + 1) % historySize
+	}
+
+	for sub := range b.subs {
+		select {
+		case sub.C <- event:
+		default:
+			b.drop(sub) // never block publishers on a slow client
+		}
+	}
+}
+
+// Since returns the events after lastID. ok is false when some of them are no
+// longer in the history, in which case the client has to resync from the API.
+func (b *Broker) Since(lastID int64) (events []Event, ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// An empty history or an ID from the future means this process restarted since the client's last event
+	if len(b.history) == 0 {
+		return nil, false
+	}
+
+	// Events normally arrive in ID order, but nothing here relies on it
+	lowest, highest := b.history[0].ID, b.history[0].ID
+	for _, e := range b.history {
+		if e.ID < lowest {
+			lowest = e.ID
+		}
+		if e.ID > highest {
+			highest = e.ID
+		}
+		if e.ID > lastID {
+			events = append(events, e)
+		}
+	}
+	if lastID < lowest-1 || lastID > highest {
+		return nil, false
+	}
+
+	sort.Slice(events, func(i, j int) bool { return events[i].ID < events[j].ID })
+	return events, true
+}
+
+// Close ends every subscription, e.g. so open streams finish when the server shuts down
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for sub := range b.subs {
+		b.drop(sub)
+	}
+}
+
+// must hold b.mu
+func (b *Broker) drop(sub *Subscription) {
+	if _, ok := b.subs[sub]; !ok {
+		return
+	}
+	delete(b.subs, sub)
+	if !sub.closed {
+		sub.closed = true
+		close(sub.C)
+	}
+}
@@ -0,0 +1,75 @@
+package realtime
+
+import (
+	"reflect"
+	"testing"
+)
+
+func eventIDs(events []Event) []int64 {
+	ids := []int64{}
+	for _, e := range events {
+		ids = append(ids, e.ID)
+	}
+	return ids
+}
+
+// History doesn't have to be in ID order for clients to catch up
+func TestBrokerSinceOutOfOrder(t *testing.T) {
+	b := NewBroker()
+	for _, id := range []int64{10, 12, 11, 14, 13} {
+		b.Deliver(Event{ID: id, Type: "expense_added"})
+	}
+
+	tests := []struct {
+		lastID int64
+		want   []int64
+		ok     bool
+	}{
+		{9, []int64{10, 11, 12, 13, 14}, true},
+		{11, []int64{12, 13, 14}, true},
+		{12, []int64{13, 14}, true},
+		{14, []int64{}, true},
+		{8, nil, false},  // older than the history
+		{15, nil, false}, // from before a restart
+	}
+	for _, tt := range tests {
+		events, ok := b.Since(tt.lastID)
+		if ok != tt.ok || (ok && !reflect.DeepEqual(eventIDs(events), tt.want)) {
+			t.Errorf("Since(%d) = %v, %v; want %v, %v", tt.lastID, eventIDs(events), ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestBrokerSinceAfterWrapping(t *testing.T) {
+	b := NewBroker()
+	for id := int64(1); id <= historySize+5; id++ {
+		b.Deliver(Event{ID: id})
+	}
+
+	if _, ok := b.Since(4); ok {
+		t.Error("Since(4) ok after it fell out of the history")
+	}
+	events, ok := b.Since(historySize + 2)
+	if !ok || !reflect.DeepEqual(eventIDs(events), []int64{historySize + 3, historySize + 4, historySize + 5}) {
+		t.Errorf("Since(%d) = %v, %v", historySize+2, eventIDs(events), ok)
+	}
+}
+
+func TestDecodeRelayed(t *testing.T) {
+	tests := []struct {
+		payload string
+		id      int64
+		ok      bool
+	}{
+		{`42 {"type":"expense_added","data":{"description":"Dinner for two"}}`, 42, true},
+		{`{"id":7,"type":"expense_added","data":{}}`, 7, true}, // published by an older replica
+		{`x {"type":"expense_added"}`, 0, false},
+		{`42 not json`, 0, false},
+	}
+	for _, tt := range tests {
+		event, ok := decodeRelayed(tt.payload)
+		if ok != tt.ok || (ok && (event.ID != tt.id || event.Type != "expense_added")) {
+			t.Errorf("decodeRelayed(%q) = %+v, %v", tt.payload, event, ok)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"splitwise-backend/database"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	redisChannel = "splitfree:events"
+	redisSeqKey  = "splitfree:events:seq"
+)
+
+// Default is the process-wide broker used by the stream endpoint
+var Default = NewBroker()
+
+// localSeq numbers events when there is no Redis to share a counter with. It is
+// only advanced under localMu, together with the delivery, so events reach the
+// broker in ID order.
+var (
+	localMu  sync.Mutex
+	localSeq int64
+)
+
+// publishScript takes the next ID and publishes in one atomic step, so replicas
+// can't publish out of order. Messages are "<id> <event JSON>".
+var publishScript = redis.NewScript(`
+local id = redis.call('INCR', KEYS[1])
+redis.call('PUBLISH', ARGV[1], id .. ' ' .. ARGV[2])
+return id
+`)
+
+// Setup hooks activity logging up to the broker and, when Redis is available,
+// relays events between replicas over pub/sub. Call after database.ConnectRedis.
+func Setup() {
+	registerActivityHook()
+
+	if database.Redis == nil {
+		log.Println("⚠️  Redis not available, real-time events stay on this instance")
+		return
+	}
+	go relayFromRedis()
+	log.Println("✅ Real-time events relayed through Redis")
+}
+
+// Publish sends an event to the members of a group, on every replica
+func Publish(eventType string, groupID uuid.UUID, data interface{}) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		log.Printf("❌ Failed to encode %s event: %v", eventType, err)
+		return
+	}
+	event := Event{Type: eventType, GroupID: groupID, Data: raw, At: time.Now()}
+
+	if database.Redis == nil {
+		localMu.Lock()
+		localSeq++
+		event.ID = localSeq
+		Default.Deliver(event)
+		localMu.Unlock()
+		return
+	}
+
+	// IDs come from a shared counter so Last-Event-ID means the same thing on every replica.
+	// The event reaches this instance's subscribers through the relay like everyone else's.
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	payload, err := json.Marshal(event)
+	if err == nil {
+		err = publishScript.Run(ctx, database.Redis, []string{redisSeqKey}, redisChannel, payload).Err()
+	}
+	if err != nil {
+		log.Printf("❌ Failed to publish %s event through Redis: %v", eventType, err)
+	}
+}
+
+// Helper: decode a relayed message. Plain event JSON, as published before IDs were
+// assigned by the script, is still accepted.
+func decodeRelayed(payload string) (Event, bool) {
+	var event Event
+	prefix, body, found := strings.Cut(payload, " ")
+	if !found || strings.HasPrefix(payload, "{") {
+		return event, json.Unmarshal([]byte(payload), &event) == nil
+	}
+	id, err := strconv.ParseInt(prefix, 10, 64)
+	if err != nil || json.Unmarshal([]byte(body), &event) != nil {
+		return event, false
+	}
+	event.ID = id
+	return event, true
+}
+
+// Helper: deliver events published by any replica to local subscribers, resubscribing after errors
+func relayFromRedis() {
+	for {
+		pubsub := database.Redis.Subscribe(context.Background(), redisChannel)
+		for msg := range pubsub.Channel() {
+			if event, ok := decodeRelayed(msg.Payload); ok {
+				Default.Deliver(event)
+			}
+		}
+		pubsub.Close()
+		log.Println("⚠️  Lost Redis event subscription, reconnecting")
+		time.Sleep(time.Second)
+	}
+}
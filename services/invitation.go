@@ -1,15 +1,58 @@
 package services
 
 import (
+	"errors"
+	"fmt"
 	"log"
 	"splitwise-backend/database"
 	"splitwise-backend/models"
+	"splitwise-backend/utils"
+	"time"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
-// InviteToGroup creates an invitation and sends email/SMS
+var ErrInvitationNotPending = errors.New("invitation is no longer pending")
+
+// InviteToGroup creates an invitation and queues the invitee's notifications.
+// Registered users are added directly unless the group requires consent,
+// in which case the invitation lands in their inbox instead.
 func InviteToGroup(groupID uuid.UUID, invitedBy uuid.UUID, email string, phone string) {
+	var group models.Group
+	if err := database.DB.First(&group, groupID).Error; err != nil {
+		log.Printf("❌ Cannot invite to missing group %s", groupID)
+		return
+	}
+
+	email = utils.NormalizeEmail(email)
+
+	// Check if user is already registered
+	var existingUser models.User
+	registered := false
+	if email != "" {
+		registered = database.DB.Where("email = ?", email).First(&existingUser).Error == nil
+	}
+
+	if registered {
+		var existingMember models.GroupMember
+		if err := database.DB.Where("group_id = ? AND user_id = ?", groupID, existingUser.ID).First(&existingMember).Error; err == nil && existingMember.IsActive() {
+			return
+		}
+
+		if group.JoinPolicy != models.JoinPolicyConsent {
+			// User exists, just add them to the group
+			if _, err := JoinGroup(database.DB, groupID, existingUser.ID); err != nil {
+				log.Printf("❌ Failed to add %s to group %s: %v", email, groupID, err)
+				return
+			}
+			log.Printf("✅ Added existing user %s to group %s", email, groupID)
+			return
+		}
+	}
+
+	ExpireStaleInvitations()
+
 	// Check if invitation already exists
 	var existing models.Invitation
 	query := database.DB.Where("group_id = ? AND status = ?", groupID, "pending")
@@ -24,24 +67,6 @@ func InviteToGroup(groupID uuid.UUID, invitedBy uuid.UUID, email string, phone s
 		return
 	}
 
-	// Check if user is already registered
-	var existingUser models.User
-	if email != "" {
-		if err := database.DB.Where("email = ?", email).First(&existingUser).Error; err == nil {
-			// User exists, just add them to the group
-			var existingMember models.GroupMember
-			if err := database.DB.Where("group_id = ? AND user_id = ?", groupID, existingUser.ID).First(&existingMember).Error; err != nil {
-				database.DB.Create(&models.GroupMember{
-					GroupID: groupID,
-					UserID:  existingUser.ID,
-					Role:    "member",
-				})
-				log.Printf("✅ Added existing user %s to group %s", email, groupID)
-			}
-			return
-		}
-	}
-
 	// Create invitation
 	invitation := models.Invitation{
 		GroupID:   groupID,
@@ -50,21 +75,116 @@ func InviteToGroup(groupID uuid.UUID, invitedBy uuid.UUID, email string, phone s
 		Phone:     phone,
 		Status:    "pending",
 	}
+	if registered {
+		invitation.InviteeID = &existingUser.ID
+	}
+
+	var inviter models.User
+	database.DB.First(&inviter, invitedBy)
 
-	if err := database.DB.Create(&invitation).Error; err != nil {
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&invitation).Error; err != nil {
+			return err
+		}
+
+		// Send notification
+		if registered {
+			return GetNotificationService().NotifyInvitationReceived(tx, invitation, inviter, existingUser, group)
+		} else if email != "" {
+			return GetNotificationService().NotifyInvitation(tx, email, inviter.Name, group)
+		}
+		return nil
+	})
+	if err != nil {
 		log.Printf("❌ Failed to create invitation: %v", err)
 		return
 	}
 
-	// Send notification
-	var inviter models.User
-	database.DB.First(&inviter, invitedBy)
+	log.Printf("✅ Invitation sent to %s/%s for group %s", email, phone, groupID)
+}
+
+// PendingInvitationsFor returns the user's unexpired pending invitations, newest first
+func PendingInvitationsFor(user models.User) []models.Invitation {
+	ExpireStaleInvitations()
+
+	var invitations []models.Invitation
+	database.DB.Preload("Group").Preload("Inviter").
+		Where("status = ?", "pending").
+		Scopes(InvitationsAddressedTo(user)).
+		Order("created_at DESC").
+		Find(&invitations)
+	return invitations
+}
+
+// InvitationsAddressedTo matches invitations sent to the user's account or email.
+// Phone numbers are never verified, so an invitation by phone only reaches its
+// invitee once they are linked to it (invitee_id).
+func InvitationsAddressedTo(user models.User) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("invitee_id = ? OR LOWER(TRIM(email)) = ?", user.ID, utils.NormalizeEmail(user.Email))
+	}
+}
+
+// AcceptInvitation adds the user to the group and marks the invitation accepted
+func AcceptInvitation(inv models.Invitation, user models.User) error {
 	var group models.Group
-	database.DB.First(&group, groupID)
+	database.DB.First(&group, inv.GroupID)
 
-	if email != "" {
-		GetNotificationService().NotifyInvitation(email, inviter.Name, group.Name)
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Invitation{}).
+			Where("id = ? AND status = ?", inv.ID, "pending").
+			Updates(map[string]interface{}{
+				"status":       "accepted",
+				"invitee_id":   user.ID,
+				"responded_at": time.Now(),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvitationNotPending
+		}
+
+		joined, err := JoinGroup(tx, inv.GroupID, user.ID)
+		if err != nil || !joined {
+			return err
+		}
+
+		return tx.Create(&models.Activity{
+			GroupID:     inv.GroupID,
+			UserID:      user.ID,
+			MemberID:    &user.ID,
+			Type:        "member_joined",
+			ReferenceID: inv.ID,
+			Description: fmt.Sprintf("%s joined %s", user.Name, group.Name),
+		}).Error
+	})
+}
+
+// DeclineInvitation marks a pending invitation declined
+func DeclineInvitation(inv models.Invitation, user models.User) error {
+	result := database.DB.Model(&models.Invitation{}).
+		Where("id = ? AND status = ?", inv.ID, "pending").
+		Updates(map[string]interface{}{
+			"status":       "declined",
+			"invitee_id":   user.ID,
+			"responded_at": time.Now(),
+		})
+	if result.Error != nil {
+		return result.Error
 	}
+	if result.RowsAffected == 0 {
+		return ErrInvitationNotPending
+	}
+	return nil
+}
 
-	log.Printf("✅ Invitation sent to %s/%s for group %s", email, phone, groupID)
+// ExpireStaleInvitations marks pending invitations past their expiry as expired.
+// Invitations created before expiry existed fall back to created_at + InvitationTTL.
+func ExpireStaleInvitations() {
+	now := time.Now()
+	database.DB.Model(&models.Invitation{}).
+		Where("status = ?", "pending").
+		Where("(expires_at IS NOT NULL AND expires_at < ?) OR (expires_at IS NULL AND created_at < ?)", now, now.Add(-models.InvitationTTL)).
+		Update("status", "expired")
 }
@@ -0,0 +1,31 @@
+package services
+
+import (
+	"splitwise-backend/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// JoinGroup makes the user an active member, reactivating a former membership if there is one.
+// Returns false when the user is already an active member.
+func JoinGroup(tx *gorm.DB, groupID uuid.UUID, userID uuid.UUID) (bool, error) {
+	var existing models.GroupMember
+	if err := tx.Where("group_id = ? AND user_id = ?", groupID, userID).First(&existing).Error; err == nil {
+		if existing.IsActive() {
+			return false, nil
+		}
+		err := tx.Model(&models.GroupMember{}).
+			Where("group_id = ? AND user_id = ?", groupID, userID).
+			Updates(map[string]interface{}{"status": models.MemberActive, "role": models.RoleMember, "left_at": nil}).Error
+		return err == nil, err
+	}
+
+	err := tx.Create(&models.GroupMember{
+		GroupID: groupID,
+		UserID:  userID,
+		Role:    models.RoleMember,
+		Status:  models.MemberActive,
+	}).Error
+	return err == nil, err
+}
@@ -2,16 +2,20 @@ package services
 
 import (
 	"bytes"
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
 	"html/template"
 	"log"
-	"net/http"
 	"splitwise-backend/config"
 	"splitwise-backend/database"
+	"splitwise-backend/email"
 	"splitwise-backend/models"
+	"splitwise-backend/push"
+	"strings"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 type NotificationService struct{}
@@ -25,214 +29,300 @@ func GetNotificationService() *NotificationService {
 	return notifService
 }
 
+// errUndeliverable marks messages with nowhere to go; they are skipped rather than retried
+var errUndeliverable = errors.New("no way to deliver")
+
 // ============================================================
 // PUSH NOTIFICATIONS via FCM HTTP v1 API
 // ============================================================
 
-type FCMMessage struct {
-	To           string            `json:"to"`
-	Notification FCMNotification   `json:"notification"`
-	Data         map[string]string `json:"data,omitempty"`
-}
-
-type FCMNotification struct {
-	Title string `json:"title"`
-	Body  string `json:"body"`
-	Sound string `json:"sound"`
-}
-
-func (ns *NotificationService) sendPush(fcmToken string, title string, body string, data map[string]string) {
-	if fcmToken == "" {
-		return
-	}
-
-	msg := FCMMessage{
-		To: fcmToken,
-		Notification: FCMNotification{
-			Title: title,
-			Body:  body,
-			Sound: "default",
-		},
-		Data: data,
+// sendPush sends to every registered device of the user and forgets tokens FCM has dropped.
+// One delivered copy is a success: failures on the other devices are logged rather than
+// retried, so the devices that got it don't get it twice.
+func (ns *NotificationService) sendPush(ctx context.Context, userID uuid.UUID, title string, body string, data map[string]string) error {
+	if push.Default == nil {
+		return fmt.Errorf("%w: push notifications are not configured", errUndeliverable)
 	}
 
-	jsonData, err := json.Marshal(msg)
-	if err != nil {
-		log.Printf("❌ FCM marshal error: %v", err)
-		return
+	var devices []models.DeviceToken
+	if err := database.DB.Where("user_id = ?", userID).Find(&devices).Error; err != nil {
+		return err
 	}
-
-	// Using FCM legacy HTTP API (simpler setup)
-	// For production, use FCM HTTP v1 API with service account
-	req, err := http.NewRequest("POST", "https://fcm.googleapis.com/fcm/send", bytes.NewBuffer(jsonData))
-	if err != nil {
-		log.Printf("❌ FCM request error: %v", err)
-		return
+	if len(devices) == 0 {
+		return fmt.Errorf("%w: user has no registered devices", errUndeliverable)
 	}
 
-	// Note: Replace with your FCM server key
-	// For production, use Firebase Admin SDK
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "key=YOUR_FCM_SERVER_KEY")
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		log.Printf("❌ FCM send error: %v", err)
-		return
+	delivered := 0
+	var lastErr error
+	for _, device := range devices {
+		err := push.Default.Send(ctx, push.Message{Token: device.Token, Title: title, Body: body, Data: data})
+		switch {
+		case err == nil:
+			delivered++
+		case errors.Is(err, push.ErrUnregistered):
+			database.DB.Delete(&device)
+			log.Printf("🧹 Removed unregistered device token of user %s", userID)
+		default:
+			lastErr = err
+		}
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode == 200 {
-		log.Printf("✅ Push notification sent to token: %s...", fcmToken[:20])
-	} else {
-		log.Printf("⚠️  FCM returned status: %d", resp.StatusCode)
+	switch {
+	case delivered == 0 && lastErr != nil:
+		return lastErr
+	case delivered == 0:
+		return fmt.Errorf("%w: all of the user's devices were unregistered", errUndeliverable)
+	case lastErr != nil:
+		log.Printf("⚠️  Push reached %d of %d devices of user %s: %v", delivered, len(devices), userID, lastErr)
 	}
+	return nil
 }
 
 // ============================================================
-// EMAIL NOTIFICATIONS via SendGrid
+// EMAIL NOTIFICATIONS via the configured transport
 // ============================================================
 
-type SendGridEmail struct {
-	Personalizations []SGPersonalization `json:"personalizations"`
-	From             SGEmail             `json:"from"`
-	Subject          string              `json:"subject"`
-	Content          []SGContent         `json:"content"`
-}
-
-type SGPersonalization struct {
-	To []SGEmail `json:"to"`
-}
-
-type SGEmail struct {
-	Email string `json:"email"`
-	Name  string `json:"name,omitempty"`
-}
-
-type SGContent struct {
-	Type  string `json:"type"`
-	Value string `json:"value"`
-}
-
-func (ns *NotificationService) sendEmail(toEmail string, toName string, subject string, htmlBody string) {
-	if config.AppConfig.SendGridAPIKey == "" {
-		log.Printf("⚠️  SendGrid API key not set, skipping email to %s", toEmail)
-		return
+// sendEmail hands the email to email.Default. The plain-text part is derived from the HTML.
+func (ns *NotificationService) sendEmail(ctx context.Context, toEmail string, toName string, subject string, htmlBody string, unsubscribeURL string) error {
+	if email.Default == nil {
+		return fmt.Errorf("%w: email is not configured", errUndeliverable)
 	}
-
-	email := SendGridEmail{
-		Personalizations: []SGPersonalization{
-			{
-				To: []SGEmail{{Email: toEmail, Name: toName}},
-			},
-		},
-		From:    SGEmail{Email: config.AppConfig.SendGridFrom, Name: config.AppConfig.AppName},
-		Subject: subject,
-		Content: []SGContent{
-			{Type: "text/html", Value: htmlBody},
-		},
+	if toEmail == "" {
+		return fmt.Errorf("%w: no email address", errUndeliverable)
 	}
 
-	jsonData, err := json.Marshal(email)
-	if err != nil {
-		log.Printf("❌ Email marshal error: %v", err)
-		return
-	}
-
-	req, err := http.NewRequest("POST", "https://api.sendgrid.com/v3/mail/send", bytes.NewBuffer(jsonData))
-	if err != nil {
-		log.Printf("❌ Email request error: %v", err)
-		return
+	msg := email.Message{
+		To:      toEmail,
+		ToName:  toName,
+		Subject: subject,
+		HTML:    htmlBody,
 	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+config.AppConfig.SendGridAPIKey)
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		log.Printf("❌ Email send error: %v", err)
-		return
+	if unsubscribeURL != "" {
+		// Lets mail clients show their own unsubscribe button (RFC 8058 one-click)
+		msg.Headers = map[string]string{
+			"List-Unsubscribe":      "<" + unsubscribeURL + ">",
+			"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+		}
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
-		log.Printf("✅ Email sent to %s", toEmail)
-	} else {
-		log.Printf("⚠️  SendGrid returned status: %d", resp.StatusCode)
+	err := email.Default.Send(ctx, msg)
+	if errors.Is(err, email.ErrRejected) {
+		return fmt.Errorf("%w: %v", errUndeliverable, err)
 	}
+	return err
 }
 
 // ============================================================
 // NOTIFICATION EVENTS
 // ============================================================
-
-// NotifyExpenseAdded sends push + email to all split participants
-func (ns *NotificationService) NotifyExpenseAdded(expense models.Expense, splits []models.ExpenseSplit, payer models.User, group models.Group) {
+//
+// These queue messages in the outbox using the caller's transaction (or database.DB),
+// so notifications go out only for changes that commit and survive restarts.
+// The notification worker does the sending.
+
+// NotifyExpenseAdded queues push + email to all split participants
+func (ns *NotificationService) NotifyExpenseAdded(tx *gorm.DB, expense models.Expense, splits []models.ExpenseSplit, payer models.User, group models.Group) error {
+	var ids []uuid.UUID
+	owed := make(map[uuid.UUID]float64)
 	for _, split := range splits {
 		if split.UserID == expense.PaidBy {
 			continue // Don't notify the payer
 		}
+		ids = append(ids, split.UserID)
+		owed[split.UserID] = split.OwedAmount
+	}
+	if len(ids) == 0 {
+		return nil
+	}
 
-		var user models.User
-		if err := database.DB.First(&user, split.UserID).Error; err != nil {
-			continue
-		}
+	var users []models.User
+	if err := tx.Where("id IN ?", ids).Find(&users).Error; err != nil {
+		return err
+	}
 
+	var messages []models.OutboxMessage
+	for _, user := range users {
 		title := fmt.Sprintf("%s added an expense", payer.Name)
-		body := fmt.Sprintf("You owe %s %.2f for \"%s\" in %s", expense.Currency, split.OwedAmount, expense.Description, group.Name)
+		body := fmt.Sprintf("You owe %s %.2f for \"%s\" in %s", expense.Currency, owed[user.ID], expense.Description, group.Name)
 
-		// Push notification
-		ns.sendPush(user.FCMToken, title, body, map[string]string{
+		messages = append(messages, pushMessage(user, group.ID, models.NotifyExpenseAdded, title, body, map[string]string{
 			"type":       "expense_added",
 			"expense_id": expense.ID.String(),
 			"group_id":   expense.GroupID.String(),
-		})
+		}))
 
-		// Email notification
-		htmlBody := buildExpenseEmailHTML(payer.Name, user.Name, expense.Description, expense.Amount, split.OwedAmount, expense.Currency, group.Name)
-		ns.sendEmail(user.Email, user.Name, fmt.Sprintf("%s added \"%s\" in %s", payer.Name, expense.Description, group.Name), htmlBody)
+		htmlBody := buildExpenseEmailHTML(payer.Name, user.Name, expense.Description, expense.Amount, owed[user.ID], expense.Currency, group.Name)
+		messages = append(messages, emailMessage(user, group.ID, models.NotifyExpenseAdded, fmt.Sprintf("%s added \"%s\" in %s", payer.Name, expense.Description, group.Name), htmlBody))
 	}
+	return enqueue(tx, messages)
 }
 
-// NotifySettlement sends push + email to the payee
-func (ns *NotificationService) NotifySettlement(settlement models.Settlement, payer models.User, payee models.User, group models.Group) {
+// NotifySettlement queues push + email to the payee
+func (ns *NotificationService) NotifySettlement(tx *gorm.DB, settlement models.Settlement, payer models.User, payee models.User, group models.Group) error {
 	title := fmt.Sprintf("%s paid you", payer.Name)
 	body := fmt.Sprintf("%s paid you INR %.2f in %s", payer.Name, settlement.Amount, group.Name)
 
-	// Push
-	ns.sendPush(payee.FCMToken, title, body, map[string]string{
-		"type":     "settlement",
-		"group_id": settlement.GroupID.String(),
-	})
-
-	// Email
 	htmlBody := buildSettlementEmailHTML(payer.Name, payee.Name, settlement.Amount, group.Name)
-	ns.sendEmail(payee.Email, payee.Name, fmt.Sprintf("%s settled up with you in %s", payer.Name, group.Name), htmlBody)
+	return enqueue(tx, []models.OutboxMessage{
+		pushMessage(payee, group.ID, models.NotifySettlement, title, body, map[string]string{
+			"type":     "settlement",
+			"group_id": settlement.GroupID.String(),
+		}),
+		emailMessage(payee, group.ID, models.NotifySettlement, fmt.Sprintf("%s settled up with you in %s", payer.Name, group.Name), htmlBody),
+	})
 }
 
-// NotifyMemberAdded sends push + email to the newly added member
-func (ns *NotificationService) NotifyMemberAdded(group models.Group, adder models.User, newMember models.User) {
+// NotifyMemberAdded queues push + email to the newly added member
+func (ns *NotificationService) NotifyMemberAdded(tx *gorm.DB, group models.Group, adder models.User, newMember models.User) error {
 	title := fmt.Sprintf("You were added to \"%s\"", group.Name)
 	body := fmt.Sprintf("%s added you to the group \"%s\"", adder.Name, group.Name)
 
-	// Push
-	ns.sendPush(newMember.FCMToken, title, body, map[string]string{
-		"type":     "member_added",
-		"group_id": group.ID.String(),
+	htmlBody := buildMemberAddedEmailHTML(adder.Name, newMember.Name, group.Name)
+	return enqueue(tx, []models.OutboxMessage{
+		pushMessage(newMember, group.ID, models.NotifyMemberAdded, title, body, map[string]string{
+			"type":     "member_added",
+			"group_id": group.ID.String(),
+		}),
+		emailMessage(newMember, group.ID, models.NotifyMemberAdded, title, htmlBody),
 	})
+}
 
-	// Email
-	htmlBody := buildMemberAddedEmailHTML(adder.Name, newMember.Name, group.Name)
-	ns.sendEmail(newMember.Email, newMember.Name, title, htmlBody)
+// NotifyInvitation queues an email to someone without an account
+func (ns *NotificationService) NotifyInvitation(tx *gorm.DB, email string, inviterName string, group models.Group) error {
+	subject := fmt.Sprintf("%s invited you to join \"%s\" on %s", inviterName, group.Name, config.AppConfig.AppName)
+	htmlBody := buildInvitationEmailHTML(inviterName, group.Name)
+	return enqueue(tx, []models.OutboxMessage{
+		emailMessage(models.User{Email: email}, group.ID, models.NotifyInvitation, subject, htmlBody),
+	})
 }
 
-// NotifyInvitation sends email to non-registered users
-func (ns *NotificationService) NotifyInvitation(email string, inviterName string, groupName string) {
-	subject := fmt.Sprintf("%s invited you to join \"%s\" on %s", inviterName, groupName, config.AppConfig.AppName)
-	htmlBody := buildInvitationEmailHTML(inviterName, groupName)
-	ns.sendEmail(email, "", subject, htmlBody)
+// NotifyInvitationReceived queues push + email to a registered user invited to a consent-required group
+func (ns *NotificationService) NotifyInvitationReceived(tx *gorm.DB, invitation models.Invitation, inviter models.User, invitee models.User, group models.Group) error {
+	title := fmt.Sprintf("%s invited you to \"%s\"", inviter.Name, group.Name)
+	body := "Open the app to accept or decline the invitation"
+
+	htmlBody := buildInvitationReceivedEmailHTML(inviter.Name, invitee.Name, group.Name)
+	return enqueue(tx, []models.OutboxMessage{
+		pushMessage(invitee, group.ID, models.NotifyInvitation, title, body, map[string]string{
+			"type":          "invitation",
+			"invitation_id": invitation.ID.String(),
+			"group_id":      group.ID.String(),
+		}),
+		emailMessage(invitee, group.ID, models.NotifyInvitation, title, htmlBody),
+	})
+}
+
+// NotifyGroupClosing queues push + email to every member with the final transfers that involve them
+func (ns *NotificationService) NotifyGroupClosing(tx *gorm.DB, group models.Group, closer models.User, members []models.User, transfers []models.Balance) error {
+	var messages []models.OutboxMessage
+	for _, member := range members {
+		var lines []string
+		for _, t := range transfers {
+			if t.From == member.ID {
+				lines = append(lines, fmt.Sprintf("You pay %s %s %.2f", t.ToName, t.Currency, t.Amount))
+			} else if t.To == member.ID {
+				lines = append(lines, fmt.Sprintf("%s pays you %s %.2f", t.FromName, t.Currency, t.Amount))
+			}
+		}
+
+		title := fmt.Sprintf("%s is closing \"%s\"", closer.Name, group.Name)
+		body := "You're all settled up"
+		if len(lines) > 0 {
+			body = strings.Join(lines, ", ")
+		}
+
+		messages = append(messages, pushMessage(member, group.ID, models.NotifyGroupClosing, title, body, map[string]string{
+			"type":     "group_closing",
+			"group_id": group.ID.String(),
+		}))
+
+		htmlBody := buildGroupClosingEmailHTML(closer.Name, member.Name, group.Name, lines)
+		messages = append(messages, emailMessage(member, group.ID, models.NotifyGroupClosing, title, htmlBody))
+	}
+	return enqueue(tx, messages)
+}
+
+// NotifyMentioned queues a push to a member who was @mentioned in a comment
+func (ns *NotificationService) NotifyMentioned(tx *gorm.DB, comment models.Comment, author models.User, mentioned models.User, group models.Group, targetLabel string) error {
+	title := fmt.Sprintf("%s mentioned you in %s", author.Name, group.Name)
+	body := fmt.Sprintf("On %s: %s", targetLabel, comment.Body)
+
+	return enqueue(tx, []models.OutboxMessage{
+		pushMessage(mentioned, group.ID, models.NotifyCommentMention, title, body, map[string]string{
+			"type":        "comment_mention",
+			"group_id":    group.ID.String(),
+			"comment_id":  comment.ID.String(),
+			"target_type": comment.TargetType,
+			"target_id":   comment.TargetID.String(),
+		}),
+	})
+}
+
+// Helper: a push for the user's devices, resolved when it is sent
+func pushMessage(user models.User, groupID uuid.UUID, eventType, title, body string, data map[string]string) models.OutboxMessage {
+	return models.OutboxMessage{
+		UserID:    &user.ID,
+		GroupID:   &groupID,
+		Channel:   models.ChannelPush,
+		EventType: eventType,
+		Title:     title,
+		Body:      body,
+		Data:      data,
+	}
+}
+
+// Helper: an email to the user's current address. Users without an ID are invitees with no account yet.
+func emailMessage(user models.User, groupID uuid.UUID, eventType, subject, htmlBody string) models.OutboxMessage {
+	msg := models.OutboxMessage{
+		GroupID:       &groupID,
+		Channel:       models.ChannelEmail,
+		EventType:     eventType,
+		Recipient:     user.Email,
+		RecipientName: user.Name,
+		Title:         subject,
+		HTML:          htmlBody,
+	}
+	if user.ID != uuid.Nil {
+		msg.UserID = &user.ID
+	}
+	return msg
+}
+
+// Helper: record the notifications in the recipients' inboxes, then queue the messages
+// they want to receive (see applyPreferences)
+func enqueue(tx *gorm.DB, messages []models.OutboxMessage) error {
+	if err := addToInbox(tx, messages); err != nil {
+		return err
+	}
+
+	messages, err := applyPreferences(tx, messages)
+	if err != nil || len(messages) == 0 {
+		return err
+	}
+	return tx.Create(&messages).Error
+}
+
+// Helper: every user gets a push for each notification, so the pushes double as the inbox
+// entries. This happens before preferences are applied: muting a channel keeps the history.
+func addToInbox(tx *gorm.DB, messages []models.OutboxMessage) error {
+	var entries []models.Notification
+	for _, m := range messages {
+		if m.Channel != models.ChannelPush || m.UserID == nil {
+			continue
+		}
+		entries = append(entries, models.Notification{
+			UserID:  *m.UserID,
+			GroupID: m.GroupID,
+			Type:    m.EventType,
+			Title:   m.Title,
+			Body:    m.Body,
+			Data:    m.Data,
+		})
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+	return tx.Create(&entries).Error
 }
 
 // ============================================================
@@ -322,5 +412,55 @@ func buildInvitationEmailHTML(inviterName, groupName string) string {
 </html>`, inviterName, groupName, config.AppConfig.AppURL)
 }
 
-// bytes import needed for template buffer
-var _ = uuid.Nil // keep import
+func buildInvitationReceivedEmailHTML(inviterName, inviteeName, groupName string) string {
+	tmpl := `
+<!DOCTYPE html>
+<html>
+<body style="font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif; max-width: 600px; margin: 0 auto; padding: 20px; background-color: #f5f5f5;">
+	<div style="background: white; border-radius: 12px; padding: 32px; box-shadow: 0 2px 8px rgba(0,0,0,0.1);">
+		<h2 style="color: #1DB954; margin-top: 0;">📨 New group invitation</h2>
+		<p>Hi <strong>{{.InviteeName}}</strong>,</p>
+		<p><strong>{{.InviterName}}</strong> invited you to join <strong>"{{.GroupName}}"</strong>.</p>
+		<p>Open the app to accept or decline.</p>
+		<p style="color: #999; font-size: 12px; margin-top: 24px;">— SplitApp</p>
+	</div>
+</body>
+</html>`
+
+	t, _ := template.New("invitation_received").Parse(tmpl)
+	var buf bytes.Buffer
+	t.Execute(&buf, map[string]interface{}{
+		"InviterName": inviterName,
+		"InviteeName": inviteeName,
+		"GroupName":   groupName,
+	})
+	return buf.String()
+}
+
+func buildGroupClosingEmailHTML(closerName, memberName, groupName string, lines []string) string {
+	tmpl := `
+<!DOCTYPE html>
+<html>
+<body style="font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif; max-width: 600px; margin: 0 auto; padding: 20px; background-color: #f5f5f5;">
+	<div style="background: white; border-radius: 12px; padding: 32px; box-shadow: 0 2px 8px rgba(0,0,0,0.1);">
+		<h2 style="color: #1DB954; margin-top: 0;">🏁 Time to settle up</h2>
+		<p>Hi <strong>{{.MemberName}}</strong>,</p>
+		<p><strong>{{.CloserName}}</strong> closed <strong>"{{.GroupName}}"</strong>. The group will be archived once these final payments are recorded:</p>
+		<div style="background: #f8f9fa; border-radius: 8px; padding: 16px; margin: 16px 0;">
+			{{range .Lines}}<p style="margin: 4px 0; font-size: 16px;">{{.}}</p>{{else}}<p style="margin: 4px 0;">You're all settled up 🎉</p>{{end}}
+		</div>
+		<p style="color: #999; font-size: 12px; margin-top: 24px;">— SplitApp</p>
+	</div>
+</body>
+</html>`
+
+	t, _ := template.New("closing").Parse(tmpl)
+	var buf bytes.Buffer
+	t.Execute(&buf, map[string]interface{}{
+		"CloserName": closerName,
+		"MemberName": memberName,
+		"GroupName":  groupName,
+		"Lines":      lines,
+	})
+	return buf.String()
+}
@@ -0,0 +1,208 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"splitwise-backend/config"
+	"splitwise-backend/models"
+	"splitwise-backend/utils"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const unsubscribePurpose = "unsubscribe"
+
+var ErrInvalidUnsubscribeToken = errors.New("invalid unsubscribe link")
+
+// PreferenceSet holds a user's notification preferences: defaults by event type,
+// and per-group overrides by group then event type
+type PreferenceSet struct {
+	defaults map[string]models.NotificationPreference
+	groups   map[uuid.UUID]map[string]models.NotificationPreference
+}
+
+// Effective returns the channels for an event, in a group if groupID is set
+func (s PreferenceSet) Effective(eventType string, groupID *uuid.UUID) models.NotificationPreference {
+	if groupID != nil {
+		if p, ok := s.groups[*groupID][eventType]; ok {
+			return p
+		}
+	}
+	if p, ok := s.defaults[eventType]; ok {
+		return p
+	}
+	return models.NotificationPreference{EventType: eventType, Push: true, Email: true}
+}
+
+// LoadPreferences returns the preference sets of several users with one query
+func LoadPreferences(tx *gorm.DB, userIDs []uuid.UUID) (map[uuid.UUID]PreferenceSet, error) {
+	sets := make(map[uuid.UUID]PreferenceSet, len(userIDs))
+	for _, id := range userIDs {
+		sets[id] = PreferenceSet{
+			defaults: make(map[string]models.NotificationPreference),
+			groups:   make(map[uuid.UUID]map[string]models.NotificationPreference),
+		}
+	}
+	if len(userIDs) == 0 {
+		return sets, nil
+	}
+
+	var prefs []models.NotificationPreference
+	if err := tx.Where("user_id IN ?", userIDs).Find(&prefs).Error; err != nil {
+		return nil, err
+	}
+	for _, p := range prefs {
+		set := sets[p.UserID]
+		if p.GroupID == nil {
+			set.defaults[p.EventType] = p
+			continue
+		}
+		if set.groups[*p.GroupID] == nil {
+			set.groups[*p.GroupID] = make(map[string]models.NotificationPreference)
+		}
+		set.groups[*p.GroupID][p.EventType] = p
+	}
+	return sets, nil
+}
+
+// SetPreference updates the user's default (groupID nil) or group override for one event type.
+// Channels left nil keep what currently applies.
+func SetPreference(tx *gorm.DB, userID uuid.UUID, groupID *uuid.UUID, input models.NotificationChannelsInput) error {
+	// A new row starts from what applies now; an existing one only changes the channels given
+	sets, err := LoadPreferences(tx, []uuid.UUID{userID})
+	if err != nil {
+		return err
+	}
+	pref := sets[userID].Effective(input.EventType, groupID)
+	updates := []string{"updated_at = EXCLUDED.updated_at"}
+	if input.Push != nil {
+		pref.Push = *input.Push
+		updates = append(updates, "push = EXCLUDED.push")
+	}
+	if input.Email != nil {
+		pref.Email = *input.Email
+		updates = append(updates, "email = EXCLUDED.email")
+	}
+
+	// A single statement, so concurrent updates can't both insert
+	return tx.Exec(`INSERT INTO notification_preferences (id, user_id, group_id, event_type, push, email, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (`+models.NotificationPreferenceKey+`) DO UPDATE SET `+strings.Join(updates, ", "),
+		uuid.New(), userID, groupID, input.EventType, pref.Push, pref.Email, time.Now()).Error
+}
+
+// Helper: drop messages their recipients opted out of, hold back the ones that would land in
+// quiet hours, and add unsubscribe links to emails. Messages to people without an account pass as-is.
+func applyPreferences(tx *gorm.DB, messages []models.OutboxMessage) ([]models.OutboxMessage, error) {
+	var ids []uuid.UUID
+	seen := make(map[uuid.UUID]bool)
+	for _, m := range messages {
+		if m.UserID != nil && !seen[*m.UserID] {
+			seen[*m.UserID] = true
+			ids = append(ids, *m.UserID)
+		}
+	}
+	if len(ids) == 0 {
+		return messages, nil
+	}
+
+	sets, err := LoadPreferences(tx, ids)
+	if err != nil {
+		return nil, err
+	}
+	var users []models.User
+	if err := tx.Where("id IN ?", ids).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	quietUntil := make(map[uuid.UUID]time.Time)
+	now := time.Now()
+	for _, u := range users {
+		if end, ok := u.QuietHours.Until(now, u.Location()); ok {
+			quietUntil[u.ID] = end
+		}
+	}
+
+	kept := messages[:0]
+	for _, m := range messages {
+		if m.UserID != nil {
+			if !sets[*m.UserID].Effective(m.EventType, m.GroupID).Allows(m.Channel) {
+				continue
+			}
+			if end, ok := quietUntil[*m.UserID]; ok {
+				m.NextAttemptAt = end
+			}
+			if m.Channel == models.ChannelEmail {
+				m.Unsubscribe = UnsubscribeURL(*m.UserID, m.EventType)
+				m.HTML = withUnsubscribeFooter(m.HTML, m.Unsubscribe)
+			}
+		}
+		kept = append(kept, m)
+	}
+	return kept, nil
+}
+
+// UnsubscribeURL is the one-click link that turns off one kind of email for a user
+func UnsubscribeURL(userID uuid.UUID, eventType string) string {
+	token := utils.SignValue(unsubscribePurpose, userID.String()+":"+eventType)
+	return strings.TrimSuffix(config.AppConfig.AppURL, "/") + "/notifications/unsubscribe?token=" + url.QueryEscape(token)
+}
+
+// ParseUnsubscribeToken returns the user and event type of a link made by UnsubscribeURL
+func ParseUnsubscribeToken(token string) (uuid.UUID, string, error) {
+	value, ok := utils.VerifySignedValue(unsubscribePurpose, token)
+	if !ok {
+		return uuid.Nil, "", ErrInvalidUnsubscribeToken
+	}
+	rawID, eventType, found := strings.Cut(value, ":")
+	userID, err := uuid.Parse(rawID)
+	if !found || err != nil || !IsNotificationEvent(eventType) {
+		return uuid.Nil, "", ErrInvalidUnsubscribeToken
+	}
+	return userID, eventType, nil
+}
+
+// Unsubscribe turns off emails for the event type in the token, everywhere: the user's default
+// and any group where they had turned them back on
+func Unsubscribe(tx *gorm.DB, token string) (models.User, string, error) {
+	var user models.User
+
+	userID, eventType, err := ParseUnsubscribeToken(token)
+	if err != nil {
+		return user, "", err
+	}
+	if err := tx.First(&user, userID).Error; err != nil {
+		return user, "", ErrInvalidUnsubscribeToken
+	}
+
+	off := false
+	err = tx.Transaction(func(tx *gorm.DB) error {
+		if err := SetPreference(tx, userID, nil, models.NotificationChannelsInput{EventType: eventType, Email: &off}); err != nil {
+			return err
+		}
+		return tx.Model(&models.NotificationPreference{}).
+			Where("user_id = ? AND event_type = ? AND group_id IS NOT NULL", userID, eventType).
+			Update("email", false).Error
+	})
+	return user, eventType, err
+}
+
+// IsNotificationEvent reports whether users can configure the event type
+func IsNotificationEvent(eventType string) bool {
+	for _, e := range models.NotificationEvents {
+		if e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Helper: link at the bottom of the email body
+func withUnsubscribeFooter(html string, link string) string {
+	footer := fmt.Sprintf(`<p style="color: #999; font-size: 12px; text-align: center; margin-top: 16px;">Don't want these emails? <a href="%s" style="color: #999;">Unsubscribe</a></p>
+</body>`, link)
+	return strings.Replace(html, "</body>", footer, 1)
+}
@@ -0,0 +1,61 @@
+package services
+
+import (
+	"splitwise-backend/database"
+	"splitwise-backend/models"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// Concurrent updates of the same preference end up in one row, defaults (NULL group)
+// included, and each update only changes the channels it names
+func TestSetPreferenceUpserts(t *testing.T) {
+	requireDB(t)
+
+	user := createTestUser(t, "Alice")
+	group := createTestGroup(t, user)
+	off, on := false, true
+
+	for _, groupID := range []*uuid.UUID{nil, &group.ID} {
+		var wg sync.WaitGroup
+		errs := make(chan error, 10)
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs <- SetPreference(database.DB, user.ID, groupID, models.NotificationChannelsInput{EventType: models.NotifySettlement, Push: &off})
+			}()
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if err := SetPreference(database.DB, user.ID, groupID, models.NotificationChannelsInput{EventType: models.NotifySettlement, Email: &off}); err != nil {
+			t.Fatal(err)
+		}
+		if err := SetPreference(database.DB, user.ID, groupID, models.NotificationChannelsInput{EventType: models.NotifySettlement, Push: &on}); err != nil {
+			t.Fatal(err)
+		}
+
+		var rows []models.NotificationPreference
+		query := database.DB.Where("user_id = ? AND event_type = ?", user.ID, models.NotifySettlement)
+		if groupID == nil {
+			query = query.Where("group_id IS NULL")
+		} else {
+			query = query.Where("group_id = ?", *groupID)
+		}
+		query.Find(&rows)
+		if len(rows) != 1 {
+			t.Fatalf("group %v: %d rows, want 1", groupID, len(rows))
+		}
+		if !rows[0].Push || rows[0].Email {
+			t.Errorf("group %v: push %v email %v, want push on and email off", groupID, rows[0].Push, rows[0].Email)
+		}
+	}
+}
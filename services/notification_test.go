@@ -0,0 +1,112 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"splitwise-backend/database"
+	"splitwise-backend/models"
+	"splitwise-backend/push"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// Helper: point push.Default at a stub FCM that knows the device tokens in registered
+func useStubFCM(t *testing.T, registered map[string]bool) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"access_token":"stub","expires_in":3600}`)
+	})
+	mux.HandleFunc("/v1/projects/demo-project/messages:send", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Message struct {
+				Token string `json:"token"`
+			} `json:"message"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if !registered[body.Message.Token] {
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, `{"error":{"code":404,"status":"NOT_FOUND","details":[{"errorCode":"UNREGISTERED"}]}}`)
+			return
+		}
+		io.WriteString(w, `{"name":"projects/demo-project/messages/1"}`)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	creds, _ := json.Marshal(map[string]string{
+		"type":         "service_account",
+		"project_id":   "demo-project",
+		"private_key":  string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})),
+		"client_email": "push@demo-project.iam.gserviceaccount.com",
+		"token_uri":    server.URL + "/token",
+	})
+	fcm, err := push.NewFCM(creds, server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prev := push.Default
+	push.Default = fcm
+	t.Cleanup(func() { push.Default = prev })
+}
+
+func TestSendPushPrunesUnregisteredTokens(t *testing.T) {
+	requireDB(t)
+
+	user := createTestUser(t, "Alice")
+	live, gone := "live-"+uuid.NewString(), "gone-"+uuid.NewString()
+	for _, token := range []string{live, gone} {
+		if err := database.DB.Create(&models.DeviceToken{UserID: user.ID, Token: token, Platform: "android"}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	useStubFCM(t, map[string]bool{live: true})
+	ns := GetNotificationService()
+
+	if err := ns.sendPush(context.Background(), user.ID, "Dinner", "Asha added Dinner", nil); err != nil {
+		t.Fatalf("send with one live device: %v", err)
+	}
+	var tokens []string
+	database.DB.Model(&models.DeviceToken{}).Where("user_id = ?", user.ID).Pluck("token", &tokens)
+	if len(tokens) != 1 || tokens[0] != live {
+		t.Fatalf("tokens after send = %v, want only the live one", tokens)
+	}
+
+	// With no device left that FCM knows, the message is undeliverable rather than retried
+	useStubFCM(t, map[string]bool{})
+	err := ns.sendPush(context.Background(), user.ID, "Dinner", "Asha added Dinner", nil)
+	if !errors.Is(err, errUndeliverable) {
+		t.Errorf("send to unregistered devices: %v, want errUndeliverable", err)
+	}
+	var left int64
+	database.DB.Model(&models.DeviceToken{}).Where("user_id = ?", user.ID).Count(&left)
+	if left != 0 {
+		t.Errorf("%d tokens left, want none", left)
+	}
+}
+
+func TestInvitationReceivedEmailEscapesNames(t *testing.T) {
+	html := buildInvitationReceivedEmailHTML(`<a href="https://evil.example">Mallory</a>`, "Bob & Co", `Trip <script>`)
+	for _, raw := range []string{`<a href="https://evil.example">`, "<script>", "Bob & Co"} {
+		if strings.Contains(html, raw) {
+			t.Errorf("email contains %q unescaped", raw)
+		}
+	}
+	if !strings.Contains(html, "Bob &amp; Co") || !strings.Contains(html, "Trip &lt;script&gt;") {
+		t.Errorf("names missing from the email:\n%s", html)
+	}
+}
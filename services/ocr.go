@@ -0,0 +1,286 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"splitwise-backend/database"
+	"splitwise-backend/models"
+	"splitwise-backend/ocr"
+	"splitwise-backend/storage"
+	"splitwise-backend/utils"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ocrTimeout   = 60 * time.Second
+	ocrLease     = 2 * time.Minute // a job whose lease runs out has lost its worker
+	ocrHeartbeat = 30 * time.Second
+)
+
+// ocrWorkerID tells this process's jobs apart from other replicas'
+var ocrWorkerID = func() string {
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), uuid.NewString()[:8])
+}()
+
+var ErrUnsupportedScan = errors.New("only JPEG, PNG, GIF or WebP images can be scanned")
+
+// StartReceiptScan stores an uploaded image and queues an OCR job for it
+func StartReceiptScan(ctx context.Context, groupID, userID uuid.UUID, data []byte, itemize bool) (*models.OCRJob, error) {
+	if ocr.Default == nil {
+		return nil, ocr.ErrDisabled
+	}
+
+	contentType := http.DetectContentType(data)
+	ext, ok := receiptExtensions[contentType]
+	if !ok || !strings.HasPrefix(contentType, "image/") {
+		return nil, ErrUnsupportedScan
+	}
+
+	job := models.OCRJob{
+		ID:      uuid.New(),
+		GroupID: groupID,
+		UserID:  userID,
+		Status:  models.OCRJobPending,
+		Itemize: itemize,
+	}
+	leaseOCRJob(&job)
+	job.ImageKey = fmt.Sprintf("scans/%s%s", job.ID, ext)
+
+	if err := storage.Files.Put(ctx, job.ImageKey, data, contentType); err != nil {
+		return nil, err
+	}
+	if err := database.DB.Create(&job).Error; err != nil {
+		storage.Files.Delete(ctx, job.ImageKey)
+		return nil, err
+	}
+
+	go runOCRJob(job.ID)
+	return &job, nil
+}
+
+// StartExpenseReceiptScan queues an OCR job for a receipt already attached to an expense
+func StartExpenseReceiptScan(expense models.Expense, userID uuid.UUID, itemize bool) (*models.OCRJob, error) {
+	if ocr.Default == nil {
+		return nil, ocr.ErrDisabled
+	}
+	if !strings.HasPrefix(expense.ReceiptContentType, "image/") {
+		return nil, ErrUnsupportedScan
+	}
+
+	job := models.OCRJob{
+		GroupID:   expense.GroupID,
+		UserID:    userID,
+		ExpenseID: &expense.ID,
+		Status:    models.OCRJobPending,
+		Itemize:   itemize,
+		ImageKey:  expense.ReceiptKey,
+	}
+	leaseOCRJob(&job)
+	if err := database.DB.Create(&job).Error; err != nil {
+		return nil, err
+	}
+
+	go runOCRJob(job.ID)
+	return &job, nil
+}
+
+// StartOCRJobReaper fails jobs whose worker went away (a replica that crashed or restarted)
+// until ctx is cancelled. Jobs still held by a live replica keep running.
+func StartOCRJobReaper(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(ocrLease / 2)
+		defer ticker.Stop()
+		for {
+			failExpiredOCRJobs()
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Helper: fail unfinished jobs whose lease ran out
+func failExpiredOCRJobs() {
+	result := database.DB.Model(&models.OCRJob{}).
+		Where("status IN ? AND (lease_until IS NULL OR lease_until < ?)",
+			[]string{models.OCRJobPending, models.OCRJobProcessing}, time.Now()).
+		Updates(map[string]interface{}{
+			"status":       models.OCRJobFailed,
+			"error":        "Scan was interrupted, please try again",
+			"completed_at": time.Now(),
+			"lease_until":  nil,
+		})
+	if result.RowsAffected > 0 {
+		log.Printf("⚠️  Marked %d interrupted OCR jobs as failed", result.RowsAffected)
+	}
+}
+
+// Helper: a new job belongs to the replica that received it, which runs it straight away
+func leaseOCRJob(job *models.OCRJob) {
+	until := time.Now().Add(ocrLease)
+	job.WorkerID = ocrWorkerID
+	job.LeaseUntil = &until
+}
+
+// Helper: renew a job's lease until the returned stop function is called
+func keepOCRLease(jobID uuid.UUID) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(ocrHeartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				database.DB.Model(&models.OCRJob{}).
+					Where("id = ? AND worker_id = ?", jobID, ocrWorkerID).
+					Update("lease_until", time.Now().Add(ocrLease))
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+func runOCRJob(jobID uuid.UUID) {
+	var job models.OCRJob
+	if err := database.DB.First(&job, jobID).Error; err != nil {
+		return
+	}
+	database.DB.Model(&job).Update("status", models.OCRJobProcessing)
+
+	stop := keepOCRLease(job.ID)
+	defer stop()
+
+	// Scans uploaded just for OCR are not kept; an expense's own receipt stays
+	if job.ExpenseID == nil {
+		defer storage.Files.Delete(context.Background(), job.ImageKey)
+	}
+
+	draft, err := scanReceipt(job)
+	now := time.Now()
+	if err != nil {
+		log.Printf("❌ OCR job %s failed: %v", job.ID, err)
+		database.DB.Model(&job).Where("status = ?", models.OCRJobProcessing).Updates(map[string]interface{}{
+			"status":       models.OCRJobFailed,
+			"error":        "Could not read this receipt",
+			"completed_at": now,
+			"lease_until":  nil,
+		})
+		return
+	}
+
+	// A job the reaper already gave up on stays failed: the client has been told so
+	database.DB.Model(&job).Where("status = ?", models.OCRJobProcessing).Updates(map[string]interface{}{
+		"status":       models.OCRJobCompleted,
+		"draft":        draft,
+		"completed_at": now,
+		"lease_until":  nil,
+	})
+	log.Printf("✅ OCR job %s completed (%s, %.2f)", job.ID, draft.Merchant, draft.Total)
+}
+
+// Helper: run the engine and turn its text into a draft expense
+func scanReceipt(job models.OCRJob) (*models.ReceiptDraft, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), ocrTimeout)
+	defer cancel()
+
+	image, err := storage.Files.Get(ctx, job.ImageKey)
+	if err != nil {
+		return nil, err
+	}
+
+	text, err := ocr.Default.Recognize(ctx, image)
+	if err != nil {
+		return nil, err
+	}
+
+	var group models.Group
+	database.DB.First(&group, job.GroupID)
+
+	return BuildReceiptDraft(ocr.Parse(text), group, job.Itemize), nil
+}
+
+// BuildReceiptDraft converts parsed receipt fields into expense requests using the group's defaults
+func BuildReceiptDraft(receipt ocr.Receipt, group models.Group, itemize bool) *models.ReceiptDraft {
+	draft := &models.ReceiptDraft{
+		Merchant: receipt.Merchant,
+		Total:    receipt.Total,
+		Tax:      receipt.Tax,
+		Items:    []models.ReceiptLineItem{},
+	}
+	if receipt.Date != nil {
+		draft.Date = receipt.Date.Format("2006-01-02")
+	}
+	for _, item := range receipt.Items {
+		draft.Items = append(draft.Items, models.ReceiptLineItem{
+			Description: item.Description,
+			Quantity:    item.Quantity,
+			Amount:      item.Amount,
+		})
+	}
+
+	description := receipt.Merchant
+	if description == "" {
+		description = "Receipt"
+	}
+
+	var notes string
+	if receipt.Tax > 0 {
+		notes = fmt.Sprintf("Includes tax %.2f", receipt.Tax)
+	}
+
+	// Split type and currency are left to the group's settings unless the user changes them
+	draft.Expense = models.CreateExpenseRequest{
+		GroupID:     group.ID.String(),
+		Description: description,
+		Amount:      receipt.Total,
+		Currency:    group.Settings.DefaultCurrency,
+		Notes:       notes,
+		ExpenseDate: draft.Date,
+	}
+
+	if !itemize || len(receipt.Items) == 0 {
+		return draft
+	}
+
+	// Spread whatever isn't covered by item prices (tax, service) proportionally over the items
+	var itemsTotal float64
+	for _, item := range receipt.Items {
+		itemsTotal += item.Amount
+	}
+	extra := utils.RoundToTwo(receipt.Total - itemsTotal)
+	if extra < 0 {
+		extra = 0
+	}
+
+	var allocated float64
+	for i, item := range receipt.Items {
+		share := utils.RoundToTwo(extra * item.Amount / itemsTotal)
+		if i == len(receipt.Items)-1 {
+			share = utils.RoundToTwo(extra - allocated) // last item absorbs rounding
+		}
+		allocated += share
+
+		draft.ItemizedExpenses = append(draft.ItemizedExpenses, models.CreateExpenseRequest{
+			GroupID:     group.ID.String(),
+			Description: fmt.Sprintf("%s: %s", description, item.Description),
+			Amount:      utils.RoundToTwo(item.Amount + share),
+			Currency:    group.Settings.DefaultCurrency,
+			SplitType:   "equal",
+			ExpenseDate: draft.Date,
+		})
+	}
+
+	return draft
+}
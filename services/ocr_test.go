@@ -0,0 +1,140 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/png"
+	"splitwise-backend/database"
+	"splitwise-backend/models"
+	"splitwise-backend/ocr"
+	"splitwise-backend/storage"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// useFakeOCR points storage at a scratch directory and OCR at a fake engine for one test
+func useFakeOCR(t *testing.T, engine *ocr.FakeEngine) {
+	t.Helper()
+
+	local, err := storage.NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	prevFiles, prevEngine := storage.Files, ocr.Default
+	storage.Files, ocr.Default = local, engine
+	t.Cleanup(func() { storage.Files, ocr.Default = prevFiles, prevEngine })
+}
+
+func samplePNG(t *testing.T) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 8, 8))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// Helper: poll a job until it leaves pending/processing
+func waitForOCRJob(t *testing.T, jobID uuid.UUID) models.OCRJob {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var job models.OCRJob
+		if err := database.DB.First(&job, jobID).Error; err != nil {
+			t.Fatal(err)
+		}
+		if job.Status == models.OCRJobCompleted || job.Status == models.OCRJobFailed {
+			return job
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job still %s after 5s", job.Status)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestReceiptScanEndToEnd(t *testing.T) {
+	requireDB(t)
+	useFakeOCR(t, &ocr.FakeEngine{Text: ocr.SampleReceipt})
+
+	user := createTestUser(t, "Alice")
+	group := createTestGroup(t, user)
+
+	started, err := StartReceiptScan(context.Background(), group.ID, user.ID, samplePNG(t), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if started.WorkerID != ocrWorkerID || started.LeaseUntil == nil {
+		t.Errorf("new job not leased to this worker: %q %v", started.WorkerID, started.LeaseUntil)
+	}
+
+	job := waitForOCRJob(t, started.ID)
+	if job.Status != models.OCRJobCompleted {
+		t.Fatalf("status = %s (%s)", job.Status, job.Error)
+	}
+	if job.Draft == nil || job.Draft.Merchant != "THE BEACH SHACK" || job.Draft.Total != 1879.50 || job.Draft.Date != "2025-03-14" {
+		t.Fatalf("draft = %+v", job.Draft)
+	}
+	if len(job.Draft.ItemizedExpenses) != 3 {
+		t.Errorf("itemized expenses = %d, want 3", len(job.Draft.ItemizedExpenses))
+	}
+	if job.LeaseUntil != nil {
+		t.Error("finished job still holds a lease")
+	}
+
+	// The scan was uploaded only for OCR, so it is not kept
+	time.Sleep(50 * time.Millisecond)
+	if _, err := storage.Files.Get(context.Background(), job.ImageKey); err != storage.ErrNotFound {
+		t.Errorf("scan image after job: %v, want ErrNotFound", err)
+	}
+}
+
+func TestReceiptScanEngineFailure(t *testing.T) {
+	requireDB(t)
+	useFakeOCR(t, &ocr.FakeEngine{Err: errors.New("engine crashed")})
+
+	user := createTestUser(t, "Alice")
+	group := createTestGroup(t, user)
+
+	started, err := StartReceiptScan(context.Background(), group.ID, user.ID, samplePNG(t), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	job := waitForOCRJob(t, started.ID)
+	if job.Status != models.OCRJobFailed || job.Error == "" || job.Draft != nil {
+		t.Errorf("job = %s %q %+v, want failed with an error and no draft", job.Status, job.Error, job.Draft)
+	}
+}
+
+// Only jobs whose worker stopped renewing the lease are failed; other replicas' running jobs are left alone
+func TestFailExpiredOCRJobs(t *testing.T) {
+	requireDB(t)
+
+	user := createTestUser(t, "Alice")
+	group := createTestGroup(t, user)
+
+	past, future := time.Now().Add(-time.Minute), time.Now().Add(time.Minute)
+	expired := models.OCRJob{GroupID: group.ID, UserID: user.ID, Status: models.OCRJobProcessing, WorkerID: "gone", LeaseUntil: &past}
+	live := models.OCRJob{GroupID: group.ID, UserID: user.ID, Status: models.OCRJobProcessing, WorkerID: "other-replica", LeaseUntil: &future}
+	if err := database.DB.Create(&[]*models.OCRJob{&expired, &live}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	failExpiredOCRJobs()
+
+	database.DB.First(&expired, expired.ID)
+	database.DB.First(&live, live.ID)
+	if expired.Status != models.OCRJobFailed {
+		t.Errorf("expired job status = %s, want failed", expired.Status)
+	}
+	if live.Status != models.OCRJobProcessing {
+		t.Errorf("live job status = %s, want processing", live.Status)
+	}
+}
@@ -0,0 +1,197 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"splitwise-backend/database"
+	"splitwise-backend/models"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	outboxWorkers      = 4
+	outboxBatchSize    = 50
+	outboxPollInterval = 2 * time.Second
+	outboxSendTimeout  = 15 * time.Second
+	outboxMaxAttempts  = 6
+	outboxLease        = 2 * time.Minute // a claimed message is retried after this if the process dies
+	outboxRetention    = 7 * 24 * time.Hour
+
+	inboxReadRetention = 30 * 24 * time.Hour // read inbox entries
+	inboxRetention     = 90 * 24 * time.Hour // any inbox entry
+)
+
+// StartNotificationWorker sends queued notifications with a small pool of senders until ctx
+// is cancelled. The returned channel is closed once the messages already claimed are sent.
+func StartNotificationWorker(ctx context.Context) <-chan struct{} {
+	done := make(chan struct{})
+	jobs := make(chan models.OutboxMessage)
+
+	var wg sync.WaitGroup
+	for i := 0; i < outboxWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for msg := range jobs {
+				deliverNotification(msg)
+			}
+		}()
+	}
+
+	go func() {
+		defer close(done)
+		defer wg.Wait()
+		defer close(jobs)
+
+		ticker := time.NewTicker(outboxPollInterval)
+		defer ticker.Stop()
+
+		lastPrune := time.Time{}
+		for {
+			// Keep claiming while there's a backlog, then wait for the next tick
+			for ctx.Err() == nil {
+				claimed := claimDueNotifications()
+				for _, msg := range claimed {
+					jobs <- msg
+				}
+				if len(claimed) < outboxBatchSize {
+					break
+				}
+			}
+
+			if time.Since(lastPrune) > time.Hour {
+				pruneOutbox()
+				pruneInbox()
+				lastPrune = time.Now()
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	log.Println("✅ Notification worker started")
+	return done
+}
+
+// Helper: lock a batch of due messages and push their next attempt past the lease,
+// so other replicas skip them while they are being sent
+func claimDueNotifications() []models.OutboxMessage {
+	var due []models.OutboxMessage
+	now := time.Now()
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status IN ? AND next_attempt_at <= ?", []string{models.OutboxPending, models.OutboxFailed}, now).
+			Order("next_attempt_at").
+			Limit(outboxBatchSize).
+			Find(&due).Error
+		if err != nil || len(due) == 0 {
+			return err
+		}
+
+		ids := make([]uuid.UUID, 0, len(due))
+		for _, m := range due {
+			ids = append(ids, m.ID)
+		}
+		return tx.Model(&models.OutboxMessage{}).Where("id IN ?", ids).
+			Update("next_attempt_at", now.Add(outboxLease)).Error
+	})
+	if err != nil {
+		log.Printf("❌ Failed to claim notifications: %v", err)
+		return nil
+	}
+	return due
+}
+
+// Helper: make one attempt and record the outcome. A panicking sender counts as a failed attempt.
+func deliverNotification(msg models.OutboxMessage) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("❌ Panic sending notification %s: %v\n%s", msg.ID, r, debug.Stack())
+			recordNotificationAttempt(msg, fmt.Errorf("panic: %v", r))
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), outboxSendTimeout)
+	defer cancel()
+
+	recordNotificationAttempt(msg, GetNotificationService().send(ctx, msg))
+}
+
+// send delivers an outbox message on its channel
+func (ns *NotificationService) send(ctx context.Context, msg models.OutboxMessage) error {
+	switch msg.Channel {
+	case models.ChannelPush:
+		if msg.UserID == nil {
+			return fmt.Errorf("%w: push without a user", errUndeliverable)
+		}
+		return ns.sendPush(ctx, *msg.UserID, msg.Title, msg.Body, msg.Data)
+	case models.ChannelEmail:
+		return ns.sendEmail(ctx, msg.Recipient, msg.RecipientName, msg.Title, msg.HTML, msg.Unsubscribe)
+	default:
+		return fmt.Errorf("%w: unknown channel %q", errUndeliverable, msg.Channel)
+	}
+}
+
+func recordNotificationAttempt(msg models.OutboxMessage, err error) {
+	now := time.Now()
+	updates := map[string]interface{}{
+		"attempts":   msg.Attempts + 1,
+		"last_error": "",
+	}
+
+	switch {
+	case err == nil:
+		updates["status"] = models.OutboxSent
+		updates["sent_at"] = now
+	case errors.Is(err, errUndeliverable):
+		updates["status"] = models.OutboxSkipped
+		updates["last_error"] = err.Error()
+	case msg.Attempts+1 >= outboxMaxAttempts:
+		updates["status"] = models.OutboxDead
+		updates["last_error"] = err.Error()
+		log.Printf("❌ %s notification %s is dead after %d attempts: %v", msg.Channel, msg.ID, msg.Attempts+1, err)
+	default:
+		updates["status"] = models.OutboxFailed
+		updates["last_error"] = err.Error()
+		updates["next_attempt_at"] = now.Add(retryBackoff(msg.Attempts + 1))
+		log.Printf("⚠️  %s notification %s failed, retrying: %v", msg.Channel, msg.ID, err)
+	}
+
+	if err := database.DB.Model(&models.OutboxMessage{}).Where("id = ?", msg.ID).Updates(updates).Error; err != nil {
+		log.Printf("❌ Failed to record notification %s: %v", msg.ID, err)
+	}
+}
+
+// Helper: finished messages are kept for a week to help debug delivery, then removed
+func pruneOutbox() {
+	result := database.DB.
+		Where("status IN ? AND updated_at < ?", []string{models.OutboxSent, models.OutboxSkipped, models.OutboxDead}, time.Now().Add(-outboxRetention)).
+		Delete(&models.OutboxMessage{})
+	if result.RowsAffected > 0 {
+		log.Printf("🧹 Pruned %d old notifications", result.RowsAffected)
+	}
+}
+
+// Helper: the inbox keeps read notifications for a month and unread ones for three
+func pruneInbox() {
+	now := time.Now()
+	result := database.DB.
+		Where("(read_at IS NOT NULL AND read_at < ?) OR created_at < ?", now.Add(-inboxReadRetention), now.Add(-inboxRetention)).
+		Delete(&models.Notification{})
+	if result.RowsAffected > 0 {
+		log.Printf("🧹 Pruned %d old inbox notifications", result.RowsAffected)
+	}
+}
@@ -0,0 +1,184 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"log"
+	"net/http"
+	"splitwise-backend/models"
+	"splitwise-backend/storage"
+
+	_ "image/gif"
+	_ "image/png"
+
+	"github.com/google/uuid"
+)
+
+const (
+	MaxReceiptSize   = 10 << 20 // 10 MB
+	receiptThumbSize = 320      // longest edge of the thumbnail, in pixels
+
+	// Larger images get no thumbnail: decoding one would take gigabytes of memory
+	maxThumbnailPixels = 50_000_000
+)
+
+var ErrUnsupportedReceipt = errors.New("receipt must be a JPEG, PNG, GIF, WebP or PDF file")
+
+// Receipt content types we accept, keyed by detected MIME type
+var receiptExtensions = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"application/pdf": ".pdf",
+}
+
+// StoredReceipt describes the objects written for one upload
+type StoredReceipt struct {
+	Key         string
+	ThumbKey    string // empty when no thumbnail could be made (PDF, WebP)
+	ContentType string
+}
+
+// StoreReceipt validates the file by its content (not its name), stores it and a thumbnail
+func StoreReceipt(ctx context.Context, expenseID uuid.UUID, data []byte) (*StoredReceipt, error) {
+	contentType := http.DetectContentType(data)
+	ext, ok := receiptExtensions[contentType]
+	if !ok {
+		return nil, ErrUnsupportedReceipt
+	}
+
+	// A fresh name per upload so cached copies of a replaced receipt are never served
+	base := fmt.Sprintf("receipts/%s/%s", expenseID, uuid.New())
+	stored := &StoredReceipt{Key: base + ext, ContentType: contentType}
+
+	if err := storage.Files.Put(ctx, stored.Key, data, contentType); err != nil {
+		return nil, err
+	}
+
+	if thumb, err := makeThumbnail(data); err == nil {
+		stored.ThumbKey = base + "_thumb.jpg"
+		if err := storage.Files.Put(ctx, stored.ThumbKey, thumb, "image/jpeg"); err != nil {
+			log.Printf("⚠️  Failed to store receipt thumbnail for expense %s: %v", expenseID, err)
+			stored.ThumbKey = ""
+		}
+	}
+
+	return stored, nil
+}
+
+// DeleteReceipt removes an expense's receipt and thumbnail from storage
+func DeleteReceipt(expense models.Expense) {
+	for _, key := range []string{expense.ReceiptKey, expense.ReceiptThumbKey} {
+		if key == "" {
+			continue
+		}
+		if err := storage.Files.Delete(context.Background(), key); err != nil {
+			log.Printf("⚠️  Failed to delete receipt object %s: %v", key, err)
+		}
+	}
+}
+
+// Helper: decode an image and scale it down to fit receiptThumbSize, encoded as JPEG
+func makeThumbnail(data []byte) ([]byte, error) {
+	// A small file can still claim enormous dimensions; check before allocating the pixels
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 {
+		return nil, errors.New("empty image")
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > maxThumbnailPixels {
+		return nil, fmt.Errorf("image is %dx%d, too large to thumbnail", cfg.Width, cfg.Height)
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	bounds := src.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w == 0 || h == 0 {
+		return nil, errors.New("empty image")
+	}
+
+	tw, th := w, h
+	if w > receiptThumbSize || h > receiptThumbSize {
+		if w >= h {
+			tw, th = receiptThumbSize, max(1, h*receiptThumbSize/w)
+		} else {
+			tw, th = max(1, w*receiptThumbSize/h), receiptThumbSize
+		}
+	}
+
+	// Box filter: each thumbnail pixel averages the source pixels it covers
+	at := pixelReader(src)
+	dst := image.NewRGBA(image.Rect(0, 0, tw, th))
+	for y := 0; y < th; y++ {
+		y0 := bounds.Min.Y + y*h/th
+		y1 := max(y0+1, bounds.Min.Y+(y+1)*h/th)
+		for x := 0; x < tw; x++ {
+			x0 := bounds.Min.X + x*w/tw
+			x1 := max(x0+1, bounds.Min.X+(x+1)*w/tw)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := at(sx, sy)
+					r, g, b, a = r+uint64(cr), g+uint64(cg), b+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{uint8(r / n >> 8), uint8(g / n >> 8), uint8(b / n >> 8), uint8(a / n >> 8)})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 80}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Helper: read pixels straight from the decoders' own layouts (JPEG, PNG, GIF) as
+// premultiplied 16-bit RGBA. image.Image.At boxes a color for every pixel, which is
+// most of the cost on a phone photo.
+func pixelReader(src image.Image) func(x, y int) (r, g, b, a uint32) {
+	switch img := src.(type) {
+	case *image.YCbCr:
+		return func(x, y int) (uint32, uint32, uint32, uint32) {
+			yi, ci := img.YOffset(x, y), img.COffset(x, y)
+			return color.YCbCr{Y: img.Y[yi], Cb: img.Cb[ci], Cr: img.Cr[ci]}.RGBA()
+		}
+	case *image.Gray:
+		return func(x, y int) (uint32, uint32, uint32, uint32) {
+			v := uint32(img.Pix[img.PixOffset(x, y)]) * 0x101
+			return v, v, v, 0xffff
+		}
+	case *image.RGBA:
+		return func(x, y int) (uint32, uint32, uint32, uint32) {
+			p := img.Pix[img.PixOffset(x, y):]
+			return uint32(p[0]) * 0x101, uint32(p[1]) * 0x101, uint32(p[2]) * 0x101, uint32(p[3]) * 0x101
+		}
+	case *image.NRGBA:
+		return func(x, y int) (uint32, uint32, uint32, uint32) {
+			p := img.Pix[img.PixOffset(x, y):]
+			return color.NRGBA{p[0], p[1], p[2], p[3]}.RGBA()
+		}
+	case *image.Paletted:
+		return func(x, y int) (uint32, uint32, uint32, uint32) {
+			return img.Palette[img.Pix[img.PixOffset(x, y)]].RGBA()
+		}
+	default:
+		return func(x, y int) (uint32, uint32, uint32, uint32) {
+			return src.At(x, y).RGBA()
+		}
+	}
+}
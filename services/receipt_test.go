@@ -0,0 +1,100 @@
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/jpeg"
+	"image/png"
+	"math/rand"
+	"testing"
+)
+
+func TestMakeThumbnailScalesDown(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 1000, 500))
+	for i := 0; i < len(src.Pix); i += 4 {
+		copy(src.Pix[i:], []byte{200, 40, 40, 255})
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, src); err != nil {
+		t.Fatal(err)
+	}
+
+	thumb, err := makeThumbnail(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := jpeg.Decode(bytes.NewReader(thumb))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := img.Bounds().Size(); got != image.Pt(320, 160) {
+		t.Errorf("thumbnail size = %v, want 320x160", got)
+	}
+	r, g, b, _ := img.At(160, 80).RGBA()
+	if r>>8 < 180 || g>>8 > 70 || b>>8 > 70 {
+		t.Errorf("thumbnail colour = %d,%d,%d, want about 200,40,40", r>>8, g>>8, b>>8)
+	}
+}
+
+// The header alone decides: a PNG claiming 100000x100000 pixels is refused before decoding
+func TestMakeThumbnailRejectsHugeImage(t *testing.T) {
+	var ihdr [13]byte
+	binary.BigEndian.PutUint32(ihdr[0:], 100000)
+	binary.BigEndian.PutUint32(ihdr[4:], 100000)
+	ihdr[8], ihdr[9] = 8, 2 // 8-bit RGB
+
+	var buf bytes.Buffer
+	buf.WriteString("\x89PNG\r\n\x1a\n")
+	binary.Write(&buf, binary.BigEndian, uint32(len(ihdr)))
+	chunk := append([]byte("IHDR"), ihdr[:]...)
+	buf.Write(chunk)
+	binary.Write(&buf, binary.BigEndian, crc32.ChecksumIEEE(chunk))
+
+	if _, err := makeThumbnail(buf.Bytes()); err == nil {
+		t.Fatal("thumbnail made for a 10 gigapixel image")
+	}
+}
+
+// Every fast path must read the same colours as image.Image.At
+func TestPixelReaderMatchesAt(t *testing.T) {
+	rect := image.Rect(3, 5, 40, 30) // non-zero origin catches offset mistakes
+	rng := rand.New(rand.NewSource(1))
+
+	ycbcr := image.NewYCbCr(rect, image.YCbCrSubsampleRatio420)
+	rng.Read(ycbcr.Y)
+	rng.Read(ycbcr.Cb)
+	rng.Read(ycbcr.Cr)
+	gray := image.NewGray(rect)
+	rng.Read(gray.Pix)
+	rgba := image.NewRGBA(rect)
+	nrgba := image.NewNRGBA(rect)
+	paletted := image.NewPaletted(rect, palette.Plan9)
+	rng.Read(paletted.Pix)
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			a := uint8(rng.Intn(256))
+			rgba.SetRGBA(x, y, color.RGBA{uint8(rng.Intn(int(a) + 1)), uint8(rng.Intn(int(a) + 1)), uint8(rng.Intn(int(a) + 1)), a})
+			nrgba.SetNRGBA(x, y, color.NRGBA{uint8(rng.Intn(256)), uint8(rng.Intn(256)), uint8(rng.Intn(256)), a})
+		}
+	}
+
+	for name, img := range map[string]image.Image{
+		"ycbcr": ycbcr, "gray": gray, "rgba": rgba, "nrgba": nrgba, "paletted": paletted,
+	} {
+		at := pixelReader(img)
+		for y := rect.Min.Y; y < rect.Max.Y; y++ {
+			for x := rect.Min.X; x < rect.Max.X; x++ {
+				r, g, b, a := at(x, y)
+				wr, wg, wb, wa := img.At(x, y).RGBA()
+				if r != wr || g != wg || b != wb || a != wa {
+					t.Fatalf("%s at (%d,%d) = %d,%d,%d,%d, want %d,%d,%d,%d", name, x, y, r, g, b, a, wr, wg, wb, wa)
+				}
+			}
+		}
+	}
+}
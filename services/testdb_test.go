@@ -0,0 +1,69 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"splitwise-backend/config"
+	"splitwise-backend/database"
+	"splitwise-backend/models"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// Tests that need Postgres run against TEST_DATABASE_URL and are skipped without it.
+// Point it at a scratch database: it is migrated on first use, and tests leave their rows behind.
+var connectOnce sync.Once
+
+func requireDB(t *testing.T) {
+	t.Helper()
+
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	connectOnce.Do(func() {
+		config.AppConfig = &config.Config{
+			DatabaseURL: url,
+			JWTSecret:   "test-secret",
+			AppName:     "SplitApp",
+			AppURL:      "http://localhost:8080",
+		}
+		database.Connect()
+	})
+}
+
+func createTestUser(t *testing.T, name string) models.User {
+	t.Helper()
+
+	user := models.User{
+		Email:        fmt.Sprintf("%s-%s@example.test", strings.ToLower(name), uuid.NewString()[:8]),
+		Name:         name,
+		PasswordHash: "not-a-real-hash",
+	}
+	if err := database.DB.Create(&user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	return user
+}
+
+// createTestGroup makes a group owned by its creator, with the others as plain members
+func createTestGroup(t *testing.T, creator models.User, others ...models.User) models.Group {
+	t.Helper()
+
+	group := models.Group{Name: "Test group", Type: "other", CreatedBy: creator.ID}
+	if err := database.DB.Create(&group).Error; err != nil {
+		t.Fatalf("create group: %v", err)
+	}
+
+	members := []models.GroupMember{{GroupID: group.ID, UserID: creator.ID, Role: models.RoleOwner}}
+	for _, u := range others {
+		members = append(members, models.GroupMember{GroupID: group.ID, UserID: u.ID, Role: models.RoleMember})
+	}
+	if err := database.DB.Create(&members).Error; err != nil {
+		t.Fatalf("add members: %v", err)
+	}
+	return group
+}
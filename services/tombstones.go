@@ -0,0 +1,34 @@
+package services
+
+import (
+	"context"
+	"log"
+	"splitwise-backend/database"
+	"splitwise-backend/models"
+	"time"
+)
+
+const tombstonePruneInterval = 6 * time.Hour
+
+// StartTombstonePruner drops sync tombstones that no valid sync token can still need,
+// until ctx is cancelled. Clients with older tokens are told to resync in full.
+func StartTombstonePruner(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(tombstonePruneInterval)
+		defer ticker.Stop()
+		for {
+			pruned, err := database.PruneTombstones(time.Now().Add(-models.TombstoneRetention))
+			if err != nil {
+				log.Printf("❌ Failed to prune sync tombstones: %v", err)
+			} else if pruned > 0 {
+				log.Printf("🧹 Pruned %d sync tombstones", pruned)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
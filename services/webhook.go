@@ -0,0 +1,324 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"splitwise-backend/config"
+	"splitwise-backend/database"
+	"splitwise-backend/models"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	webhookTimeout      = 10 * time.Second
+	webhookMaxAttempts  = 8 // about an hour of retries before a delivery goes dead
+	webhookPollInterval = 5 * time.Second
+	webhookBatchSize    = 20
+	webhookLease        = 2 * time.Minute // a claimed delivery is retried after this if the sender dies
+	webhookRetention    = 30 * 24 * time.Hour
+)
+
+var errPrivateAddress = errors.New("webhook URL resolves to a private address")
+
+// Webhooks never follow redirects, ignore proxy settings and, unless allowed for
+// development, refuse to connect to loopback or private networks
+var webhookClient = &http.Client{
+	Timeout: webhookTimeout,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{Timeout: 5 * time.Second, Control: blockPrivateAddresses}).DialContext,
+	},
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// SetupWebhooks queues a delivery for every matching webhook whenever an activity is logged.
+// Deliveries are written in the same transaction as the activity, so nothing is sent for
+// changes that are rolled back and nothing is lost if the process stops before sending.
+func SetupWebhooks() {
+	err := database.DB.Callback().Create().After("gorm:create").Register("webhooks:activity", func(db *gorm.DB) {
+		if db.Error != nil {
+			return
+		}
+		tx := db.Session(&gorm.Session{NewDB: true})
+		for _, a := range models.ActivitiesIn(db.Statement.Dest) {
+			if err := EnqueueWebhookEvent(tx, a.Type, a.GroupID, a.Event()); err != nil {
+				log.Printf("❌ Failed to queue webhooks for %s: %v", a.Type, err)
+			}
+		}
+	})
+	if err != nil {
+		log.Printf("⚠️  Failed to register webhook hook: %v", err)
+	}
+}
+
+// EnqueueWebhookEvent queues an event for the group's webhooks and its members' personal webhooks
+func EnqueueWebhookEvent(tx *gorm.DB, eventType string, groupID uuid.UUID, data interface{}) error {
+	members := tx.Model(&models.GroupMember{}).Select("user_id").Where("group_id = ?", groupID)
+
+	var hooks []models.Webhook
+	err := tx.Where("active AND (group_id = ? OR (group_id IS NULL AND user_id IN (?)))", groupID, members).
+		Find(&hooks).Error
+	if err != nil {
+		return err
+	}
+
+	var deliveries []models.WebhookDelivery
+	for _, hook := range hooks {
+		if !hook.Wants(eventType) {
+			continue
+		}
+		delivery, err := newWebhookDelivery(hook, eventType, &groupID, data, time.Now())
+		if err != nil {
+			return err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return tx.Create(&deliveries).Error
+}
+
+// PingWebhook sends a test event right away and returns the logged delivery
+func PingWebhook(hook models.Webhook) (models.WebhookDelivery, error) {
+	data := map[string]string{"message": "Webhook is set up correctly"}
+
+	// Scheduled in the future so the background worker leaves it alone while we send it
+	delivery, err := newWebhookDelivery(hook, models.WebhookPing, hook.GroupID, data, time.Now().Add(webhookLease))
+	if err != nil {
+		return delivery, err
+	}
+	if err := database.DB.Create(&delivery).Error; err != nil {
+		return delivery, err
+	}
+	return deliverWebhook(delivery), nil
+}
+
+// Helper: a pending delivery with its payload rendered now, so retries send identical bytes
+func newWebhookDelivery(hook models.Webhook, eventType string, groupID *uuid.UUID, data interface{}, due time.Time) (models.WebhookDelivery, error) {
+	delivery := models.WebhookDelivery{
+		ID:            uuid.New(),
+		WebhookID:     hook.ID,
+		EventType:     eventType,
+		Status:        models.DeliveryPending,
+		NextAttemptAt: due,
+	}
+
+	payload, err := json.Marshal(models.WebhookPayload{
+		ID:        delivery.ID,
+		Type:      eventType,
+		GroupID:   groupID,
+		CreatedAt: time.Now(),
+		Data:      data,
+	})
+	if err != nil {
+		return delivery, err
+	}
+	delivery.Payload = string(payload)
+	return delivery, nil
+}
+
+// StartWebhookWorker sends due deliveries in the background until ctx is cancelled.
+// Several replicas can run it at once. The returned channel closes after the last batch.
+func StartWebhookWorker(ctx context.Context) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(webhookPollInterval)
+		defer ticker.Stop()
+
+		lastPrune := time.Time{}
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			sendDueWebhooks()
+
+			if time.Since(lastPrune) > time.Hour {
+				pruneWebhookDeliveries()
+				lastPrune = time.Now()
+			}
+		}
+	}()
+	log.Println("✅ Webhook worker started")
+	return done
+}
+
+// Helper: claim a batch of due deliveries and send them concurrently
+func sendDueWebhooks() {
+	var due []models.WebhookDelivery
+	now := time.Now()
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status IN ? AND next_attempt_at <= ?", []string{models.DeliveryPending, models.DeliveryFailed}, now).
+			Order("next_attempt_at").
+			Limit(webhookBatchSize).
+			Find(&due).Error
+		if err != nil || len(due) == 0 {
+			return err
+		}
+
+		ids := make([]uuid.UUID, 0, len(due))
+		for _, d := range due {
+			ids = append(ids, d.ID)
+		}
+		return tx.Model(&models.WebhookDelivery{}).Where("id IN ?", ids).
+			Update("next_attempt_at", now.Add(webhookLease)).Error
+	})
+	if err != nil {
+		log.Printf("❌ Failed to claim webhook deliveries: %v", err)
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, d := range due {
+		wg.Add(1)
+		go func(d models.WebhookDelivery) {
+			defer wg.Done()
+			deliverWebhook(d)
+		}(d)
+	}
+	wg.Wait()
+}
+
+// Helper: make one attempt and record the outcome
+func deliverWebhook(delivery models.WebhookDelivery) models.WebhookDelivery {
+	var hook models.Webhook
+	if err := database.DB.First(&hook, delivery.WebhookID).Error; err != nil || !hook.Active {
+		delivery.Status = models.DeliveryDead
+		delivery.LastError = "Webhook was disabled"
+		database.DB.Model(&delivery).Updates(map[string]interface{}{
+			"status":     delivery.Status,
+			"last_error": delivery.LastError,
+		})
+		return delivery
+	}
+
+	statusCode, err := postWebhook(hook, delivery)
+	now := time.Now()
+	delivery.Attempts++
+	delivery.LastStatusCode = statusCode
+	delivery.LastError = ""
+
+	switch {
+	case err == nil:
+		delivery.Status = models.DeliveryDelivered
+		delivery.DeliveredAt = &now
+	case delivery.Attempts >= webhookMaxAttempts:
+		delivery.Status = models.DeliveryDead
+		delivery.LastError = err.Error()
+		log.Printf("❌ Webhook delivery %s to %s is dead after %d attempts: %v", delivery.ID, hook.URL, delivery.Attempts, err)
+	default:
+		delivery.Status = models.DeliveryFailed
+		delivery.LastError = err.Error()
+		delivery.NextAttemptAt = now.Add(retryBackoff(delivery.Attempts))
+	}
+
+	database.DB.Model(&delivery).Updates(map[string]interface{}{
+		"status":           delivery.Status,
+		"attempts":         delivery.Attempts,
+		"last_status_code": delivery.LastStatusCode,
+		"last_error":       delivery.LastError,
+		"next_attempt_at":  delivery.NextAttemptAt,
+		"delivered_at":     delivery.DeliveredAt,
+	})
+	return delivery
+}
+
+// Helper: POST the signed payload; any non-2xx answer is an error
+func postWebhook(hook models.Webhook, delivery models.WebhookDelivery) (int, error) {
+	body := []byte(delivery.Payload)
+	timestamp := time.Now().Unix()
+
+	ctx, cancel := context.WithTimeout(context.Background(), webhookTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", config.AppConfig.AppName+"-Webhooks/1.0")
+	req.Header.Set("X-SplitFree-Event", delivery.EventType)
+	req.Header.Set("X-SplitFree-Delivery", delivery.ID.String())
+	req.Header.Set("X-SplitFree-Signature", fmt.Sprintf("t=%d,v1=%s", timestamp, SignWebhook(hook.Secret, timestamp, body)))
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 200))
+		return resp.StatusCode, fmt.Errorf("receiver answered %d: %s", resp.StatusCode, bytes.TrimSpace(snippet))
+	}
+	return resp.StatusCode, nil
+}
+
+// SignWebhook is the hex HMAC-SHA256 of "<timestamp>.<body>" keyed with the webhook secret.
+// Receivers recompute it and should reject timestamps more than a few minutes old.
+func SignWebhook(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Helper: retry delay for webhooks and notifications: 30s, 1m, 2m, 4m ... capped at one hour
+func retryBackoff(attempts int) time.Duration {
+	delay := 30 * time.Second << (attempts - 1)
+	if delay > time.Hour || delay <= 0 {
+		delay = time.Hour
+	}
+	return delay
+}
+
+// Helper: delivered and dead deliveries are kept for a while as a log, then removed
+func pruneWebhookDeliveries() {
+	result := database.DB.
+		Where("status IN ? AND updated_at < ?", []string{models.DeliveryDelivered, models.DeliveryDead}, time.Now().Add(-webhookRetention)).
+		Delete(&models.WebhookDelivery{})
+	if result.RowsAffected > 0 {
+		log.Printf("🧹 Pruned %d old webhook deliveries", result.RowsAffected)
+	}
+}
+
+// Helper: dialer hook that rejects loopback, private and link-local addresses
+func blockPrivateAddresses(network, address string, _ syscall.RawConn) error {
+	if config.AppConfig.WebhookAllowPrivate {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast() {
+		return errPrivateAddress
+	}
+	return nil
+}
@@ -0,0 +1,246 @@
+package services
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"splitwise-backend/config"
+	"splitwise-backend/database"
+	"splitwise-backend/models"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// webhookReceiver checks every request's signature the way a receiver would, counts the
+// ones that don't match, and answers with status
+type webhookReceiver struct {
+	secret string
+	status int
+
+	mu       sync.Mutex
+	requests []*http.Request
+	badSigs  int
+}
+
+func (rcv *webhookReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	var timestamp int64
+	var signature string
+	for _, part := range strings.Split(r.Header.Get("X-SplitFree-Signature"), ",") {
+		if v, ok := strings.CutPrefix(part, "t="); ok {
+			timestamp, _ = strconv.ParseInt(v, 10, 64)
+		} else if v, ok := strings.CutPrefix(part, "v1="); ok {
+			signature = v
+		}
+	}
+	age := time.Since(time.Unix(timestamp, 0))
+	valid := signature != "" && signature == SignWebhook(rcv.secret, timestamp, body) &&
+		age > -time.Minute && age < time.Minute
+
+	rcv.mu.Lock()
+	defer rcv.mu.Unlock()
+	rcv.requests = append(rcv.requests, r)
+	if !valid {
+		rcv.badSigs++
+	}
+	w.WriteHeader(rcv.status)
+	io.WriteString(w, "receiver says hi")
+}
+
+func (rcv *webhookReceiver) count() int {
+	rcv.mu.Lock()
+	defer rcv.mu.Unlock()
+	return len(rcv.requests)
+}
+
+// Helper: a receiver on localhost, which webhooks may only reach with WebhookAllowPrivate
+func newWebhookReceiver(t *testing.T, secret string, status int) (*webhookReceiver, *httptest.Server) {
+	rcv := &webhookReceiver{secret: secret, status: status}
+	server := httptest.NewServer(rcv)
+	t.Cleanup(server.Close)
+	return rcv, server
+}
+
+func allowPrivateWebhooks(t *testing.T, allow bool) {
+	prev := config.AppConfig
+	cfg := config.Config{AppName: "SplitApp"}
+	if prev != nil {
+		cfg = *prev
+	}
+	cfg.WebhookAllowPrivate = allow
+	config.AppConfig = &cfg
+	t.Cleanup(func() { config.AppConfig = prev })
+}
+
+func TestPostWebhookSigned(t *testing.T) {
+	allowPrivateWebhooks(t, true)
+	rcv, server := newWebhookReceiver(t, "s3cret", http.StatusOK)
+
+	hook := models.Webhook{ID: uuid.New(), URL: server.URL, Secret: "s3cret"}
+	delivery, err := newWebhookDelivery(hook, "expense_added", nil, map[string]string{"k": "v"}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status, err := postWebhook(hook, delivery)
+	if err != nil || status != http.StatusOK {
+		t.Fatalf("post = %d, %v", status, err)
+	}
+	if rcv.count() != 1 {
+		t.Fatalf("receiver got %d requests", rcv.count())
+	}
+	r := rcv.requests[0]
+	if r.Header.Get("X-SplitFree-Event") != "expense_added" || r.Header.Get("X-SplitFree-Delivery") != delivery.ID.String() {
+		t.Errorf("headers = %v", r.Header)
+	}
+	if rcv.badSigs != 0 {
+		t.Errorf("signature %q does not verify", r.Header.Get("X-SplitFree-Signature"))
+	}
+
+	// Signed with another secret, the receiver must notice
+	hook.Secret = "other"
+	postWebhook(hook, delivery)
+	if rcv.badSigs != 1 {
+		t.Error("receiver accepted a signature made with the wrong secret")
+	}
+}
+
+func TestPostWebhookRefusesPrivateAddresses(t *testing.T) {
+	allowPrivateWebhooks(t, false)
+	rcv, server := newWebhookReceiver(t, "s3cret", http.StatusOK)
+
+	hook := models.Webhook{ID: uuid.New(), URL: server.URL, Secret: "s3cret"}
+	delivery, _ := newWebhookDelivery(hook, "expense_added", nil, nil, time.Now())
+	if _, err := postWebhook(hook, delivery); err == nil || !strings.Contains(err.Error(), errPrivateAddress.Error()) {
+		t.Errorf("post to localhost: %v, want %v", err, errPrivateAddress)
+	}
+	if rcv.count() != 0 {
+		t.Error("request reached a private address")
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{7, 32 * time.Minute},
+		{8, time.Hour},
+		{100, time.Hour},
+	}
+	for _, tt := range tests {
+		if got := retryBackoff(tt.attempts); got != tt.want {
+			t.Errorf("retryBackoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+// Helper: a group webhook pointing at url
+func createTestWebhook(t *testing.T, url string) models.Webhook {
+	t.Helper()
+
+	owner := createTestUser(t, "Owner")
+	group := createTestGroup(t, owner)
+	hook := models.Webhook{GroupID: &group.ID, UserID: owner.ID, URL: url, Secret: "s3cret", Active: true}
+	if err := database.DB.Create(&hook).Error; err != nil {
+		t.Fatal(err)
+	}
+	return hook
+}
+
+// A receiver answering 500 gets the delivery again after a backoff, until it goes dead
+func TestWebhookRetriesUntilDead(t *testing.T) {
+	requireDB(t)
+	allowPrivateWebhooks(t, true)
+	rcv, server := newWebhookReceiver(t, "s3cret", http.StatusInternalServerError)
+	hook := createTestWebhook(t, server.URL)
+
+	delivery, err := newWebhookDelivery(hook, "expense_added", hook.GroupID, map[string]string{"k": "v"}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := database.DB.Create(&delivery).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	reload := func() models.WebhookDelivery {
+		var d models.WebhookDelivery
+		if err := database.DB.First(&d, delivery.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+
+	// First attempt through the worker
+	sendDueWebhooks()
+	d := reload()
+	if rcv.count() != 1 || d.Status != models.DeliveryFailed || d.Attempts != 1 || d.LastStatusCode != http.StatusInternalServerError {
+		t.Fatalf("after a 500: %d requests, delivery %+v", rcv.count(), d)
+	}
+	if !strings.Contains(d.LastError, "receiver says hi") {
+		t.Errorf("last error = %q, want the receiver's answer", d.LastError)
+	}
+	if wait := time.Until(d.NextAttemptAt); wait < 25*time.Second || wait > 35*time.Second {
+		t.Errorf("next attempt in %v, want about 30s", wait)
+	}
+
+	// Not retried before it is due, retried once it is
+	sendDueWebhooks()
+	if rcv.count() != 1 {
+		t.Fatal("retried before the backoff passed")
+	}
+	database.DB.Model(&d).Update("next_attempt_at", time.Now().Add(-time.Second))
+	sendDueWebhooks()
+	d = reload()
+	if rcv.count() != 2 || d.Attempts != 2 {
+		t.Fatalf("after the backoff: %d requests, %d attempts", rcv.count(), d.Attempts)
+	}
+	if wait := time.Until(d.NextAttemptAt); wait < 55*time.Second || wait > 65*time.Second {
+		t.Errorf("second backoff %v, want about 1m", wait)
+	}
+
+	for d.Attempts < webhookMaxAttempts {
+		d = deliverWebhook(d)
+	}
+	d = reload()
+	if d.Status != models.DeliveryDead || d.Attempts != webhookMaxAttempts || rcv.count() != webhookMaxAttempts {
+		t.Errorf("after %d attempts: status %s, %d attempts, %d requests", webhookMaxAttempts, d.Status, d.Attempts, rcv.count())
+	}
+	if rcv.badSigs != 0 {
+		t.Errorf("%d retries were not signed correctly", rcv.badSigs)
+	}
+
+	// Dead deliveries stay dead
+	database.DB.Model(&d).Update("next_attempt_at", time.Now().Add(-time.Second))
+	sendDueWebhooks()
+	if rcv.count() != webhookMaxAttempts {
+		t.Error("dead delivery was sent again")
+	}
+}
+
+func TestPingWebhook(t *testing.T) {
+	requireDB(t)
+	allowPrivateWebhooks(t, true)
+	rcv, server := newWebhookReceiver(t, "s3cret", http.StatusNoContent)
+	hook := createTestWebhook(t, server.URL)
+
+	delivery, err := PingWebhook(hook)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if delivery.Status != models.DeliveryDelivered || delivery.LastStatusCode != http.StatusNoContent || delivery.DeliveredAt == nil {
+		t.Errorf("ping delivery = %+v", delivery)
+	}
+	if rcv.count() != 1 || rcv.requests[0].Header.Get("X-SplitFree-Event") != models.WebhookPing || rcv.badSigs != 0 {
+		t.Errorf("receiver got %d requests, %d badly signed", rcv.count(), rcv.badSigs)
+	}
+}
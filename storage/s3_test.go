@@ -0,0 +1,176 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeS3 is a path-style bucket that checks Signature V4 the way S3 does: it rebuilds the
+// canonical request from what arrived on the wire and compares signatures
+type fakeS3 struct {
+	region  string
+	secrets map[string]string // access key → secret key
+
+	mu      sync.Mutex
+	objects map[string][]byte // escaped path → body
+	types   map[string]string
+}
+
+var authPattern = regexp.MustCompile(`^AWS4-HMAC-SHA256 Credential=([^/]+)/(\d{8})/([^/]+)/s3/aws4_request, SignedHeaders=([a-z0-9;-]+), Signature=([0-9a-f]{64})$`)
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	if msg := f.verify(r, body); msg != "" {
+		http.Error(w, "SignatureDoesNotMatch: "+msg, http.StatusForbidden)
+		return
+	}
+
+	// RequestURI is exactly what the client signed, before any unescaping
+	path := strings.SplitN(r.RequestURI, "?", 2)[0]
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		f.objects[path] = body
+		f.types[path] = r.Header.Get("Content-Type")
+	case http.MethodGet:
+		data, ok := f.objects[path]
+		if !ok {
+			http.Error(w, "NoSuchKey", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", f.types[path])
+		w.Write(data)
+	case http.MethodDelete:
+		delete(f.objects, path)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (f *fakeS3) verify(r *http.Request, body []byte) string {
+	m := authPattern.FindStringSubmatch(r.Header.Get("Authorization"))
+	if m == nil {
+		return "malformed Authorization header"
+	}
+	accessKey, day, region, signedHeaders, signature := m[1], m[2], m[3], m[4], m[5]
+
+	secret, ok := f.secrets[accessKey]
+	if !ok {
+		return "unknown access key"
+	}
+	if region != f.region {
+		return "wrong region"
+	}
+	amzDate := r.Header.Get("X-Amz-Date")
+	if !strings.HasPrefix(amzDate, day) {
+		return "credential date does not match X-Amz-Date"
+	}
+	sum := sha256.Sum256(body)
+	if payload := hex.EncodeToString(sum[:]); r.Header.Get("X-Amz-Content-Sha256") != payload {
+		return "payload hash mismatch"
+	}
+
+	names := strings.Split(signedHeaders, ";")
+	if !sort.StringsAreSorted(names) {
+		return "signed headers not sorted"
+	}
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		value := r.Header.Get(name)
+		if name == "host" {
+			value = r.Host
+		}
+		fmt.Fprintf(&canonicalHeaders, "%s:%s\n", name, strings.TrimSpace(value))
+	}
+
+	canonicalRequest := strings.Join([]string{
+		r.Method,
+		strings.SplitN(r.RequestURI, "?", 2)[0],
+		r.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		r.Header.Get("X-Amz-Content-Sha256"),
+	}, "\n")
+	hashed := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		fmt.Sprintf("%s/%s/s3/aws4_request", day, region),
+		hex.EncodeToString(hashed[:]),
+	}, "\n")
+
+	key := []byte("AWS4" + secret)
+	for _, part := range []string{day, region, "s3", "aws4_request", stringToSign} {
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(part))
+		key = mac.Sum(nil)
+	}
+	if hex.EncodeToString(key) != signature {
+		return "signature mismatch"
+	}
+	return ""
+}
+
+func newFakeS3(t *testing.T) (*fakeS3, *httptest.Server) {
+	f := &fakeS3{
+		region:  "eu-west-1",
+		secrets: map[string]string{"AKIDEXAMPLE": "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"},
+		objects: map[string][]byte{},
+		types:   map[string]string{},
+	}
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+	return f, server
+}
+
+func TestS3RoundTrip(t *testing.T) {
+	f, server := newFakeS3(t)
+	s3 := NewS3Storage(server.URL, "eu-west-1", "receipts", "AKIDEXAMPLE", "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", true)
+	ctx := context.Background()
+
+	// Spaces and non-ASCII must be escaped identically in the URL and the signature
+	key := "receipts/2024 trip/café+bill.jpg"
+	if err := s3.Put(ctx, key, []byte("jpeg bytes"), "image/jpeg"); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	if _, ok := f.objects["/receipts/receipts/2024%20trip/caf%C3%A9%2Bbill.jpg"]; !ok {
+		t.Fatalf("object stored under unexpected path: %v", f.objects)
+	}
+
+	got, err := s3.Get(ctx, key)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if string(got) != "jpeg bytes" {
+		t.Errorf("get = %q", got)
+	}
+
+	if err := s3.Delete(ctx, key); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := s3.Get(ctx, key); err != ErrNotFound {
+		t.Errorf("get after delete: %v, want ErrNotFound", err)
+	}
+}
+
+func TestS3WrongSecretRejected(t *testing.T) {
+	_, server := newFakeS3(t)
+	s3 := NewS3Storage(server.URL, "eu-west-1", "receipts", "AKIDEXAMPLE", "not-the-secret", true)
+
+	err := s3.Put(context.Background(), "receipts/a.jpg", []byte("x"), "image/jpeg")
+	if err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("put with wrong secret: %v, want a 403", err)
+	}
+}
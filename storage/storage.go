@@ -0,0 +1,40 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"log"
+	"splitwise-backend/config"
+)
+
+var ErrNotFound = errors.New("object not found")
+
+// Storage keeps uploaded files (receipts, thumbnails) by key
+type Storage interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	Get(ctx context.Context, key string) ([]byte, error)
+	Delete(ctx context.Context, key string) error
+}
+
+var Files Storage
+
+// Connect sets up the configured storage backend
+func Connect() {
+	cfg := config.AppConfig
+
+	switch cfg.StorageDriver {
+	case "s3":
+		if cfg.S3Bucket == "" {
+			log.Fatal("S3_BUCKET is required when STORAGE_DRIVER=s3")
+		}
+		Files = NewS3Storage(cfg.S3Endpoint, cfg.S3Region, cfg.S3Bucket, cfg.S3AccessKey, cfg.S3SecretKey, cfg.S3PathStyle)
+		log.Printf("✅ Using S3 storage (bucket %s at %s)", cfg.S3Bucket, cfg.S3Endpoint)
+	default:
+		local, err := NewLocalStorage(cfg.StorageLocalPath)
+		if err != nil {
+			log.Fatal("Failed to set up local storage:", err)
+		}
+		Files = local
+		log.Printf("✅ Using local storage at %s", local.Root)
+	}
+}
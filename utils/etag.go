@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+var ErrInvalidIfMatch = errors.New("If-Match must be the ETag of the version you edited, e.g. \"3\"")
+
+// SetETag sends a row's version number as its ETag
+func SetETag(c *gin.Context, version int) {
+	c.Header("ETag", strconv.Quote(strconv.Itoa(version)))
+}
+
+// IfMatchVersion reads the version a client based its edit on from the If-Match header.
+// ok is false when the header is missing or "*", meaning the write is unconditional.
+func IfMatchVersion(c *gin.Context) (version int, ok bool, err error) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		return 0, false, nil
+	}
+
+	// Versions are compared as numbers, so weak tags are as good as strong ones
+	tag := strings.Trim(strings.TrimPrefix(header, "W/"), `"`)
+	version, err = strconv.Atoi(tag)
+	if err != nil || version < 1 {
+		return 0, false, ErrInvalidIfMatch
+	}
+	return version, true, nil
+}
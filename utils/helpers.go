@@ -1,8 +1,11 @@
 package utils
 
 import (
+	"crypto/rand"
+	"encoding/base64"
 	"math"
 	"net/http"
+	"sort"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -13,6 +16,7 @@ type APIResponse struct {
 	Success bool        `json:"success"`
 	Message string      `json:"message,omitempty"`
 	Data    interface{} `json:"data,omitempty"`
+	Meta    interface{} `json:"meta,omitempty"` // totals, paging info
 }
 
 func SuccessResponse(c *gin.Context, statusCode int, message string, data interface{}) {
@@ -23,6 +27,16 @@ func SuccessResponse(c *gin.Context, statusCode int, message string, data interf
 	})
 }
 
+// SuccessResponseWithMeta is SuccessResponse plus metadata about the result set
+func SuccessResponseWithMeta(c *gin.Context, statusCode int, message string, data interface{}, meta interface{}) {
+	c.JSON(statusCode, APIResponse{
+		Success: true,
+		Message: message,
+		Data:    data,
+		Meta:    meta,
+	})
+}
+
 func ErrorResponse(c *gin.Context, statusCode int, message string) {
 	c.JSON(statusCode, APIResponse{
 		Success: false,
@@ -42,6 +56,19 @@ func NotFound(c *gin.Context, message string) {
 	ErrorResponse(c, http.StatusNotFound, message)
 }
 
+func Forbidden(c *gin.Context, message string) {
+	ErrorResponse(c, http.StatusForbidden, message)
+}
+
+// Conflict responds with 409 and data describing what is blocking the request
+func Conflict(c *gin.Context, message string, data interface{}) {
+	c.JSON(http.StatusConflict, APIResponse{
+		Success: false,
+		Message: message,
+		Data:    data,
+	})
+}
+
 func InternalError(c *gin.Context, message string) {
 	ErrorResponse(c, http.StatusInternalServerError, message)
 }
@@ -65,12 +92,68 @@ func RoundToTwo(val float64) float64 {
 	return math.Round(val*100) / 100
 }
 
-// Pagination helpers
-type PaginationQuery struct {
-	Page  int `form:"page,default=1"`
-	Limit int `form:"limit,default=20"`
+// Split an amount into n parts that differ by at most one cent and add up exactly.
+// Leftover cents go to the first parts.
+func SplitEvenly(amount float64, n int) []float64 {
+	parts := make([]float64, n)
+	if n <= 0 {
+		return parts
+	}
+	cents := int64(math.Round(amount * 100))
+	base, extra := cents/int64(n), cents%int64(n)
+	for i := range parts {
+		c := base
+		if int64(i) < extra {
+			c++
+		}
+		parts[i] = float64(c) / 100
+	}
+	return parts
+}
+
+// Split an amount in proportion to weights so the parts add up exactly. Each part is
+// rounded down to the cent and the leftover cents go to the largest remainders.
+func SplitByWeights(amount float64, weights []float64) []float64 {
+	parts := make([]float64, len(weights))
+	var totalWeight float64
+	for _, w := range weights {
+		totalWeight += w
+	}
+	if totalWeight == 0 {
+		return parts
+	}
+
+	cents := int64(math.Round(amount * 100))
+	floors := make([]int64, len(weights))
+	remainders := make([]float64, len(weights))
+	leftover := cents
+	for i, w := range weights {
+		exact := float64(cents) * w / totalWeight
+		floors[i] = int64(math.Floor(exact))
+		remainders[i] = exact - float64(floors[i])
+		leftover -= floors[i]
+	}
+
+	order := make([]int, len(weights))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return remainders[order[a]] > remainders[order[b]] })
+	for i := 0; int64(i) < leftover && i < len(order); i++ {
+		floors[order[i]]++
+	}
+
+	for i, c := range floors {
+		parts[i] = float64(c) / 100
+	}
+	return parts
 }
 
-func (p *PaginationQuery) Offset() int {
-	return (p.Page - 1) * p.Limit
+// RandomToken returns a URL-safe random string built from n random bytes
+func RandomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
 }
@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitEvenly(t *testing.T) {
+	tests := []struct {
+		amount float64
+		n      int
+		want   []float64
+	}{
+		{90, 3, []float64{30, 30, 30}},
+		{100, 3, []float64{33.34, 33.33, 33.33}},
+		{10, 6, []float64{1.67, 1.67, 1.67, 1.67, 1.66, 1.66}},
+		{0.05, 3, []float64{0.02, 0.02, 0.01}},
+		{0.01, 2, []float64{0.01, 0}},
+		{10, 0, []float64{}},
+	}
+	for _, tt := range tests {
+		if got := SplitEvenly(tt.amount, tt.n); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SplitEvenly(%v, %d) = %v, want %v", tt.amount, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestSplitByWeights(t *testing.T) {
+	tests := []struct {
+		name    string
+		amount  float64
+		weights []float64
+		want    []float64
+	}{
+		{"equal shares of 100", 100, []float64{1, 1, 1}, []float64{33.34, 33.33, 33.33}},
+		{"percentages", 10, []float64{33.33, 33.33, 33.34}, []float64{3.33, 3.33, 3.34}},
+		{"largest remainder wins the cent", 1, []float64{1, 2}, []float64{0.33, 0.67}},
+		{"exact shares stay exact", 90, []float64{2, 1}, []float64{60, 30}},
+		{"cents over several parts", 0.05, []float64{1, 1, 1, 1, 1, 1}, []float64{0.01, 0.01, 0.01, 0.01, 0.01, 0}},
+		{"zero weight owes nothing", 50, []float64{1, 0, 1}, []float64{25, 0, 25}},
+		{"no weight at all", 50, []float64{0, 0}, []float64{0, 0}},
+	}
+	for _, tt := range tests {
+		got := SplitByWeights(tt.amount, tt.weights)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: SplitByWeights(%.2f, %v) = %v, want %v", tt.name, tt.amount, tt.weights, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package utils
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// PaginationQuery supports two modes: keyset cursors (the default) and, for older
+// clients that still send ?page=, classic offset paging.
+type PaginationQuery struct {
+	Page   int    `form:"page"`
+	Limit  int    `form:"limit"`
+	Cursor string `form:"cursor"`
+
+	after     *Cursor
+	requested bool
+}
+
+// Cursor is the position of the last row of a page. Date is only used by lists ordered by expense date.
+type Cursor struct {
+	Date      string    `json:"d,omitempty"`
+	CreatedAt time.Time `json:"t"`
+	ID        uuid.UUID `json:"i"`
+}
+
+// PageMeta goes in APIResponse.Meta for paginated lists
+type PageMeta struct {
+	Limit      int    `json:"limit"`
+	Total      int64  `json:"total"`
+	HasMore    bool   `json:"has_more"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	Page       int    `json:"page,omitempty"` // only for ?page= clients
+}
+
+// BindPagination reads page/limit/cursor from the query string and clamps the page size
+func BindPagination(c *gin.Context) (PaginationQuery, error) {
+	var p PaginationQuery
+	c.ShouldBindQuery(&p)
+	p.requested = p.Page > 0 || p.Limit > 0 || p.Cursor != ""
+
+	if p.Limit <= 0 {
+		p.Limit = DefaultPageSize
+	}
+	if p.Limit > MaxPageSize {
+		p.Limit = MaxPageSize
+	}
+	if p.Page < 0 {
+		p.Page = 0
+	}
+
+	if p.Cursor != "" {
+		raw, err := base64.RawURLEncoding.DecodeString(p.Cursor)
+		if err != nil {
+			return p, ErrInvalidCursor
+		}
+		var cursor Cursor
+		if err := json.Unmarshal(raw, &cursor); err != nil || cursor.ID == uuid.Nil {
+			return p, ErrInvalidCursor
+		}
+		p.after = &cursor
+		p.Page = 0 // a cursor wins over page
+	}
+	return p, nil
+}
+
+// Requested reports whether the client asked for a page at all. Lists that were returned
+// whole before pagination existed keep doing so for clients that don't.
+func (p PaginationQuery) Requested() bool {
+	return p.requested
+}
+
+// UsesOffset reports whether this is a legacy ?page= request
+func (p PaginationQuery) UsesOffset() bool {
+	return p.Page > 0
+}
+
+func (p PaginationQuery) Offset() int {
+	return (p.Page - 1) * p.Limit
+}
+
+// Scope limits a query to one page. keyset adds the "rows after this cursor" condition;
+// it must match the query's ORDER BY. One extra row is fetched to tell whether more exist.
+func (p PaginationQuery) Scope(keyset func(db *gorm.DB, after Cursor) *gorm.DB) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if p.UsesOffset() {
+			return db.Offset(p.Offset()).Limit(p.Limit + 1)
+		}
+		if p.after != nil {
+			db = keyset(db, *p.after)
+		}
+		return db.Limit(p.Limit + 1)
+	}
+}
+
+// Meta builds the response metadata. fetched is the number of rows the Scope query returned;
+// last is the cursor of the last row that will be returned (ignored when there are no more).
+func (p PaginationQuery) Meta(total int64, fetched int, last Cursor) PageMeta {
+	meta := PageMeta{
+		Limit:   p.Limit,
+		Total:   total,
+		HasMore: fetched > p.Limit,
+	}
+	if p.UsesOffset() {
+		meta.Page = p.Page
+		return meta
+	}
+	if meta.HasMore {
+		raw, _ := json.Marshal(last)
+		meta.NextCursor = base64.RawURLEncoding.EncodeToString(raw)
+	}
+	return meta
+}
@@ -0,0 +1,38 @@
+package utils
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestBindPaginationRequested(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		query     string
+		requested bool
+		limit     int
+	}{
+		{"", false, DefaultPageSize},
+		{"?limit=5", true, 5},
+		{"?limit=500", true, MaxPageSize},
+		{"?page=2", true, DefaultPageSize},
+		{"?cursor=eyJ0IjoiMjAyNC0wMS0wMVQwMDowMDowMFoiLCJpIjoiMDAwMDAwMDAtMDAwMC0wMDAwLTAwMDAtMDAwMDAwMDAwMDAxIn0", true, DefaultPageSize},
+		{"?sort=new", false, DefaultPageSize},
+	}
+	for _, tt := range tests {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("GET", "/"+tt.query, nil)
+
+		p, err := BindPagination(c)
+		if err != nil {
+			t.Errorf("%q: %v", tt.query, err)
+			continue
+		}
+		if p.Requested() != tt.requested || p.Limit != tt.limit {
+			t.Errorf("%q: requested=%v limit=%d, want %v %d", tt.query, p.Requested(), p.Limit, tt.requested, tt.limit)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"splitwise-backend/config"
+	"strings"
+)
+
+// SignValue returns "<value>.<signature>", both base64url, for links that must not be forgeable
+// but don't need a login, like unsubscribe links. purpose keeps tokens from one use valid in another.
+func SignValue(purpose string, value string) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(value))
+	return payload + "." + base64.RawURLEncoding.EncodeToString(signedMAC(purpose, payload))
+}
+
+// VerifySignedValue returns the value of a token made by SignValue for the same purpose
+func VerifySignedValue(purpose string, token string) (string, bool) {
+	payload, sig, found := strings.Cut(token, ".")
+	if !found {
+		return "", false
+	}
+	got, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(got, signedMAC(purpose, payload)) {
+		return "", false
+	}
+	value, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return "", false
+	}
+	return string(value), true
+}
+
+func signedMAC(purpose string, payload string) []byte {
+	mac := hmac.New(sha256.New, []byte(config.AppConfig.JWTSecret))
+	mac.Write([]byte(purpose + ":" + payload))
+	return mac.Sum(nil)
+}